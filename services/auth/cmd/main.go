@@ -1,28 +1,191 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/go-pg/pg/v11"
 	"github.com/ramyadmz/goauth/internal/auth"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials/bearer"
+	"github.com/ramyadmz/goauth/internal/credentials/hasher"
+	"github.com/ramyadmz/goauth/internal/credentials/jwt"
+	"github.com/ramyadmz/goauth/internal/credentials/keys"
+	"github.com/ramyadmz/goauth/internal/credentials/policy"
+	"github.com/ramyadmz/goauth/internal/credentials/session"
+	"github.com/ramyadmz/goauth/internal/credentials/throttle"
+	"github.com/ramyadmz/goauth/internal/credentials/totp"
+	"github.com/ramyadmz/goauth/internal/data/postgres"
+	"github.com/ramyadmz/goauth/internal/httpapi"
+	"github.com/ramyadmz/goauth/internal/mail"
 	"github.com/ramyadmz/goauth/pkg/pb"
 	"google.golang.org/grpc"
 )
 
+const cleanupInterval = time.Hour
+
 func main() {
-	listener, err := net.Listen("tcp", ":5051")
+	ctx := context.Background()
+
+	serverConfig, err := config.NewServerConfig()
+	if err != nil {
+		fmt.Printf("Failed to load server config:%v", err)
+		return
+	}
+
+	jwtConfig, err := config.NewJWTConfig()
+	if err != nil {
+		fmt.Printf("Failed to load jwt config:%v", err)
+		return
+	}
+
+	pgConfig, err := config.NewPostgresConfig()
+	if err != nil {
+		fmt.Printf("Failed to load postgres config:%v", err)
+		return
+	}
+
+	db := pg.Connect(&pg.Options{
+		Addr:     fmt.Sprintf("%s:%d", pgConfig.GetHost(), pgConfig.GetPort()),
+		User:     pgConfig.GetUser(),
+		Password: pgConfig.GetPassword(),
+		Database: pgConfig.GetDatabase(),
+	})
+	defer db.Close(ctx)
+	dal := postgres.NewDataProvider(db)
+
+	// Asymmetric algorithms without a configured key sign with rotating keys from the database.
+	tokenHandler := jwt.NewJWTHandler(jwtConfig, dal)
+	if !strings.HasPrefix(jwtConfig.GetAlgorithm(), "HS") && jwtConfig.GetPrivateKey() == nil {
+		keyConfig, err := config.NewKeyConfig()
+		if err != nil {
+			fmt.Printf("Failed to load key config:%v", err)
+			return
+		}
+
+		keyManager := keys.NewKeyManager(keyConfig, jwtConfig, dal)
+		if err := keyManager.Load(ctx); err != nil {
+			fmt.Printf("Failed to load signing keys:%v", err)
+			return
+		}
+		go keyManager.Run(ctx)
+		tokenHandler = jwt.NewJWTHandlerWithKeyStore(jwtConfig, dal, keyManager)
+	}
+
+	throttleConfig, err := config.NewThrottleConfig()
+	if err != nil {
+		fmt.Printf("Failed to load throttle config:%v", err)
+		return
+	}
+
+	// The postgres store shares failure counters between replicas.
+	var throttleStore throttle.Store = throttle.NewMemoryStore()
+	if throttleConfig.GetStore() == config.ThrottleStorePostgres {
+		throttleStore = dal
+	}
+	throttler := throttle.NewThrottler(throttleConfig, throttleStore)
+
+	hasherConfig, err := config.NewHasherConfig()
+	if err != nil {
+		fmt.Printf("Failed to load hasher config:%v", err)
+		return
+	}
+	secureHasher := hasher.NewHasher(hasherConfig)
+
+	mfaConfig, err := config.NewMFAConfig()
+	if err != nil {
+		fmt.Printf("Failed to load mfa config:%v", err)
+		return
+	}
+	mfaManager := totp.NewTOTPManager(mfaConfig, dal, secureHasher)
+
+	mailConfig, err := config.NewMailConfig()
+	if err != nil {
+		fmt.Printf("Failed to load mail config:%v", err)
+		return
+	}
+
+	var mailer mail.Mailer
+	switch mailConfig.GetTransport() {
+	case config.MailTransportSMTP:
+		mailer = mail.NewSMTPMailer(mailConfig)
+	case config.MailTransportMemory:
+		mailer = mail.NewMemoryMailer()
+	default:
+		out := os.Stdout
+		if len(mailConfig.GetFile()) > 0 {
+			out, err = os.OpenFile(mailConfig.GetFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+			if err != nil {
+				fmt.Printf("Failed to open mail file:%v", err)
+				return
+			}
+			defer out.Close()
+		}
+		mailer = mail.NewFileMailer(mailConfig.GetFrom(), out)
+	}
+
+	emailConfig, err := config.NewEmailVerificationConfig()
+	if err != nil {
+		fmt.Printf("Failed to load email verification config:%v", err)
+		return
+	}
+
+	resetConfig, err := config.NewPasswordResetConfig()
+	if err != nil {
+		fmt.Printf("Failed to load password reset config:%v", err)
+		return
+	}
+
+	policyConfig, err := config.NewPasswordPolicyConfig()
+	if err != nil {
+		fmt.Printf("Failed to load password policy config:%v", err)
+		return
+	}
+	passwordPolicy := policy.NewPasswordPolicy(policyConfig, dal, secureHasher)
+
+	userAuth := auth.NewUserAuthService(dal, session.NewSessionManager(dal), mfaManager, secureHasher, passwordPolicy, throttler, mailer, emailConfig, resetConfig)
+	clientAuth := auth.NewClientAuthService(dal, tokenHandler, secureHasher, throttler)
+	metadata := auth.NewMetadataService(jwtConfig, serverConfig)
+	go userAuth.CleanupAuthorizationRequests(ctx, cleanupInterval)
+	go userAuth.CleanupEmailVerifications(ctx, cleanupInterval)
+	go userAuth.CleanupPasswordResets(ctx, cleanupInterval)
+	go tokenHandler.CleanupRevoked(ctx, cleanupInterval)
+	go throttler.Cleanup(ctx, cleanupInterval)
+	go mfaManager.CleanupChallenges(ctx, cleanupInterval)
+
+	httpServer := httpapi.NewServer(userAuth, clientAuth, metadata, jwtConfig.GetExpirationTime(), serverConfig.GetLoginURL(), auth.ValidationInterceptor)
+	go func() {
+		if err := http.ListenAndServe(serverConfig.GetHTTPAddr(), httpServer.Handler()); err != nil {
+			fmt.Printf("Failed to serve http:%v", err)
+		}
+	}()
+
+	listener, err := net.Listen("tcp", serverConfig.GetGRPCAddr())
 	if err != nil {
 		fmt.Printf("Failed to listen:%v", err)
 		return
 	}
-	authService := &auth.UserAuthService{}
+	accessPolicy := auth.NewPolicy()
+	if policyFile := serverConfig.GetPolicyFile(); len(policyFile) > 0 {
+		if accessPolicy, err = auth.LoadPolicy(policyFile); err != nil {
+			fmt.Printf("Failed to load policy:%v", err)
+			return
+		}
+	}
+
+	authenticator := bearer.NewAuthenticator(jwtConfig, tokenHandler, auth.PublicMethods...)
 	serviceOpts := []grpc.ServerOption{
-		grpc.ChainUnaryInterceptor(auth.ValidationInterceptor),
+		grpc.ChainUnaryInterceptor(authenticator.UnaryInterceptor(), accessPolicy.UnaryInterceptor(), auth.ValidationInterceptor),
+		grpc.ChainStreamInterceptor(authenticator.StreamInterceptor(), accessPolicy.StreamInterceptor()),
 	}
 	srv := grpc.NewServer(serviceOpts...)
-	pb.RegisterOAuthServiceServer(srv, authService)
-	srv.Serve(listener)
-	if err != nil {
+	pb.RegisterOAuthServiceServer(srv, auth.NewOAuthServer(userAuth, clientAuth, metadata))
+	if err := srv.Serve(listener); err != nil {
 		fmt.Printf("Failed to serve:%v", err)
 		return
 	}
@@ -3,6 +3,8 @@ package integration
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/go-pg/pg/v11"
 	"github.com/google/uuid"
@@ -10,29 +12,38 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/ramyadmz/goauth/internal/auth"
 	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/hasher"
 	"github.com/ramyadmz/goauth/internal/credentials/jwt"
+	"github.com/ramyadmz/goauth/internal/credentials/policy"
 	"github.com/ramyadmz/goauth/internal/credentials/session"
+	"github.com/ramyadmz/goauth/internal/credentials/throttle"
+	"github.com/ramyadmz/goauth/internal/credentials/totp"
+	"github.com/ramyadmz/goauth/internal/data"
 	"github.com/ramyadmz/goauth/internal/data/postgres"
+	"github.com/ramyadmz/goauth/internal/mail"
 	"github.com/ramyadmz/goauth/pkg/pb"
 )
 
 var ClientID, UserID int64
 var ClientName, ClientSecret, Website, Scope, AuthCode, Token, RefreshToken string
 var Username, SessionID, Email, Password string
+var RequestID, State = "", "af0ifjsldkj"
+var RedirectURI = "https://client.test/callback"
 
 var _ = Describe("Oauth Test Suite", func() {
 	var (
-		ctx        context.Context
-		dal        *postgres.DataProvider
-		userAuth   *auth.UserAuthService
-		clientAuth *auth.ClientAuthService
+		ctx          context.Context
+		dal          *postgres.DataProvider
+		userAuth     *auth.UserAuthService
+		clientAuth   *auth.ClientAuthService
+		tokenHandler *jwt.JWTHandler
 	)
 
 	BeforeEach(func() {
 		ctx = context.Background()
 		jwtConfig, err := config.NewJWTConfig()
 		Expect(err).NotTo(HaveOccurred())
-		tokenHandler := jwt.NewJWTHandler(jwtConfig)
 
 		pgConfig, err := config.NewPostgresConfig()
 		Expect(err).NotTo(HaveOccurred())
@@ -47,10 +58,32 @@ var _ = Describe("Oauth Test Suite", func() {
 		db := pg.Connect(options)
 		dal = postgres.NewDataProvider(db)
 		sessionHandler := session.NewSessionManager(dal)
+		tokenHandler = jwt.NewJWTHandler(jwtConfig, dal)
+
+		throttleConfig, err := config.NewThrottleConfig()
+		Expect(err).NotTo(HaveOccurred())
+		throttler := throttle.NewThrottler(throttleConfig, dal)
+
+		mfaConfig, err := config.NewMFAConfig()
+		Expect(err).NotTo(HaveOccurred())
+
+		emailConfig, err := config.NewEmailVerificationConfig()
+		Expect(err).NotTo(HaveOccurred())
+
+		resetConfig, err := config.NewPasswordResetConfig()
+		Expect(err).NotTo(HaveOccurred())
+
+		hasherConfig, err := config.NewHasherConfig()
+		Expect(err).NotTo(HaveOccurred())
+		secureHasher := hasher.NewHasher(hasherConfig)
+
+		policyConfig, err := config.NewPasswordPolicyConfig()
+		Expect(err).NotTo(HaveOccurred())
 
 		// reuse dal to avoid unnecessary overhead in opening and closing multiple database connections.
-		userAuth = auth.NewUserAuthService(dal, sessionHandler)
-		clientAuth = auth.NewClientAuthService(dal, tokenHandler)
+		mfaManager := totp.NewTOTPManager(mfaConfig, dal, secureHasher)
+		userAuth = auth.NewUserAuthService(dal, sessionHandler, mfaManager, secureHasher, policy.NewPasswordPolicy(policyConfig, dal, secureHasher), throttler, mail.NewMemoryMailer(), emailConfig, resetConfig)
+		clientAuth = auth.NewClientAuthService(dal, tokenHandler, secureHasher, throttler)
 	})
 
 	Context("User Registration", func() {
@@ -82,9 +115,10 @@ var _ = Describe("Oauth Test Suite", func() {
 			ClientName, Website, Scope = uuid.NewString(), uuid.NewString(), "admin"
 
 			rsp, err := clientAuth.RegisterClient(ctx, &pb.RegisterClientRequest{
-				Name:    ClientName,
-				Website: Website,
-				Scope:   Scope,
+				Name:         ClientName,
+				Website:      Website,
+				Scope:        Scope,
+				RedirectUris: []string{RedirectURI},
 			})
 			Expect(err).To(BeNil(), "Client registration should complete without errors")
 			ClientID = rsp.ClientId
@@ -97,6 +131,7 @@ var _ = Describe("Oauth Test Suite", func() {
 			Expect(client.Website).To(Equal(Website))
 			Expect(client.Scope).To(Equal(Scope))
 			Expect(client.HashedSecret).ToNot(BeEmpty())
+			Expect(client.RedirectURIs).To(ConsistOf(RedirectURI))
 		})
 	})
 
@@ -121,38 +156,54 @@ var _ = Describe("Oauth Test Suite", func() {
 		})
 	})
 
+	Context("Authorization Request", func() {
+		It("creates a pending authorization request successfully", func() {
+			rsp, err := userAuth.Authorize(ctx, &pb.AuthorizeRequest{
+				SessionId:   SessionID,
+				ClientId:    ClientID,
+				RedirectUri: RedirectURI,
+				Scope:       Scope,
+				State:       State,
+			})
+			Expect(err).To(BeNil(), "Authorization request should complete without errors")
+			Expect(rsp.ClientName).To(Equal(ClientName))
+			Expect(rsp.Scope).To(Equal(Scope))
+
+			By("Validating the stored authorization request in the database")
+			request, err := dal.GetAuthorizationRequestByID(ctx, rsp.RequestId)
+			Expect(err).To(BeNil(), "Expected to find the authorization request in the database")
+			Expect(request.UserID).To(Equal(UserID))
+			Expect(request.ClientID).To(Equal(ClientID))
+			Expect(request.Status).To(Equal(data.AuthorizationRequestPending))
+			RequestID = request.ID
+		})
+	})
+
 	Context("User Consent", func() {
 		It("completes user consent successfully", func() {
-			_, err := userAuth.ConsentUser(ctx, &pb.UserConsentRequest{
-				ClientId:  ClientID,
+			rsp, err := userAuth.ConsentUser(ctx, &pb.UserConsentRequest{
 				SessionId: SessionID,
+				RequestId: RequestID,
+				Approved:  true,
 			})
 			Expect(err).To(BeNil(), "User consent should complete without errors")
+			Expect(rsp.State).To(Equal(State))
+			Expect(rsp.RedirectUri).To(Equal(RedirectURI))
 
 			By("Validating the stored authorization record in the database")
-			auth, err := dal.GetAuthorizationCodeByUserIDAndClientID(ctx, UserID, ClientID)
-			Expect(err).To(BeNil(), "Expected to find the auth code by user id and client id in the database")
-
-			Expect(auth.AuthCode).NotTo(Equal(""))
-		})
-
-	})
+			auth, err := dal.GetAuthorizationCodeByAuthCode(ctx, rsp.AuthorizationCode)
+			Expect(err).To(BeNil(), "Expected to find the auth code in the database")
+			Expect(auth.UserID).To(Equal(UserID))
+			Expect(auth.ClientID).To(Equal(ClientID))
+			AuthCode = rsp.AuthorizationCode
 
-	Context("Authorization Code Retrieval", func() {
-		It("retrieves an authorization code successfully", func() {
-			rsp, err := clientAuth.GetAuthorizationCode(ctx, &pb.GetAuthorizationCodeRequest{
-				ClientId:     ClientID,
-				ClientSecret: ClientSecret,
-				Username:     Username,
+			By("Answering the same authorization request again")
+			_, err = userAuth.ConsentUser(ctx, &pb.UserConsentRequest{
+				SessionId: SessionID,
+				RequestId: RequestID,
+				Approved:  true,
 			})
-			Expect(err).To(BeNil(), "Authorization code retrieval should complete without errors")
-
-			By("Validating the returned authorization record")
-			auth, err := dal.GetAuthorizationCodeByUserIDAndClientID(ctx, UserID, ClientID)
-			Expect(err).To(BeNil(), "Expected to find the auth code by user id and client id in the database")
-
-			Expect(auth.AuthCode).To(Equal(rsp.AuthorizationCode))
-			AuthCode = rsp.AuthorizationCode
+			Expect(err).NotTo(BeNil(), "Authorization requests should only be resolved once")
 		})
 	})
 
@@ -162,10 +213,12 @@ var _ = Describe("Oauth Test Suite", func() {
 				ClientId:          ClientID,
 				ClientSecret:      ClientSecret,
 				AuthorizationCode: AuthCode,
+				RedirectUri:       RedirectURI,
 			})
 			Expect(err).To(BeNil(), "Token exchange should complete without errors")
 			Expect(len(rsp.AccessToken)).NotTo(Equal(0))
 			Expect(len(rsp.RefreshToken)).ToNot(Equal(0))
+			Expect(rsp.Scope).To(Equal(Scope))
 			Token = rsp.AccessToken
 			RefreshToken = rsp.RefreshToken
 		})
@@ -174,10 +227,92 @@ var _ = Describe("Oauth Test Suite", func() {
 	Context("Token Refresh", func() {
 		It("refreshes an access token successfully", func() {
 			rsp, err := clientAuth.RefreshToken(ctx, &pb.RefreshTokenRequest{
+				ClientId:     ClientID,
+				ClientSecret: ClientSecret,
 				RefreshToken: RefreshToken,
 			})
 			Expect(err).To(BeNil(), "Token refresh should complete without errors")
 			Expect(len(rsp.AccessToken)).NotTo(Equal(0))
+			Expect(rsp.RefreshToken).NotTo(Equal(RefreshToken))
+
+			By("Replaying the rotated refresh token")
+			_, err = clientAuth.RefreshToken(ctx, &pb.RefreshTokenRequest{
+				ClientId:     ClientID,
+				ClientSecret: ClientSecret,
+				RefreshToken: RefreshToken,
+			})
+			Expect(err).NotTo(BeNil(), "Reusing a rotated refresh token should fail")
+
+			By("Checking the rotated refresh token was revoked with its family")
+			_, err = clientAuth.RefreshToken(ctx, &pb.RefreshTokenRequest{
+				ClientId:     ClientID,
+				ClientSecret: ClientSecret,
+				RefreshToken: rsp.RefreshToken,
+			})
+			Expect(err).NotTo(BeNil(), "Refresh tokens of a revoked family should be rejected")
+		})
+	})
+
+	Context("Authorization Code Replay", func() {
+		It("rejects a second exchange of the same authorization code", func() {
+			_, err := clientAuth.ExchangeToken(ctx, &pb.ExchangeTokenRequest{
+				ClientId:          ClientID,
+				ClientSecret:      ClientSecret,
+				AuthorizationCode: AuthCode,
+				RedirectUri:       RedirectURI,
+			})
+			Expect(err).NotTo(BeNil(), "Authorization codes should only be exchangeable once")
+
+			By("Validating the authorization code was revoked")
+			auth, err := dal.GetAuthorizationCodeByAuthCode(ctx, AuthCode)
+			Expect(err).To(BeNil())
+			Expect(auth.IsRevoked).To(BeTrue())
+		})
+	})
+
+	Context("Refresh Token Revocation", func() {
+		It("keeps the later tokens of a revoked family rejected after the denylist is cleaned up", func() {
+			family, err := dal.CreateTokenFamily(ctx, data.CreateTokenFamilyParams{
+				AuthCode: AuthCode,
+				UserID:   UserID,
+				ClientID: ClientID,
+			})
+			Expect(err).To(BeNil())
+			subject := credentials.Subject{
+				Type:     credentials.UserSubject,
+				ID:       UserID,
+				ClientID: ClientID,
+				GrantID:  family.ID,
+			}
+
+			By("Issuing a short-lived refresh token that was rotated into longer-lived tokens")
+			refreshExpiration := os.Getenv("OAUTH_JWT_REFRESH_EXPIRATION_TIME")
+			os.Setenv("OAUTH_JWT_REFRESH_EXPIRATION_TIME", "1")
+			shortConfig, err := config.NewJWTConfig()
+			os.Setenv("OAUTH_JWT_REFRESH_EXPIRATION_TIME", refreshExpiration)
+			Expect(err).To(BeNil())
+			rotated, err := jwt.NewJWTHandler(shortConfig, dal).Generate(ctx, subject, credentials.RefreshToken)
+			Expect(err).To(BeNil())
+			refreshToken, err := tokenHandler.Generate(ctx, subject, credentials.RefreshToken)
+			Expect(err).To(BeNil())
+			accessToken, err := tokenHandler.Generate(ctx, subject, credentials.AccessToken)
+			Expect(err).To(BeNil())
+
+			_, err = clientAuth.RevokeToken(ctx, &pb.RevokeTokenRequest{
+				ClientId:     ClientID,
+				ClientSecret: ClientSecret,
+				Token:        rotated,
+			})
+			Expect(err).To(BeNil(), "Token revocation should complete without errors")
+
+			By("Cleaning up the denylist after the revoked token expired")
+			time.Sleep(2 * time.Second)
+			Expect(dal.DeleteExpiredRevokedTokens(ctx)).To(BeNil())
+
+			_, err = tokenHandler.Validate(ctx, refreshToken)
+			Expect(err).NotTo(BeNil(), "Later refresh tokens of a revoked family should be rejected")
+			_, err = tokenHandler.Validate(ctx, accessToken)
+			Expect(err).NotTo(BeNil(), "Access tokens of a revoked family should be rejected")
 		})
 	})
 })
@@ -22,6 +22,8 @@ func SetUpLocalTestEnvs() {
 	os.Setenv("OAUTH_JWT_REFRESH_EXPIRATION_TIME", "7200")
 	os.Setenv("OAUTH_JWT_HEADER_NAME", "Authorization")
 	os.Setenv("OAUTH_JWT_HEADER_PREFIX", "Bearer")
+	os.Setenv("OAUTH_JWT_KEY_ENCRYPTION_KEY", "dGVzdC1zaWduaW5nLWtleS1lbmNyeXB0aW9uLWtleSE=")
+	os.Setenv("OAUTH_MFA_ENCRYPTION_KEY", "dGVzdC1tZmEtZW5jcnlwdGlvbi1rZXktMzItYnl0ZXM=")
 }
 
 // UnSetLocalTestEnvs unset up the required environment variables for local testing
@@ -44,5 +46,7 @@ func UnSetLocalTestEnvs() {
 	os.Unsetenv("OAUTH_JWT_REFRESH_EXPIRATION_TIME")
 	os.Unsetenv("OAUTH_JWT_HEADER_NAME")
 	os.Unsetenv("OAUTH_JWT_HEADER_PREFIX")
+	os.Unsetenv("OAUTH_JWT_KEY_ENCRYPTION_KEY")
+	os.Unsetenv("OAUTH_MFA_ENCRYPTION_KEY")
 
 }
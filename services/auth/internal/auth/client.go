@@ -3,15 +3,19 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/throttle"
 	"github.com/ramyadmz/goauth/internal/data"
 	"github.com/ramyadmz/goauth/pkg/pb"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -19,42 +23,74 @@ import (
 const (
 	AccessTokenExp  = 1 * time.Hour
 	RefreshTokenExp = 24 * time.Hour
+
+	BearerTokenType  = "Bearer"
+	RefreshTokenType = "refresh_token"
+)
+
+// Errors shared by several RPCs, so that callers can tell them apart from other errors with the same code.
+var (
+	ErrInvalidClient = status.Error(codes.Unauthenticated, "invalid client id or secret")
+	ErrInvalidScope  = status.Error(codes.InvalidArgument, "invalid scope")
+	ErrPublicClient  = status.Error(codes.PermissionDenied, "client is not allowed to use this grant")
 )
 
 type ClientAuthService struct {
 	pb.UnimplementedOAuthServiceServer
 	dal          data.DataProvider
 	tokenHandler credentials.TokenHandler
+	hasher       credentials.SecureHasher
+	throttler    *throttle.Throttler
 }
 
 // NewClientAuthService creates a new instance of ClientAuthService with the provided dependencies.
-func NewClientAuthService(dal data.DataProvider, tokenHandler credentials.TokenHandler) *ClientAuthService {
+func NewClientAuthService(dal data.DataProvider, tokenHandler credentials.TokenHandler, hasher credentials.SecureHasher, throttler *throttle.Throttler) *ClientAuthService {
 	return &ClientAuthService{
 		dal:          dal,
 		tokenHandler: tokenHandler,
+		hasher:       hasher,
+		throttler:    throttler,
 	}
 }
 
 func (c *ClientAuthService) RegisterClient(ctx context.Context, req *pb.RegisterClientRequest) (*pb.RegisterClientResponse, error) {
-	logger := logrus.WithContext(ctx).WithField("name", req.Name).WithField("website", req.Website).WithField("scope", req.Scope)
+	logger := logrus.WithContext(ctx).WithField("name", req.Name).WithField("website", req.Website).WithField("scope", req.Scope).WithField("public", req.IsPublic)
 
-	secret, err := generateSecret(ctx)
-	if err != nil {
-		logger.Error("error generating secret: %w", err)
-		return nil, status.Errorf(codes.Internal, "Internal server error")
+	if !isValidScope(req.Scope) {
+		logger.Warn("client registered with malformed scope")
+		return nil, ErrInvalidScope
 	}
 
-	hashedSecret, err := bcrypt.GenerateFromPassword([]byte(secret), 10)
-	if err != nil {
-		logger.Error("Error hashing secret: %w", err)
-		return nil, status.Errorf(codes.Internal, "Internal server error")
+	if hasScope(req.Scope, ScopeOpenID) && !c.tokenHandler.SupportsIDTokens() {
+		logger.Warn("client registered with openid scope while id tokens are not supported")
+		return nil, ErrInvalidScope
+	}
+
+	// Public clients can't keep a secret confidential, so they don't get one and
+	// rely on PKCE instead.
+	var secret, hashedSecret string
+	if !req.IsPublic {
+		var err error
+		secret, err = generateSecret(ctx)
+		if err != nil {
+			logger.Error("error generating secret: %w", err)
+			return nil, status.Errorf(codes.Internal, "Internal server error")
+		}
+
+		hashedSecret, err = c.hasher.Hash(secret)
+		if err != nil {
+			logger.Error("Error hashing secret: %w", err)
+			return nil, status.Errorf(codes.Internal, "Internal server error")
+		}
 	}
 
 	clientData, err := c.dal.CreateClient(ctx, data.CreateClientParams{
 		Name:         req.Name,
 		Website:      req.Website,
-		Scope:        req.Scope,
-		HashedSecret: string(hashedSecret),
+		Scope:        normalizeScope(req.Scope),
+		HashedSecret: hashedSecret,
+		IsPublic:     req.IsPublic,
+		RedirectURIs: req.RedirectUris,
 	})
 
 	if err != nil {
@@ -70,140 +106,517 @@ func (c *ClientAuthService) RegisterClient(ctx context.Context, req *pb.Register
 
 }
 
-func (c *ClientAuthService) GetAuthorizationCode(ctx context.Context, req *pb.GetAuthorizationCodeRequest) (*pb.GetAuthorizationCodeResponse, error) {
-	logger := logrus.WithContext(ctx).WithField("clientID", req.ClientId).WithField("username", req.Username)
+func (c *ClientAuthService) ExchangeToken(ctx context.Context, req *pb.ExchangeTokenRequest) (*pb.ExchangeTokenResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("clientID", req.ClientId)
 
-	client, err := c.dal.GetClientByID(ctx, req.ClientId)
+	// Public clients are bound to the code by PKCE instead of a secret, see checkAuthorizationCode.
+	client, err := c.authenticateClient(ctx, req.ClientId, req.ClientSecret, true)
 	if err != nil {
-		if err == data.ErrClientNotFound {
-			logger.Warn("Invalid client id: %w", err)
-			return nil, status.Errorf(codes.Unauthenticated, "invalid client id or secret")
+		return nil, err
+	}
+
+	// The code is checked and marked as used in one step, so that each code can only ever be exchanged
+	// once while a request failing the checks leaves it usable for the client it was issued to.
+	var rejected error
+	auth, err := c.dal.ConsumeAuthorizationCode(ctx, req.AuthorizationCode, func(auth *data.Authorization) error {
+		rejected = checkAuthorizationCode(ctx, auth, client, req)
+		return rejected
+	})
+	if rejected != nil {
+		return nil, rejected
+	}
+	if err != nil {
+		switch err {
+		case data.ErrAuthorizationNotFound:
+			logger.Warn("Invalid auth code: %w", err)
+			return nil, status.Errorf(codes.Unauthenticated, "invalid auth code")
+		case data.ErrAuthorizationCodeUsed:
+			// RFC 6749 section 4.1.2: a replayed code revokes every token already issued from it.
+			fields := logrus.Fields{"clientID": req.ClientId}
+			if auth != nil {
+				fields["userID"] = auth.UserID
+			}
+			emitSecurityEvent(ctx, EventAuthorizationCodeReuse, fields)
+			if err := c.dal.RevokeAuthorizationCode(ctx, req.AuthorizationCode); err != nil {
+				logger.Error("error failed to revoke authorization code: %w", err)
+				return nil, status.Errorf(codes.Internal, "Internal server error")
+			}
+			return nil, status.Errorf(codes.Unauthenticated, "invalid auth code")
 		}
-		logger.Error("error failed to fetch client by client id: %w", err)
+		logger.Error("error failed to consume authorization code: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	err = bcrypt.CompareHashAndPassword(client.HashedSecret, []byte(req.ClientSecret))
+	// Roles are read when tokens are issued, so role changes apply from the next issued token on.
+	user, err := c.dal.GetUserByID(ctx, auth.UserID)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			logger.Warn("invalid client secret: %w", err)
-			return nil, status.Errorf(codes.Unauthenticated, "invalid client id or secret")
+		if err == data.ErrUserNotFound {
+			logger.Warn("user of auth code no longer exists")
+			return nil, status.Errorf(codes.Unauthenticated, "invalid auth code")
 		}
-		logger.Error("error comparing client secret and hashed secret: %w", err)
+		logger.Error("error failed to fetch user: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	user, err := c.dal.GetUserByUsername(ctx, req.Username)
+	family, err := c.dal.CreateTokenFamily(ctx, data.CreateTokenFamilyParams{
+		AuthCode: auth.AuthCode,
+		UserID:   auth.UserID,
+		ClientID: auth.ClientID,
+	})
 	if err != nil {
-		if err == data.ErrClientNotFound {
-			logger.Warn("invalid username: %w", err)
-			return nil, status.Errorf(codes.Unauthenticated, "invalid username")
-		}
-		logger.Error("error failed to fetch user by username: %w", err)
+		logger.Error("error failed to create token family: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	auth, err := c.dal.GetAuthorizationCodeByUserIDAndClientID(ctx, user.ID, req.ClientId)
+	accessToken, refreshToken, err := c.issueTokenPair(ctx, credentials.Subject{
+		Type:     credentials.UserSubject,
+		ID:       auth.UserID,
+		ClientID: auth.ClientID,
+		Scope:    auth.Scope,
+		GrantID:  family.ID,
+		Roles:    user.Roles,
+	}, auth.Scope)
 	if err != nil {
-		if err == data.ErrAuthorizationNotFound {
-			logger.Warn("auth not found: %w", err)
-			return nil, status.Errorf(codes.Unauthenticated, "Authorization record not found.")
+		return nil, err
+	}
+
+	var idToken string
+	if hasScope(auth.Scope, ScopeOpenID) {
+		idToken, err = c.issueIDToken(ctx, auth, user)
+		if err != nil {
+			return nil, err
 		}
-		logger.Error("error failed to fetch authorization record: %w", err)
+	}
+
+	logger.Info("access and refresh token returned successfully")
+	return &pb.ExchangeTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scope:        auth.Scope,
+		IdToken:      idToken,
+	}, nil
+}
+
+// RefreshToken rotates a refresh token: the presented token is marked as used and a new access and
+// refresh token pair from the same family is returned. Presenting a refresh token that has already
+// been used means it was leaked, so the whole family is revoked.
+// The client may ask for a narrower scope for the new access token; the refresh token always keeps
+// the scope originally granted (RFC 6749 section 6).
+func (c *ClientAuthService) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("clientID", req.ClientId)
+
+	// Refresh tokens of public clients are bound to the client they were issued to by their claims.
+	client, err := c.authenticateClient(ctx, req.ClientId, req.ClientSecret, true)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := c.tokenHandler.Validate(ctx, req.RefreshToken)
+
+	if err != nil {
+		if errors.Is(err, credentials.ErrInvalidToken) {
+			logger.Warn("Invalid refresh token: %w", err)
+			return nil, status.Errorf(codes.Unauthenticated, "Invalid refresh token.")
+		} else {
+			logger.Error("error failed to validate refresh token: %w", err)
+			return nil, status.Errorf(codes.Internal, "Internal server error")
+		}
+	}
+
+	if claims.TokenType != credentials.RefreshToken {
+		logger.Warnf("token of type %s presented as refresh token", claims.TokenType)
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid refresh token.")
+	}
+
+	if claims.ClientID != client.ID {
+		logger.Warnf("Mismatched Client IDs: claims.ClientID = %d, req.ClientID = %d", claims.ClientID, req.ClientId)
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid refresh token.")
+	}
+
+	scope := claims.Scope
+	if len(parseScope(req.Scope)) > 0 {
+		if !isScopeSubset(req.Scope, claims.Scope) {
+			logger.WithField("scope", req.Scope).Warn("requested scope exceeds granted scope")
+			return nil, ErrInvalidScope
+		}
+		scope = normalizeScope(req.Scope)
+	}
+
+	_, err = c.dal.UseRefreshToken(ctx, hashToken(req.RefreshToken))
+	if err != nil {
+		switch err {
+		case data.ErrRefreshTokenUsed:
+			emitSecurityEvent(ctx, EventRefreshTokenReuse, logrus.Fields{
+				"clientID": client.ID,
+				"userID":   claims.Subject,
+				"familyID": claims.GrantID,
+			})
+			if err := c.dal.RevokeTokenFamily(ctx, claims.GrantID); err != nil {
+				logger.Error("error failed to revoke token family: %w", err)
+				return nil, status.Errorf(codes.Internal, "Internal server error")
+			}
+			return nil, status.Errorf(codes.Unauthenticated, "Invalid refresh token.")
+		case data.ErrRefreshTokenNotFound:
+			logger.Warn("refresh token is not part of any token family")
+			return nil, status.Errorf(codes.Unauthenticated, "Invalid refresh token.")
+		default:
+			logger.Error("error failed to use refresh token: %w", err)
+			return nil, status.Errorf(codes.Internal, "Internal server error")
+		}
+	}
+
+	var roles []string
+	if claims.SubjectType == credentials.UserSubject {
+		user, err := c.dal.GetUserByID(ctx, claims.Subject.(int64))
+		if err != nil {
+			if err == data.ErrUserNotFound {
+				logger.Warn("user of refresh token no longer exists")
+				return nil, status.Errorf(codes.Unauthenticated, "Invalid refresh token.")
+			}
+			logger.Error("error failed to fetch user: %w", err)
+			return nil, status.Errorf(codes.Internal, "Internal server error")
+		}
+		roles = user.Roles
+	}
+
+	accessToken, refreshToken, err := c.issueTokenPair(ctx, credentials.Subject{
+		Type:     claims.SubjectType,
+		ID:       claims.Subject.(int64),
+		ClientID: claims.ClientID,
+		Scope:    claims.Scope,
+		GrantID:  claims.GrantID,
+		Roles:    roles,
+	}, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("refresh token rotated successfully")
+	return &pb.RefreshTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	}, nil
+}
+
+// ClientCredentialsToken implements the client credentials grant (RFC 6749 section 4.4): a confidential
+// client authenticates with its own secret and receives an access token issued to itself rather than a user.
+func (c *ClientAuthService) ClientCredentialsToken(ctx context.Context, req *pb.ClientCredentialsTokenRequest) (*pb.ClientCredentialsTokenResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("clientID", req.ClientId).WithField("scope", req.Scope)
+
+	client, err := c.authenticateClient(ctx, req.ClientId, req.ClientSecret, false)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := normalizeScope(req.Scope)
+	if len(scope) == 0 {
+		scope = client.Scope
+	}
+
+	if !isScopeSubset(scope, client.Scope) {
+		logger.Warn("requested scope exceeds client scope")
+		return nil, ErrInvalidScope
+	}
+
+	accessToken, err := c.tokenHandler.Generate(ctx, credentials.Subject{
+		Type:     credentials.ClientSubject,
+		ID:       client.ID,
+		ClientID: client.ID,
+		Scope:    scope,
+	}, credentials.AccessToken)
+
+	if err != nil {
+		logger.Error("error failed to generate access token: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	logger.Info("authorization code returned successfully")
-	return &pb.GetAuthorizationCodeResponse{
-		AuthorizationCode: auth.AuthCode,
+	logger.Info("client credentials access token returned successfully")
+	return &pb.ClientCredentialsTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   BearerTokenType,
+		Scope:       scope,
 	}, nil
 }
 
-func (c *ClientAuthService) ExchangeToken(ctx context.Context, req *pb.ExchangeTokenRequest) (*pb.ExchangeTokenResponse, error) {
+// IntrospectToken reports whether a token is active and what it grants (RFC 7662). Only confidential
+// clients may introspect. Expired, revoked or malformed tokens yield active=false rather than an error.
+func (c *ClientAuthService) IntrospectToken(ctx context.Context, req *pb.IntrospectTokenRequest) (*pb.IntrospectTokenResponse, error) {
 	logger := logrus.WithContext(ctx).WithField("clientID", req.ClientId)
 
-	client, err := c.dal.GetClientByID(ctx, req.ClientId)
+	if _, err := c.authenticateClient(ctx, req.ClientId, req.ClientSecret, false); err != nil {
+		return nil, err
+	}
+
+	claims, err := c.tokenHandler.Validate(ctx, req.Token)
+	if err != nil {
+		logger.Info("introspected token is not active: %w", err)
+		return &pb.IntrospectTokenResponse{Active: false}, nil
+	}
+
+	tokenType := BearerTokenType
+	if claims.TokenType == credentials.RefreshToken {
+		tokenType = RefreshTokenType
+	}
+
+	logger.Info("token introspected successfully")
+	return &pb.IntrospectTokenResponse{
+		Active:    true,
+		Sub:       fmt.Sprint(claims.Subject),
+		ClientId:  claims.ClientID,
+		Scope:     claims.Scope,
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Iss:       claims.Issuer,
+		Aud:       claims.Audience,
+		TokenType: tokenType,
+	}, nil
+}
+
+// RevokeToken revokes an access or refresh token issued to the calling client (RFC 7009). Revoking a
+// refresh token also revokes the access tokens issued from the same grant. Invalid, expired or
+// unknown tokens are not an error, so the caller can't learn anything about them.
+func (c *ClientAuthService) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("clientID", req.ClientId)
+
+	// Public clients may revoke the tokens issued to them, which are checked against the client below.
+	client, err := c.authenticateClient(ctx, req.ClientId, req.ClientSecret, true)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := c.tokenHandler.Validate(ctx, req.Token)
+	if err != nil {
+		if errors.Is(err, credentials.ErrInvalidToken) {
+			logger.Info("revoked token is already invalid: %w", err)
+			return &pb.RevokeTokenResponse{}, nil
+		}
+		logger.Error("error failed to validate token: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	if claims.ClientID != client.ID {
+		logger.Warnf("Mismatched Client IDs: claims.ClientID = %d, req.ClientID = %d", claims.ClientID, req.ClientId)
+		return nil, status.Errorf(codes.PermissionDenied, "token was not issued to this client")
+	}
+
+	if err := c.tokenHandler.Invalidate(ctx, req.Token); err != nil {
+		logger.Error("error failed to revoke token: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	logger.Info("token revoked successfully")
+	return &pb.RevokeTokenResponse{}, nil
+}
+
+// authenticateClient fetches the client and, for confidential clients, verifies the presented secret.
+// Public clients have no secret to verify, so they are rejected unless allowPublic is set, in which
+// case the caller must bind them to the grant some other way, such as PKCE.
+func (c *ClientAuthService) authenticateClient(ctx context.Context, clientID int64, clientSecret string, allowPublic bool) (*data.Client, error) {
+	logger := logrus.WithContext(ctx).WithField("clientID", clientID)
+
+	client, err := c.dal.GetClientByID(ctx, clientID)
 	if err != nil {
 		if err == data.ErrClientNotFound {
 			logger.Warn("Invalid client id: %w", err)
-			return nil, status.Errorf(codes.Unauthenticated, "invalid client id or secret")
+			return nil, ErrInvalidClient
 		}
 		logger.Error("error failed to fetch client by client id: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	err = bcrypt.CompareHashAndPassword(client.HashedSecret, []byte(req.ClientSecret))
+	if client.IsPublic {
+		if !allowPublic {
+			logger.Warn("public client attempted a confidential client request")
+			return nil, ErrPublicClient
+		}
+		return client, nil
+	}
+
+	// Count the attempt before spending a secret comparison on it, rejecting throttled ones
+	throttleKeys := throttle.WithIP(ctx, throttle.ClientKey(clientID))
+	if err := c.throttler.Attempt(ctx, throttleKeys...); err != nil {
+		return nil, err
+	}
+
+	err = c.hasher.Compare(string(client.HashedSecret), clientSecret)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		if err == credentials.ErrInvalidPass {
 			logger.Warn("invalid client secret: %w", err)
-			return nil, status.Errorf(codes.Unauthenticated, "invalid client id or secret")
+			return nil, ErrInvalidClient
 		}
 		logger.Error("error comparing client secret and hashed secret: %w", err)
+		c.throttler.Release(ctx, throttleKeys...)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
+	c.throttler.Succeed(ctx, throttleKeys...)
 
-	auth, err := c.dal.GetAuthorizationCodeByAuthCode(ctx, req.AuthorizationCode)
+	return client, nil
+}
+
+// UserInfo returns the claims about the user an access token was issued for (OIDC Core section 5.3).
+// The token must have been granted the openid scope; profile and email claims follow the granted scope.
+func (c *ClientAuthService) UserInfo(ctx context.Context, req *pb.UserInfoRequest) (*pb.UserInfoResponse, error) {
+	logger := logrus.WithContext(ctx)
+
+	claims, err := c.tokenHandler.Validate(ctx, req.AccessToken)
 	if err != nil {
-		if err == data.ErrAuthorizationNotFound {
-			logger.Warn("Invalid auth code: %w", err)
-			return nil, status.Errorf(codes.Unauthenticated, "invalid auth code")
+		if errors.Is(err, credentials.ErrInvalidToken) {
+			logger.Warn("Invalid access token: %w", err)
+			return nil, status.Errorf(codes.Unauthenticated, "Invalid access token.")
 		}
-		logger.Error("error failed to fetch authorization by auth code: %w", err)
+		logger.Error("error failed to validate access token: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	if auth.ClientID != req.ClientId {
-		logger.Warnf("Mismatched Client IDs: auth.ClientID = %d, req.ClientID = %d", auth.ClientID, req.ClientId)
-		return nil, status.Errorf(codes.Unauthenticated, "Invalid authorization code.")
+	if claims.TokenType != credentials.AccessToken || claims.SubjectType != credentials.UserSubject {
+		logger.Warnf("%s token of %s subject presented to userinfo", claims.TokenType, claims.SubjectType)
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid access token.")
 	}
 
-	accessToken, err := c.tokenHandler.Generate(ctx, auth.UserID, credentials.AccessToken)
+	if !hasScope(claims.Scope, ScopeOpenID) {
+		logger.Warn("access token was not granted the openid scope")
+		return nil, status.Errorf(codes.PermissionDenied, "insufficient scope")
+	}
 
+	user, err := c.dal.GetUserByID(ctx, claims.Subject.(int64))
 	if err != nil {
-		logger.Error("error failed to generate access token: %w", err)
+		if err == data.ErrUserNotFound {
+			logger.Warn("user of access token no longer exists")
+			return nil, status.Errorf(codes.Unauthenticated, "Invalid access token.")
+		}
+		logger.Error("error failed to fetch user: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	refreshToken, err := c.tokenHandler.Generate(ctx, auth.UserID, credentials.RefreshToken)
+	identity := userIdentity(user, claims.Scope)
+
+	logger.Info("userinfo returned successfully")
+	return &pb.UserInfoResponse{
+		Sub:               strconv.FormatInt(identity.Subject, 10),
+		PreferredUsername: identity.PreferredUsername,
+		Email:             identity.Email,
+		EmailVerified:     identity.EmailVerified,
+	}, nil
+}
+
+// GetJWKS publishes the public keys tokens are signed with as a JSON Web Key Set (RFC 7517), so
+// resource servers can verify tokens without sharing a secret.
+func (c *ClientAuthService) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.GetJWKSResponse, error) {
+	logger := logrus.WithContext(ctx)
 
+	keySet, err := c.tokenHandler.KeySet(ctx)
 	if err != nil {
-		logger.Error("error failed to generate refresh token: %w", err)
+		logger.Error("error failed to build key set: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	logger.Info("access and refresh token returned successfully")
-	return &pb.ExchangeTokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	keys := make([]*pb.JSONWebKey, 0, len(keySet.Keys))
+	for _, key := range keySet.Keys {
+		keys = append(keys, &pb.JSONWebKey{
+			Kty: key.KeyType,
+			Kid: key.KeyID,
+			Use: key.Use,
+			Alg: key.Algorithm,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Curve,
+			X:   key.X,
+			Y:   key.Y,
+		})
+	}
+
+	return &pb.GetJWKSResponse{Keys: keys}, nil
 }
 
-func (c *ClientAuthService) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
-	logger := logrus.WithContext(ctx).WithField("refreshToken", req.RefreshToken)
+// issueIDToken generates the OpenID Connect ID token for the user an authorization code was issued to.
+func (c *ClientAuthService) issueIDToken(ctx context.Context, auth *data.Authorization, user *data.User) (string, error) {
+	logger := logrus.WithContext(ctx).WithField("clientID", auth.ClientID).WithField("userID", auth.UserID)
 
-	claims, err := c.tokenHandler.Validate(ctx, req.RefreshToken)
+	identity := userIdentity(user, auth.Scope)
+	identity.ClientID = auth.ClientID
+	identity.Nonce = auth.Nonce
+	identity.AuthTime = auth.AuthTime
 
+	idToken, err := c.tokenHandler.GenerateIDToken(ctx, identity)
 	if err != nil {
-		if errors.Is(err, credentials.ErrInvalidToken) {
-			logger.Warn("Invalid refresh token: %w", err)
-			return nil, status.Errorf(codes.Unauthenticated, "Invalid refresh token.")
-		} else {
-			logger.Error("error failed to validate refresh token: %w", err)
-			return nil, status.Errorf(codes.Internal, "Internal server error")
-		}
+		logger.Error("error failed to generate id token: %w", err)
+		return "", status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	accessToken, err := c.tokenHandler.Generate(ctx, claims.Subject, credentials.AccessToken)
+	return idToken, nil
+}
+
+// issueTokenPair generates an access and refresh token for the subject and records the refresh
+// token as the newest member of the subject's token family. The refresh token carries the subject's
+// scope while the access token carries accessScope, which may be narrower.
+func (c *ClientAuthService) issueTokenPair(ctx context.Context, subject credentials.Subject, accessScope string) (string, string, error) {
+	logger := logrus.WithContext(ctx).WithField("clientID", subject.ClientID).WithField("familyID", subject.GrantID)
 
+	accessSubject := subject
+	accessSubject.Scope = accessScope
+	accessToken, err := c.tokenHandler.Generate(ctx, accessSubject, credentials.AccessToken)
 	if err != nil {
 		logger.Error("error failed to generate access token: %w", err)
-		return nil, status.Errorf(codes.Internal, "Internal server error")
+		return "", "", status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	logger.Info("access token refreshed successfully")
-	return &pb.RefreshTokenResponse{
-		AccessToken: accessToken,
-	}, nil
+	refreshToken, err := c.tokenHandler.Generate(ctx, subject, credentials.RefreshToken)
+	if err != nil {
+		logger.Error("error failed to generate refresh token: %w", err)
+		return "", "", status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	_, err = c.dal.CreateRefreshToken(ctx, data.CreateRefreshTokenParams{
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  subject.GrantID,
+	})
+	if err != nil {
+		logger.Error("error failed to store refresh token: %w", err)
+		return "", "", status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// checkAuthorizationCode verifies that an authorization code was issued to the client for the
+// presented redirect uri, is still valid, and matches the PKCE code verifier.
+func checkAuthorizationCode(ctx context.Context, auth *data.Authorization, client *data.Client, req *pb.ExchangeTokenRequest) error {
+	logger := logrus.WithContext(ctx).WithField("clientID", req.ClientId)
+
+	if auth.ClientID != client.ID {
+		logger.Warnf("Mismatched Client IDs: auth.ClientID = %d, req.ClientID = %d", auth.ClientID, req.ClientId)
+		return status.Errorf(codes.Unauthenticated, "Invalid authorization code.")
+	}
+
+	if auth.IsRevoked || time.Now().After(auth.ExpiresAt) {
+		logger.Warn("expired or revoked auth code")
+		return status.Errorf(codes.Unauthenticated, "invalid auth code")
+	}
+
+	// RFC 6749 section 4.1.3: the redirect uri must be identical to the one the code was issued for.
+	if auth.RedirectURI != req.RedirectUri {
+		logger.Warnf("Mismatched redirect uris: auth.RedirectURI = %s, req.RedirectUri = %s", auth.RedirectURI, req.RedirectUri)
+		return status.Errorf(codes.Unauthenticated, "Invalid authorization code.")
+	}
+
+	if len(auth.CodeChallenge) > 0 {
+		if !verifyCodeChallenge(req.CodeVerifier, auth.CodeChallenge, auth.CodeChallengeMethod) {
+			logger.Warn("code verifier doesn't match code challenge")
+			return status.Errorf(codes.Unauthenticated, "Invalid code verifier.")
+		}
+	} else if client.IsPublic {
+		logger.Warn("public client exchanging authorization code without PKCE")
+		return status.Errorf(codes.Unauthenticated, "Invalid authorization code.")
+	}
+
+	return nil
+}
+
+// hashToken returns the hex encoded SHA-256 digest under which a token is stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func generateSecret(ctx context.Context) (string, error) {
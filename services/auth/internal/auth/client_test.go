@@ -2,8 +2,11 @@ package auth
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"math/rand"
+	"strconv"
 	"testing"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/ramyadmz/goauth/internal/data"
 	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
 
+	"github.com/ramyadmz/goauth/internal/config"
 	"github.com/ramyadmz/goauth/internal/credentials"
 	"github.com/ramyadmz/goauth/pkg/pb"
 	mock "github.com/stretchr/testify/mock"
@@ -36,7 +40,7 @@ func TestRegisterClient_HappyPath(t *testing.T) {
 	mockDAL := &dalMock.DataProvider{}
 	mockDAL.On("CreateClient", mock.Anything, mock.Anything).Return(client, nil)
 
-	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{})
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
 
 	rsp, err := authService.RegisterClient(context.Background(), &pb.RegisterClientRequest{Name: client.Name, Website: client.Website, Scope: client.Scope})
 
@@ -45,23 +49,36 @@ func TestRegisterClient_HappyPath(t *testing.T) {
 	assert.NotEqual(t, len(rsp.ClientSecret), 0)
 }
 
+func TestRegisterClient_OpenIDScopeWithoutIDTokens(t *testing.T) {
+	mockDAL := &dalMock.DataProvider{}
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("SupportsIDTokens").Return(false)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.RegisterClient(context.Background(), &pb.RegisterClientRequest{Name: uuid.NewString(), Website: "test.com", Scope: "openid email"})
+
+	assert.Equal(t, err, ErrInvalidScope)
+	mockDAL.AssertNotCalled(t, "CreateClient", mock.Anything, mock.Anything)
+}
+
 func TestRegisterClient_InternalError(t *testing.T) {
 	client := &data.Client{}
 
 	mockDAL := &dalMock.DataProvider{}
 	mockDAL.On("CreateClient", mock.Anything, mock.Anything).Return(client, errors.New("failed to create client"))
 
-	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{})
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
 
 	rsp, err := authService.RegisterClient(context.Background(), &pb.RegisterClientRequest{Name: client.Name, Website: client.Website, Scope: client.Scope})
 	assert.NotEqual(t, err, nil)
 	assert.Equal(t, rsp, nil)
 }
 
-func TestGetAuthorizationCode_HappyPath(t *testing.T) {
+func TestExchangeToken_HappyPath(t *testing.T) {
 	clientSecret := uuid.NewString()
+	accessToken := uuid.NewString()
+	refreshToken := uuid.NewString()
 	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
-
 	client := &data.Client{
 		ID:           rand.Int63(),
 		HashedSecret: hashedSec,
@@ -71,18 +88,8 @@ func TestGetAuthorizationCode_HappyPath(t *testing.T) {
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
-
-	user := &data.User{
-		ID:             rand.Int63(),
-		Username:       uuid.NewString(),
-		HashedPassword: []byte(uuid.NewString()),
-		Email:          "user102@test.com",
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
-	}
-
 	authorization := &data.Authorization{
-		UserID:    user.ID,
+		UserID:    rand.Int63(),
 		ClientID:  client.ID,
 		AuthCode:  uuid.NewString(),
 		Scope:     "scope",
@@ -93,78 +100,75 @@ func TestGetAuthorizationCode_HappyPath(t *testing.T) {
 
 	mockDAL := &dalMock.DataProvider{}
 	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
-	mockDAL.On("GetUserByUsername", mock.Anything, mock.Anything).Return(user, nil)
-	mockDAL.On("GetAuthorizationCodeByUserIDAndClientID", mock.Anything, mock.Anything, mock.Anything).Return(authorization, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(authorization, nil)
+	mockDAL.On("GetUserByID", mock.Anything, authorization.UserID).Return(&data.User{ID: authorization.UserID, Roles: []string{"admin"}}, nil)
+	mockDAL.On("CreateTokenFamily", mock.Anything, mock.Anything).Return(&data.TokenFamily{ID: uuid.NewString()}, nil)
+	mockDAL.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(&data.RefreshToken{}, nil)
 
-	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{})
-	rsp, err := authService.GetAuthorizationCode(context.Background(), &pb.GetAuthorizationCodeRequest{
-		ClientId:     client.ID,
-		Username:     user.Username,
-		ClientSecret: clientSecret,
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Generate", mock.Anything, mock.MatchedBy(func(sub credentials.Subject) bool {
+		return len(sub.Roles) == 1 && sub.Roles[0] == "admin"
+	}), credentials.AccessToken).Times(1).Return(accessToken, nil)
+	mockTokenHandler.On("Generate", mock.Anything, mock.Anything, credentials.RefreshToken).Times(2).Return(refreshToken, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	rsp, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
+		ClientId:          client.ID,
+		ClientSecret:      clientSecret,
+		AuthorizationCode: authorization.AuthCode,
 	})
+
 	assert.Equal(t, err, nil)
-	assert.Equal(t, rsp.AuthorizationCode, authorization.AuthCode)
+	assert.Equal(t, rsp.AccessToken, accessToken)
+	assert.Equal(t, rsp.RefreshToken, refreshToken)
 }
 
-func TestGetAuthorizationCode_Unauthenticated(t *testing.T) {
+func TestExchangeToken_OpenIDScopeReturnsIDToken(t *testing.T) {
 	clientSecret := uuid.NewString()
+	idToken := uuid.NewString()
 	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
-	invalidclientSecret := uuid.NewString()
 	client := &data.Client{
 		ID:           rand.Int63(),
 		HashedSecret: hashedSec,
 		Name:         uuid.NewString(),
 		Website:      "test.com",
-		Scope:        "read",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		Scope:        "openid email",
 	}
-
-	mockDAL := &dalMock.DataProvider{}
-	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
-
-	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{})
-	_, err := authService.GetAuthorizationCode(context.Background(), &pb.GetAuthorizationCodeRequest{
-		ClientId:     client.ID,
-		Username:     mock.Anything,
-		ClientSecret: invalidclientSecret,
-	})
-	assert.Equal(t, status.Code(err), codes.Unauthenticated)
-}
-
-func TestExchangeToken_HappyPath(t *testing.T) {
-	clientSecret := uuid.NewString()
-	accessToken := uuid.NewString()
-	refreshToken := uuid.NewString()
-	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
-	client := &data.Client{
-		ID:           rand.Int63(),
-		HashedSecret: hashedSec,
-		Name:         uuid.NewString(),
-		Website:      "test.com",
-		Scope:        "read",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+	user := &data.User{
+		ID:            rand.Int63(),
+		Username:      uuid.NewString(),
+		Email:         "user@test.com",
+		EmailVerified: true,
 	}
 	authorization := &data.Authorization{
-		UserID:    rand.Int63(),
+		UserID:    user.ID,
 		ClientID:  client.ID,
 		AuthCode:  uuid.NewString(),
-		Scope:     "scope",
-		CreatedAt: time.Now(),
+		Scope:     "openid email",
+		Nonce:     uuid.NewString(),
+		AuthTime:  time.Now().Add(-1 * time.Minute),
 		ExpiresAt: time.Now().Add(15 * time.Minute),
-		IsRevoked: false,
 	}
 
 	mockDAL := &dalMock.DataProvider{}
 	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
-	mockDAL.On("GetAuthorizationCodeByAuthCode", mock.Anything, mock.Anything, mock.Anything).Return(authorization, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(authorization, nil)
+	mockDAL.On("CreateTokenFamily", mock.Anything, mock.Anything).Return(&data.TokenFamily{ID: uuid.NewString()}, nil)
+	mockDAL.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(&data.RefreshToken{}, nil)
+	mockDAL.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
 
 	mockTokenHandler := &tokenMock.TokenHandler{}
-	mockTokenHandler.On("Generate", mock.Anything, mock.Anything, credentials.AccessToken).Times(1).Return(accessToken, nil)
-	mockTokenHandler.On("Generate", mock.Anything, mock.Anything, credentials.RefreshToken).Times(2).Return(refreshToken, nil)
+	mockTokenHandler.On("Generate", mock.Anything, mock.Anything, mock.Anything).Return(uuid.NewString(), nil)
+	mockTokenHandler.On("GenerateIDToken", mock.Anything, credentials.Identity{
+		Subject:       user.ID,
+		ClientID:      client.ID,
+		Nonce:         authorization.Nonce,
+		AuthTime:      authorization.AuthTime,
+		Email:         user.Email,
+		EmailVerified: &user.EmailVerified,
+	}).Return(idToken, nil)
 
-	authService := NewClientAuthService(mockDAL, mockTokenHandler)
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
 	rsp, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
 		ClientId:          client.ID,
 		ClientSecret:      clientSecret,
@@ -172,8 +176,7 @@ func TestExchangeToken_HappyPath(t *testing.T) {
 	})
 
 	assert.Equal(t, err, nil)
-	assert.Equal(t, rsp.AccessToken, accessToken)
-	assert.Equal(t, rsp.RefreshToken, refreshToken)
+	assert.Equal(t, rsp.IdToken, idToken)
 }
 
 func TestExchangeToken_Unauthenticated(t *testing.T) {
@@ -203,9 +206,9 @@ func TestExchangeToken_Unauthenticated(t *testing.T) {
 
 	mockDAL := &dalMock.DataProvider{}
 	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
-	mockDAL.On("GetAuthorizationCodeByAuthCode", mock.Anything, mock.Anything, mock.Anything).Return(authorization, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(authorization, nil)
 
-	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{})
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
 	_, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
 		ClientId:          client.ID,
 		ClientSecret:      clientSecret,
@@ -218,34 +221,866 @@ func TestExchangeToken_Unauthenticated(t *testing.T) {
 
 func TestRefreshToken_HappyPath(t *testing.T) {
 	accessToken := uuid.NewString()
+	refreshToken := uuid.NewString()
+	presentedToken := uuid.NewString()
+	familyID := uuid.NewString()
+	client := &data.Client{
+		ID:       rand.Int63(),
+		Name:     uuid.NewString(),
+		Website:  "test.com",
+		IsPublic: true,
+	}
 
-	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("UseRefreshToken", mock.Anything, hashToken(presentedToken)).Return(&data.RefreshToken{FamilyID: familyID}, nil)
+	mockDAL.On("GetUserByID", mock.Anything, mock.Anything).Return(&data.User{}, nil)
+	mockDAL.On("CreateRefreshToken", mock.Anything, data.CreateRefreshTokenParams{
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+	}).Return(&data.RefreshToken{}, nil)
 
+	mockTokenHandler := &tokenMock.TokenHandler{}
 	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
-		Subject:   rand.Int63(),
-		ExpiresAt: time.Now().Add(1 * time.Hour),
+		GrantID:     familyID,
+		Subject:     rand.Int63(),
+		SubjectType: credentials.UserSubject,
+		ClientID:    client.ID,
+		TokenType:   credentials.RefreshToken,
+		ExpiresAt:   time.Now().Add(1 * time.Hour),
 	}, nil)
-
 	mockTokenHandler.On("Generate", mock.Anything, mock.Anything, credentials.AccessToken).Return(accessToken, nil)
+	mockTokenHandler.On("Generate", mock.Anything, mock.Anything, credentials.RefreshToken).Return(refreshToken, nil)
 
-	authService := NewClientAuthService(&dalMock.DataProvider{}, mockTokenHandler)
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
 	rsp, err := authService.RefreshToken(context.Background(), &pb.RefreshTokenRequest{
-		RefreshToken: mock.Anything,
+		ClientId:     client.ID,
+		RefreshToken: presentedToken,
 	})
 
 	assert.Equal(t, err, nil)
 	assert.Equal(t, rsp.AccessToken, accessToken)
+	assert.Equal(t, rsp.RefreshToken, refreshToken)
+}
+
+func TestRefreshToken_DownScope(t *testing.T) {
+	familyID := uuid.NewString()
+	presentedToken := uuid.NewString()
+	refreshToken := uuid.NewString()
+	client := &data.Client{
+		ID:       rand.Int63(),
+		Name:     uuid.NewString(),
+		Website:  "test.com",
+		IsPublic: true,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("UseRefreshToken", mock.Anything, hashToken(presentedToken)).Return(&data.RefreshToken{FamilyID: familyID}, nil)
+	mockDAL.On("GetUserByID", mock.Anything, mock.Anything).Return(&data.User{}, nil)
+	mockDAL.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(&data.RefreshToken{}, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		GrantID:     familyID,
+		Subject:     rand.Int63(),
+		SubjectType: credentials.UserSubject,
+		ClientID:    client.ID,
+		Scope:       "read write",
+		TokenType:   credentials.RefreshToken,
+		ExpiresAt:   time.Now().Add(1 * time.Hour),
+	}, nil)
+	mockTokenHandler.On("Generate", mock.Anything, mock.MatchedBy(func(sub credentials.Subject) bool {
+		return sub.Scope == "read"
+	}), credentials.AccessToken).Return(uuid.NewString(), nil)
+	mockTokenHandler.On("Generate", mock.Anything, mock.MatchedBy(func(sub credentials.Subject) bool {
+		return sub.Scope == "read write"
+	}), credentials.RefreshToken).Return(refreshToken, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	rsp, err := authService.RefreshToken(context.Background(), &pb.RefreshTokenRequest{
+		ClientId:     client.ID,
+		RefreshToken: presentedToken,
+		Scope:        "read",
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.Scope, "read")
+	assert.Equal(t, rsp.RefreshToken, refreshToken)
+}
+
+func TestRefreshToken_UpScope(t *testing.T) {
+	client := &data.Client{
+		ID:       rand.Int63(),
+		Name:     uuid.NewString(),
+		Website:  "test.com",
+		IsPublic: true,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		GrantID:     uuid.NewString(),
+		Subject:     rand.Int63(),
+		SubjectType: credentials.UserSubject,
+		ClientID:    client.ID,
+		Scope:       "read",
+		TokenType:   credentials.RefreshToken,
+		ExpiresAt:   time.Now().Add(1 * time.Hour),
+	}, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.RefreshToken(context.Background(), &pb.RefreshTokenRequest{
+		ClientId:     client.ID,
+		RefreshToken: uuid.NewString(),
+		Scope:        "read write",
+	})
+
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	mockDAL.AssertNotCalled(t, "UseRefreshToken", mock.Anything, mock.Anything)
 }
 
 func TestRefreshToken_Unauthenticated(t *testing.T) {
+	client := &data.Client{ID: rand.Int63(), IsPublic: true}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
 
 	mockTokenHandler := &tokenMock.TokenHandler{}
 	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{}, credentials.ErrInvalidToken)
 
-	authService := NewClientAuthService(&dalMock.DataProvider{}, mockTokenHandler)
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
 	_, err := authService.RefreshToken(context.Background(), &pb.RefreshTokenRequest{
+		ClientId:     client.ID,
 		RefreshToken: mock.Anything,
 	})
 
 	assert.Equal(t, status.Code(err), codes.Unauthenticated)
 }
+
+func TestRefreshToken_ReuseRevokesFamily(t *testing.T) {
+	familyID := uuid.NewString()
+	client := &data.Client{ID: rand.Int63(), IsPublic: true}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("UseRefreshToken", mock.Anything, mock.Anything).Return(&data.RefreshToken{FamilyID: familyID}, data.ErrRefreshTokenUsed)
+	mockDAL.On("RevokeTokenFamily", mock.Anything, familyID).Return(nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		GrantID:     familyID,
+		Subject:     rand.Int63(),
+		SubjectType: credentials.UserSubject,
+		ClientID:    client.ID,
+		TokenType:   credentials.RefreshToken,
+	}, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.RefreshToken(context.Background(), &pb.RefreshTokenRequest{
+		ClientId:     client.ID,
+		RefreshToken: uuid.NewString(),
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	mockDAL.AssertCalled(t, "RevokeTokenFamily", mock.Anything, familyID)
+	mockTokenHandler.AssertNotCalled(t, "Generate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRefreshToken_OtherClientsToken(t *testing.T) {
+	client := &data.Client{ID: rand.Int63(), IsPublic: true}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		Subject:   rand.Int63(),
+		ClientID:  client.ID + 1,
+		TokenType: credentials.RefreshToken,
+	}, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.RefreshToken(context.Background(), &pb.RefreshTokenRequest{
+		ClientId:     client.ID,
+		RefreshToken: uuid.NewString(),
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	mockDAL.AssertNotCalled(t, "UseRefreshToken", mock.Anything, mock.Anything)
+}
+
+func TestExchangeToken_PKCEPublicClient(t *testing.T) {
+	accessToken := uuid.NewString()
+	refreshToken := uuid.NewString()
+	verifier := uuid.NewString() + uuid.NewString()
+	challenge := sha256.Sum256([]byte(verifier))
+
+	client := &data.Client{
+		ID:        rand.Int63(),
+		Name:      uuid.NewString(),
+		Website:   "test.com",
+		Scope:     "read",
+		IsPublic:  true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	authorization := &data.Authorization{
+		UserID:              rand.Int63(),
+		ClientID:            client.ID,
+		AuthCode:            uuid.NewString(),
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(challenge[:]),
+		CodeChallengeMethod: CodeChallengeMethodS256,
+		CreatedAt:           time.Now(),
+		ExpiresAt:           time.Now().Add(15 * time.Minute),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(authorization, nil)
+	mockDAL.On("GetUserByID", mock.Anything, authorization.UserID).Return(&data.User{ID: authorization.UserID}, nil)
+	mockDAL.On("CreateTokenFamily", mock.Anything, mock.Anything).Return(&data.TokenFamily{ID: uuid.NewString()}, nil)
+	mockDAL.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(&data.RefreshToken{}, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Generate", mock.Anything, mock.Anything, credentials.AccessToken).Return(accessToken, nil)
+	mockTokenHandler.On("Generate", mock.Anything, mock.Anything, credentials.RefreshToken).Return(refreshToken, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	rsp, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
+		ClientId:          client.ID,
+		AuthorizationCode: authorization.AuthCode,
+		CodeVerifier:      verifier,
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.AccessToken, accessToken)
+	assert.Equal(t, rsp.RefreshToken, refreshToken)
+}
+
+func TestExchangeToken_InvalidCodeVerifier(t *testing.T) {
+	client := &data.Client{
+		ID:       rand.Int63(),
+		Name:     uuid.NewString(),
+		Website:  "test.com",
+		IsPublic: true,
+	}
+	authorization := &data.Authorization{
+		UserID:              rand.Int63(),
+		ClientID:            client.ID,
+		AuthCode:            uuid.NewString(),
+		CodeChallenge:       uuid.NewString() + uuid.NewString(),
+		CodeChallengeMethod: CodeChallengeMethodPlain,
+		ExpiresAt:           time.Now().Add(15 * time.Minute),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(authorization, nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+	_, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
+		ClientId:          client.ID,
+		AuthorizationCode: authorization.AuthCode,
+		CodeVerifier:      uuid.NewString() + uuid.NewString(),
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestExchangeToken_PublicClientWithoutPKCE(t *testing.T) {
+	client := &data.Client{
+		ID:       rand.Int63(),
+		Name:     uuid.NewString(),
+		Website:  "test.com",
+		IsPublic: true,
+	}
+	authorization := &data.Authorization{
+		UserID:    rand.Int63(),
+		ClientID:  client.ID,
+		AuthCode:  uuid.NewString(),
+		ExpiresAt: time.Now().Add(15 * time.Minute),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(authorization, nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+	_, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
+		ClientId:          client.ID,
+		AuthorizationCode: authorization.AuthCode,
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestExchangeToken_MismatchedRedirectURI(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+		RedirectURIs: []string{"https://client.test/callback", "https://client.test/other"},
+	}
+	authorization := &data.Authorization{
+		UserID:      rand.Int63(),
+		ClientID:    client.ID,
+		AuthCode:    uuid.NewString(),
+		RedirectURI: client.RedirectURIs[0],
+		ExpiresAt:   time.Now().Add(15 * time.Minute),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(authorization, nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+	_, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
+		ClientId:          client.ID,
+		ClientSecret:      clientSecret,
+		AuthorizationCode: authorization.AuthCode,
+		RedirectUri:       client.RedirectURIs[1],
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestClientCredentialsToken_HappyPath(t *testing.T) {
+	clientSecret := uuid.NewString()
+	accessToken := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+		Scope:        "read write",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Generate", mock.Anything, credentials.Subject{
+		Type:     credentials.ClientSubject,
+		ID:       client.ID,
+		ClientID: client.ID,
+		Scope:    "read",
+	}, credentials.AccessToken).Return(accessToken, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	rsp, err := authService.ClientCredentialsToken(context.Background(), &pb.ClientCredentialsTokenRequest{
+		ClientId:     client.ID,
+		ClientSecret: clientSecret,
+		Scope:        "read",
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.AccessToken, accessToken)
+	assert.Equal(t, rsp.TokenType, BearerTokenType)
+	assert.Equal(t, rsp.Scope, "read")
+}
+
+func TestClientCredentialsToken_InvalidScope(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+		Scope:        "read",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+	_, err := authService.ClientCredentialsToken(context.Background(), &pb.ClientCredentialsTokenRequest{
+		ClientId:     client.ID,
+		ClientSecret: clientSecret,
+		Scope:        "read admin",
+	})
+
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestClientCredentialsToken_PublicClient(t *testing.T) {
+	client := &data.Client{
+		ID:       rand.Int63(),
+		Name:     uuid.NewString(),
+		Website:  "test.com",
+		Scope:    "read",
+		IsPublic: true,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+	_, err := authService.ClientCredentialsToken(context.Background(), &pb.ClientCredentialsTokenRequest{
+		ClientId:     client.ID,
+		ClientSecret: uuid.NewString(),
+	})
+
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+}
+
+func TestClientCredentialsToken_Throttled(t *testing.T) {
+	secret := uuid.NewString()
+	hashedSecret, _ := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.MinCost)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSecret,
+		Scope:        "read",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, client.ID).Return(client, nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+
+	for i := 0; i <= config.DefaultThrottleFreeAttempts; i++ {
+		_, err := authService.ClientCredentialsToken(context.Background(), &pb.ClientCredentialsTokenRequest{
+			ClientId:     client.ID,
+			ClientSecret: uuid.NewString(),
+		})
+		assert.Equal(t, err, ErrInvalidClient)
+	}
+
+	_, err := authService.ClientCredentialsToken(context.Background(), &pb.ClientCredentialsTokenRequest{
+		ClientId:     client.ID,
+		ClientSecret: secret,
+	})
+	assert.Equal(t, status.Code(err), codes.ResourceExhausted)
+}
+
+func TestIntrospectToken_Active(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+	}
+	claims := &credentials.Claims{
+		Subject:     int64(42),
+		SubjectType: credentials.UserSubject,
+		ClientID:    rand.Int63(),
+		Scope:       "read",
+		TokenType:   credentials.AccessToken,
+		Issuer:      "goauth",
+		Audience:    "users",
+		IssuedAt:    time.Now(),
+		ExpiresAt:   time.Now().Add(1 * time.Hour),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(claims, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	rsp, err := authService.IntrospectToken(context.Background(), &pb.IntrospectTokenRequest{
+		ClientId:     client.ID,
+		ClientSecret: clientSecret,
+		Token:        uuid.NewString(),
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.Active, true)
+	assert.Equal(t, rsp.Sub, "42")
+	assert.Equal(t, rsp.ClientId, claims.ClientID)
+	assert.Equal(t, rsp.Scope, claims.Scope)
+	assert.Equal(t, rsp.Exp, claims.ExpiresAt.Unix())
+	assert.Equal(t, rsp.TokenType, BearerTokenType)
+}
+
+func TestIntrospectToken_Inactive(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{}, credentials.ErrInvalidToken)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	rsp, err := authService.IntrospectToken(context.Background(), &pb.IntrospectTokenRequest{
+		ClientId:     client.ID,
+		ClientSecret: clientSecret,
+		Token:        uuid.NewString(),
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.Active, false)
+	assert.Equal(t, rsp.Sub, "")
+}
+
+func TestIntrospectToken_PublicClient(t *testing.T) {
+	client := &data.Client{
+		ID:       rand.Int63(),
+		Name:     uuid.NewString(),
+		Website:  "test.com",
+		IsPublic: true,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.IntrospectToken(context.Background(), &pb.IntrospectTokenRequest{
+		ClientId: client.ID,
+		Token:    uuid.NewString(),
+	})
+
+	assert.Equal(t, err, ErrPublicClient)
+	mockTokenHandler.AssertNotCalled(t, "Validate", mock.Anything, mock.Anything)
+}
+
+func TestRevokeToken_HappyPath(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		Subject:   rand.Int63(),
+		ClientID:  client.ID,
+		TokenType: credentials.RefreshToken,
+	}, nil)
+	mockTokenHandler.On("Invalidate", mock.Anything, mock.Anything).Return(nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.RevokeToken(context.Background(), &pb.RevokeTokenRequest{
+		ClientId:     client.ID,
+		ClientSecret: clientSecret,
+		Token:        uuid.NewString(),
+	})
+
+	assert.Equal(t, err, nil)
+	mockTokenHandler.AssertCalled(t, "Invalidate", mock.Anything, mock.Anything)
+}
+
+func TestRevokeToken_InvalidTokenIsNotAnError(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{}, credentials.ErrInvalidToken)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.RevokeToken(context.Background(), &pb.RevokeTokenRequest{
+		ClientId:     client.ID,
+		ClientSecret: clientSecret,
+		Token:        uuid.NewString(),
+	})
+
+	assert.Equal(t, err, nil)
+	mockTokenHandler.AssertNotCalled(t, "Invalidate", mock.Anything, mock.Anything)
+}
+
+func TestRevokeToken_OtherClientsToken(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		Subject:  rand.Int63(),
+		ClientID: client.ID + 1,
+	}, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.RevokeToken(context.Background(), &pb.RevokeTokenRequest{
+		ClientId:     client.ID,
+		ClientSecret: clientSecret,
+		Token:        uuid.NewString(),
+	})
+
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+}
+
+func TestExchangeToken_ExpiredCode(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+	}
+	authorization := &data.Authorization{
+		UserID:    rand.Int63(),
+		ClientID:  client.ID,
+		AuthCode:  uuid.NewString(),
+		CreatedAt: time.Now().Add(-20 * time.Minute),
+		ExpiresAt: time.Now().Add(-10 * time.Minute),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return(authorization, nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+	_, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
+		ClientId:          client.ID,
+		ClientSecret:      clientSecret,
+		AuthorizationCode: authorization.AuthCode,
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestExchangeToken_ReplayedCodeRevokesTokens(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+	}
+	usedAt := time.Now()
+	authorization := &data.Authorization{
+		UserID:    rand.Int63(),
+		ClientID:  client.ID,
+		AuthCode:  uuid.NewString(),
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+		UsedAt:    &usedAt,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, authorization.AuthCode).Return(authorization, data.ErrAuthorizationCodeUsed)
+	mockDAL.On("RevokeAuthorizationCode", mock.Anything, authorization.AuthCode).Return(nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+	_, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
+		ClientId:          client.ID,
+		ClientSecret:      clientSecret,
+		AuthorizationCode: authorization.AuthCode,
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	mockDAL.AssertCalled(t, "RevokeAuthorizationCode", mock.Anything, authorization.AuthCode)
+}
+
+func TestExchangeToken_ReplayedCodeWithoutAuthorization(t *testing.T) {
+	clientSecret := uuid.NewString()
+	authCode := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, authCode).Return((*data.Authorization)(nil), data.ErrAuthorizationCodeUsed)
+	mockDAL.On("RevokeAuthorizationCode", mock.Anything, authCode).Return(nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+	_, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
+		ClientId:          client.ID,
+		ClientSecret:      clientSecret,
+		AuthorizationCode: authCode,
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	mockDAL.AssertCalled(t, "RevokeAuthorizationCode", mock.Anything, authCode)
+}
+
+func TestExchangeToken_RejectedCodeIsNotRevoked(t *testing.T) {
+	clientSecret := uuid.NewString()
+	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	client := &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSec,
+		Name:         uuid.NewString(),
+		Website:      "test.com",
+	}
+	authorization := &data.Authorization{
+		UserID:      rand.Int63(),
+		ClientID:    client.ID,
+		AuthCode:    uuid.NewString(),
+		RedirectURI: "https://client.test/callback",
+		ExpiresAt:   time.Now().Add(10 * time.Minute),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, authorization.AuthCode).Return(authorization, nil)
+
+	authService := NewClientAuthService(mockDAL, &tokenMock.TokenHandler{}, newHasher(), newThrottler())
+	_, err := authService.ExchangeToken(context.Background(), &pb.ExchangeTokenRequest{
+		ClientId:          client.ID,
+		ClientSecret:      clientSecret,
+		AuthorizationCode: authorization.AuthCode,
+		RedirectUri:       "https://attacker.test/callback",
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	mockDAL.AssertNotCalled(t, "RevokeAuthorizationCode", mock.Anything, mock.Anything)
+	mockDAL.AssertNotCalled(t, "CreateTokenFamily", mock.Anything, mock.Anything)
+}
+
+func TestUserInfo_HappyPath(t *testing.T) {
+	user := &data.User{
+		ID:       rand.Int63(),
+		Username: uuid.NewString(),
+		Email:    "user@test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		Subject:     user.ID,
+		SubjectType: credentials.UserSubject,
+		Scope:       "openid profile",
+		TokenType:   credentials.AccessToken,
+	}, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	rsp, err := authService.UserInfo(context.Background(), &pb.UserInfoRequest{
+		AccessToken: uuid.NewString(),
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.Sub, strconv.FormatInt(user.ID, 10))
+	assert.Equal(t, rsp.PreferredUsername, user.Username)
+	assert.Equal(t, rsp.Email, "")
+	assert.Equal(t, rsp.EmailVerified, nil)
+}
+
+func TestUserInfo_EmailScope(t *testing.T) {
+	user := &data.User{
+		ID:       rand.Int63(),
+		Username: uuid.NewString(),
+		Email:    "user@test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		Subject:     user.ID,
+		SubjectType: credentials.UserSubject,
+		Scope:       "openid email",
+		TokenType:   credentials.AccessToken,
+	}, nil)
+
+	authService := NewClientAuthService(mockDAL, mockTokenHandler, newHasher(), newThrottler())
+	rsp, err := authService.UserInfo(context.Background(), &pb.UserInfoRequest{
+		AccessToken: uuid.NewString(),
+	})
+
+	// An unverified address is released as such, rather than leaving the claim out
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.Email, user.Email)
+	assert.NotEqual(t, rsp.EmailVerified, nil)
+	assert.Equal(t, rsp.GetEmailVerified(), false)
+	assert.Equal(t, rsp.PreferredUsername, "")
+}
+
+func TestUserInfo_WithoutOpenIDScope(t *testing.T) {
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		Subject:     rand.Int63(),
+		SubjectType: credentials.UserSubject,
+		Scope:       "read",
+		TokenType:   credentials.AccessToken,
+	}, nil)
+
+	authService := NewClientAuthService(&dalMock.DataProvider{}, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.UserInfo(context.Background(), &pb.UserInfoRequest{
+		AccessToken: uuid.NewString(),
+	})
+
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+}
+
+func TestUserInfo_ClientToken(t *testing.T) {
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return(&credentials.Claims{
+		Subject:     rand.Int63(),
+		SubjectType: credentials.ClientSubject,
+		Scope:       "openid",
+		TokenType:   credentials.AccessToken,
+	}, nil)
+
+	authService := NewClientAuthService(&dalMock.DataProvider{}, mockTokenHandler, newHasher(), newThrottler())
+	_, err := authService.UserInfo(context.Background(), &pb.UserInfoRequest{
+		AccessToken: uuid.NewString(),
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestGetJWKS_HappyPath(t *testing.T) {
+	keySet := &credentials.JWKSet{Keys: []credentials.JWK{{
+		KeyType:   "EC",
+		KeyID:     uuid.NewString(),
+		Use:       "sig",
+		Algorithm: "ES256",
+		Curve:     "P-256",
+		X:         uuid.NewString(),
+		Y:         uuid.NewString(),
+	}}}
+
+	mockTokenHandler := &tokenMock.TokenHandler{}
+	mockTokenHandler.On("KeySet", mock.Anything).Return(keySet, nil)
+
+	authService := NewClientAuthService(&dalMock.DataProvider{}, mockTokenHandler, newHasher(), newThrottler())
+	rsp, err := authService.GetJWKS(context.Background(), &pb.GetJWKSRequest{})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rsp.Keys), 1)
+	assert.Equal(t, rsp.Keys[0].Kid, keySet.Keys[0].KeyID)
+	assert.Equal(t, rsp.Keys[0].Crv, "P-256")
+}
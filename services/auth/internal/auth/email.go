@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ramyadmz/goauth/internal/data"
+	"github.com/ramyadmz/goauth/internal/mail"
+	"github.com/ramyadmz/goauth/pkg/pb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VerifyEmail marks the email address a verification token was sent to as verified.
+func (u *UserAuthService) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
+	logger := logrus.WithContext(ctx)
+	logger.Info("email verification request recieved")
+
+	verification, err := u.dal.UseEmailVerification(ctx, hashToken(req.Token))
+	if err != nil {
+		if err == data.ErrEmailVerificationNotFound {
+			logger.Warn("invalid or expired verification token: %w", err)
+			return nil, status.Errorf(codes.InvalidArgument, "invalid or expired verification token")
+		}
+		logger.Error("error verifying email: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	logger.WithField("userID", verification.UserID).Info("email verified successfully")
+	return &pb.VerifyEmailResponse{}, nil
+}
+
+// ResendVerificationEmail sends a new verification token to an unverified email address. It responds
+// the same whether or not an account with the address exists, and sends at most one email per resend
+// interval, so that it can't be used to find accounts or to flood an inbox.
+func (u *UserAuthService) ResendVerificationEmail(ctx context.Context, req *pb.ResendVerificationEmailRequest) (*pb.ResendVerificationEmailResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("email", req.Email)
+	logger.Info("verification email resend request recieved")
+
+	user, err := u.dal.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == data.ErrUserNotFound {
+			logger.Warn("no user with this email address")
+			return &pb.ResendVerificationEmailResponse{}, nil
+		}
+		logger.Error("error fetching user by email: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+	logger = logger.WithField("userID", user.ID)
+
+	if user.EmailVerified {
+		logger.Warn("email address already verified")
+		return &pb.ResendVerificationEmailResponse{}, nil
+	}
+
+	latest, err := u.dal.GetLatestEmailVerification(ctx, user.ID)
+	if err != nil && err != data.ErrEmailVerificationNotFound {
+		logger.Error("error fetching email verification: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+	if err == nil && time.Since(latest.CreatedAt) < u.emailConfig.GetResendInterval() {
+		logger.Warn("verification email resent too soon")
+		return &pb.ResendVerificationEmailResponse{}, nil
+	}
+
+	// Failing to mail the token is only logged, since an error would tell that the address is known
+	if err := u.sendVerificationEmail(ctx, user); err != nil {
+		logger.Error("error sending verification email: %w", err)
+		return &pb.ResendVerificationEmailResponse{}, nil
+	}
+
+	logger.Info("verification email resent successfully")
+	return &pb.ResendVerificationEmailResponse{}, nil
+}
+
+// CleanupEmailVerifications periodically removes expired verification tokens until ctx is canceled.
+func (u *UserAuthService) CleanupEmailVerifications(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := u.dal.DeleteExpiredEmailVerifications(ctx); err != nil {
+				logrus.WithContext(ctx).Error("error cleaning up email verifications: %w", err)
+			}
+		}
+	}
+}
+
+// sendVerificationEmail issues a new verification token for the user's email address and mails it.
+// Only the hash of the token is stored.
+func (u *UserAuthService) sendVerificationEmail(ctx context.Context, user *data.User) error {
+	token, err := generateSecret(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.dal.CreateEmailVerification(ctx, data.CreateEmailVerificationParams{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(u.emailConfig.GetExpiration()),
+	})
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nPlease verify your email address with the following token:\n\n%s\n\n", user.Username, token)
+	if link := u.emailConfig.GetURL(); len(link) > 0 {
+		fmt.Fprintf(&body, "or by opening this link:\n\n%s\n\n", withQuery(link, "token", token))
+	}
+	fmt.Fprintf(&body, "The token expires in %s. If you didn't create an account, you can ignore this email.\n", u.emailConfig.GetExpiration())
+
+	return u.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    body.String(),
+	})
+}
+
+// withQuery appends a query parameter to a URL that may already have a query.
+func withQuery(link, key, value string) string {
+	separator := "?"
+	if strings.Contains(link, "?") {
+		separator = "&"
+	}
+	return link + separator + url.Values{key: {value}}.Encode()
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	credMock "github.com/ramyadmz/goauth/internal/credentials/mock"
+	"github.com/ramyadmz/goauth/internal/data"
+	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
+	"github.com/ramyadmz/goauth/internal/mail"
+	"github.com/ramyadmz/goauth/pkg/pb"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVerifyEmail_HappyPath(t *testing.T) {
+	token := uuid.NewString()
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("UseEmailVerification", mock.Anything, hashToken(token)).Return(&data.EmailVerification{
+		TokenHash: hashToken(token),
+		UserID:    rand.Int63(),
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.VerifyEmail(context.Background(), &pb.VerifyEmailRequest{Token: token})
+
+	assert.Equal(t, err, nil)
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("UseEmailVerification", mock.Anything, mock.Anything).Return((*data.EmailVerification)(nil), data.ErrEmailVerificationNotFound)
+
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.VerifyEmail(context.Background(), &pb.VerifyEmailRequest{Token: uuid.NewString()})
+
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestResendVerificationEmail_HappyPath(t *testing.T) {
+	user := &data.User{
+		ID:       rand.Int63(),
+		Username: uuid.NewString(),
+		Email:    "user@test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockDAL.On("GetLatestEmailVerification", mock.Anything, user.ID).Return(&data.EmailVerification{
+		UserID:    user.ID,
+		CreatedAt: time.Now().Add(-time.Hour),
+	}, nil)
+	mockDAL.On("CreateEmailVerification", mock.Anything, mock.Anything).Return(&data.EmailVerification{}, nil)
+
+	mailer := mail.NewMemoryMailer()
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mailer, newEmailConfig(), newResetConfig())
+	_, err := authService.ResendVerificationEmail(context.Background(), &pb.ResendVerificationEmailRequest{Email: user.Email})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mailer.Messages()), 1)
+	assert.Equal(t, mailer.Messages()[0].To, user.Email)
+}
+
+func TestResendVerificationEmail_TooSoon(t *testing.T) {
+	user := &data.User{
+		ID:    rand.Int63(),
+		Email: "user@test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockDAL.On("GetLatestEmailVerification", mock.Anything, user.ID).Return(&data.EmailVerification{
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+	}, nil)
+
+	mailer := mail.NewMemoryMailer()
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mailer, newEmailConfig(), newResetConfig())
+	_, err := authService.ResendVerificationEmail(context.Background(), &pb.ResendVerificationEmailRequest{Email: user.Email})
+
+	// The response doesn't reveal that an email was sent recently
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mailer.Messages()), 0)
+	mockDAL.AssertNotCalled(t, "CreateEmailVerification", mock.Anything, mock.Anything)
+}
+
+func TestResendVerificationEmail_MailerFailure(t *testing.T) {
+	user := &data.User{
+		ID:    rand.Int63(),
+		Email: "user@test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockDAL.On("GetLatestEmailVerification", mock.Anything, user.ID).Return((*data.EmailVerification)(nil), data.ErrEmailVerificationNotFound)
+	mockDAL.On("CreateEmailVerification", mock.Anything, mock.Anything).Return(&data.EmailVerification{}, nil)
+
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), failingMailer{}, newEmailConfig(), newResetConfig())
+	rsp, err := authService.ResendVerificationEmail(context.Background(), &pb.ResendVerificationEmailRequest{Email: user.Email})
+
+	// The response is the same as for an unknown address
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, rsp, nil)
+}
+
+func TestResendVerificationEmail_UnknownEmail(t *testing.T) {
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByEmail", mock.Anything, mock.Anything).Return((*data.User)(nil), data.ErrUserNotFound)
+
+	mailer := mail.NewMemoryMailer()
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mailer, newEmailConfig(), newResetConfig())
+	_, err := authService.ResendVerificationEmail(context.Background(), &pb.ResendVerificationEmailRequest{Email: "nobody@test.com"})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mailer.Messages()), 0)
+}
+
+func TestSendVerificationEmail_Link(t *testing.T) {
+	t.Setenv("OAUTH_EMAIL_VERIFICATION_URL", "https://app.test/verify?lang=en")
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("CreateEmailVerification", mock.Anything, mock.Anything).Return(&data.EmailVerification{}, nil)
+
+	mailer := mail.NewMemoryMailer()
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mailer, newEmailConfig(), newResetConfig())
+	err := authService.sendVerificationEmail(context.Background(), &data.User{ID: 1, Email: "user@test.com"})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(mailer.Messages()[0].Body, "https://app.test/verify?lang=en&token="), true)
+}
+
+func TestLoginUser_EmailNotVerified(t *testing.T) {
+	t.Setenv("OAUTH_EMAIL_VERIFICATION_REQUIRED", "true")
+
+	password := uuid.NewString()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	user := &data.User{
+		ID:             rand.Int63(),
+		Username:       uuid.NewString(),
+		HashedPassword: hashedPassword,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByUsername", mock.Anything, user.Username).Return(user, nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.LoginUser(context.Background(), &pb.UserLoginRequest{
+		Username: user.Username,
+		Password: password,
+	})
+
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+	mockSessionManager.AssertNotCalled(t, "Start", mock.Anything, mock.Anything)
+}
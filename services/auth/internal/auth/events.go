@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Security events worth alerting on, emitted as structured log entries.
+const (
+	EventRefreshTokenReuse      = "refresh_token_reuse"
+	EventAuthorizationCodeReuse = "authorization_code_reuse"
+)
+
+// emitSecurityEvent records a security relevant event with the given context fields.
+func emitSecurityEvent(ctx context.Context, event string, fields logrus.Fields) {
+	logrus.WithContext(ctx).WithFields(fields).WithField("security_event", event).Warn("security event detected")
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/ramyadmz/goauth/pkg/pb"
@@ -12,38 +13,98 @@ import (
 
 func ValidationInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	switch info.FullMethod {
-	case "/pb.OAuthService/RegisterUser":
+	case "/proto.OAuthService/RegisterUser":
 		if err := validateRegisterUserRequest(req.(*pb.RegisterUserRequest)); err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "invalid register request: %v", err)
 		}
-	case "/pb.OAuthService/UserLogin":
+	case "/proto.OAuthService/UserLogin":
 		if err := validateUserLoginRequest(req.(*pb.UserLoginRequest)); err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "invalid login request: %v", err)
 		}
-	case "/pb.OAuthService/UserConsent":
+	case "/proto.OAuthService/Authorize":
+		if err := validateAuthorizeRequest(req.(*pb.AuthorizeRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid authorize request: %v", err)
+		}
+	case "/proto.OAuthService/UserConsent":
 		if err := validateUserConsentRequest(req.(*pb.UserConsentRequest)); err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "invalid consent request: %v", err)
 		}
-	case "/pb.OAuthService/UserLogout":
+	case "/proto.OAuthService/UserLogout":
 		if err := validateUserLogoutRequest(req.(*pb.UserLogoutRequest)); err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "invalid login request: %v", err)
 		}
-	case "/pb.OAuthService/RegisterClient":
+	case "/proto.OAuthService/VerifyEmail":
+		if err := validateVerifyEmailRequest(req.(*pb.VerifyEmailRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid email verification request: %v", err)
+		}
+	case "/proto.OAuthService/ResendVerificationEmail":
+		if err := validateResendVerificationEmailRequest(req.(*pb.ResendVerificationEmailRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid email verification request: %v", err)
+		}
+	case "/proto.OAuthService/RequestPasswordReset":
+		if err := validateRequestPasswordResetRequest(req.(*pb.RequestPasswordResetRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid password reset request: %v", err)
+		}
+	case "/proto.OAuthService/ResetPassword":
+		if err := validateResetPasswordRequest(req.(*pb.ResetPasswordRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid password reset request: %v", err)
+		}
+	case "/proto.OAuthService/ChangePassword":
+		if err := validateChangePasswordRequest(req.(*pb.ChangePasswordRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid password change request: %v", err)
+		}
+	case "/proto.OAuthService/VerifyMFA":
+		if err := validateVerifyMFARequest(req.(*pb.VerifyMFARequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid mfa request: %v", err)
+		}
+	case "/proto.OAuthService/EnrollMFA":
+		if err := validateEnrollMFARequest(req.(*pb.EnrollMFARequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid mfa enrollment request: %v", err)
+		}
+	case "/proto.OAuthService/ConfirmMFA":
+		if err := validateConfirmMFARequest(req.(*pb.ConfirmMFARequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid mfa confirmation request: %v", err)
+		}
+	case "/proto.OAuthService/DisableMFA":
+		if err := validateDisableMFARequest(req.(*pb.DisableMFARequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid mfa request: %v", err)
+		}
+	case "/proto.OAuthService/RegenerateRecoveryCodes":
+		if err := validateRegenerateRecoveryCodesRequest(req.(*pb.RegenerateRecoveryCodesRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid recovery codes request: %v", err)
+		}
+	case "/proto.OAuthService/CountRecoveryCodes":
+		if err := validateCountRecoveryCodesRequest(req.(*pb.CountRecoveryCodesRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid recovery codes request: %v", err)
+		}
+	case "/proto.OAuthService/RegisterClient":
 		if err := validateRegisterClientRequest(req.(*pb.RegisterClientRequest)); err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "invalid register request: %v", err)
 		}
-	case "/pb.OAuthService/GetAuthorizationCode":
-		if err := validateGetAuthorizationCodeRequest(req.(*pb.GetAuthorizationCodeRequest)); err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "invalid login request: %v", err)
-		}
-	case "/pb.OAuthService/ExchangeToken":
+	case "/proto.OAuthService/ExchangeToken":
 		if err := validateExchangeTokenRequest(req.(*pb.ExchangeTokenRequest)); err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "invalid consent request: %v", err)
 		}
-	case "/pb.OAuthService/RefreshToken":
+	case "/proto.OAuthService/RefreshToken":
 		if err := validateRefreshTokenRequest(req.(*pb.RefreshTokenRequest)); err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "invalid login request: %v", err)
 		}
+	case "/proto.OAuthService/ClientCredentialsToken":
+		if err := validateClientCredentialsTokenRequest(req.(*pb.ClientCredentialsTokenRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid client credentials request: %v", err)
+		}
+	case "/proto.OAuthService/IntrospectToken":
+		if err := validateIntrospectTokenRequest(req.(*pb.IntrospectTokenRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid introspection request: %v", err)
+		}
+	case "/proto.OAuthService/UserInfo":
+		if err := validateUserInfoRequest(req.(*pb.UserInfoRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid userinfo request: %v", err)
+		}
+	case "/proto.OAuthService/RevokeToken":
+		if err := validateRevokeTokenRequest(req.(*pb.RevokeTokenRequest)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid revocation request: %v", err)
+		}
 	}
 	return handler(ctx, req)
 }
@@ -58,7 +119,8 @@ func validateRegisterUserRequest(req *pb.RegisterUserRequest) error {
 		return err
 	}
 
-	if err := validate.Var(req.Password, "required,min=8"); err != nil {
+	// The length and content of new passwords are up to the password policy
+	if err := validate.Var(req.Password, "required,max=1024"); err != nil {
 		return err
 	}
 
@@ -67,26 +129,70 @@ func validateRegisterUserRequest(req *pb.RegisterUserRequest) error {
 
 func validateUserLoginRequest(req *pb.UserLoginRequest) error {
 	validate := validator.New()
-	if err := validate.Var(req.Username, "required,min=3,max=20"); err != nil {
+	// Imported accounts may not follow the current username and password rules, so only the stored
+	// credentials decide whether a login is valid
+	if err := validate.Var(req.Username, "required"); err != nil {
 		return err
 	}
 
-	if err := validate.Var(req.Password, "required,min=8"); err != nil {
+	if err := validate.Var(req.Password, "required"); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func validateUserConsentRequest(req *pb.UserConsentRequest) error {
+func validateAuthorizeRequest(req *pb.AuthorizeRequest) error {
 	validate := validator.New()
-	if err := validate.Var(req.ClientId, "required,min=4"); err != nil {
+	if err := validate.Var(req.SessionId, "required,min=4"); err != nil {
 		return err
 	}
+
+	if err := validate.Var(req.ClientId, "required"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.RedirectUri, "required,url,excludes=#"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.Scope, "omitempty,max=255"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.State, "omitempty,max=512"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.CodeChallenge, "omitempty,min=43,max=128"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.CodeChallengeMethod, "omitempty,oneof=plain S256"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.Nonce, "omitempty,max=255"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateUserConsentRequest(req *pb.UserConsentRequest) error {
+	validate := validator.New()
 	if err := validate.Var(req.SessionId, "required,min=4"); err != nil {
 		return err
 	}
 
+	if err := validate.Var(req.RequestId, "required,uuid"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.Scope, "omitempty,max=255"); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -99,6 +205,138 @@ func validateUserLogoutRequest(req *pb.UserLogoutRequest) error {
 	return nil
 }
 
+func validateVerifyEmailRequest(req *pb.VerifyEmailRequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.Token, "required,min=4,max=128"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateResendVerificationEmailRequest(req *pb.ResendVerificationEmailRequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.Email, "required,email"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateRequestPasswordResetRequest(req *pb.RequestPasswordResetRequest) error {
+	validate := validator.New()
+	if (len(req.Username) == 0) == (len(req.Email) == 0) {
+		return errors.New("exactly one of username and email is required")
+	}
+
+	if len(req.Email) > 0 {
+		return validate.Var(req.Email, "email")
+	}
+	return validate.Var(req.Username, "min=3,max=20")
+}
+
+func validateResetPasswordRequest(req *pb.ResetPasswordRequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.Token, "required,min=4,max=128"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.NewPassword, "required,max=1024"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateChangePasswordRequest(req *pb.ChangePasswordRequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.SessionId, "required,min=4"); err != nil {
+		return err
+	}
+
+	// The current password may predate the password policy, which checks the new one
+	if err := validate.Var(req.CurrentPassword, "required"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.NewPassword, "required,max=1024"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateVerifyMFARequest(req *pb.VerifyMFARequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.MfaChallenge, "required,uuid"); err != nil {
+		return err
+	}
+
+	// A TOTP code or a recovery code
+	if err := validate.Var(req.Code, "required,max=16"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateEnrollMFARequest(req *pb.EnrollMFARequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.SessionId, "required,min=4"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateConfirmMFARequest(req *pb.ConfirmMFARequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.SessionId, "required,min=4"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.Code, "required,numeric,len=6"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateDisableMFARequest(req *pb.DisableMFARequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.SessionId, "required,min=4"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.Code, "required,max=16"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateRegenerateRecoveryCodesRequest(req *pb.RegenerateRecoveryCodesRequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.SessionId, "required,min=4"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.Code, "required,max=16"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateCountRecoveryCodesRequest(req *pb.CountRecoveryCodesRequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.SessionId, "required,min=4"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func validateRegisterClientRequest(req *pb.RegisterClientRequest) error {
 	validate := validator.New()
 	if err := validate.Var(req.Name, "required,min=4"); err != nil {
@@ -113,29 +351,80 @@ func validateRegisterClientRequest(req *pb.RegisterClientRequest) error {
 		return err
 	}
 
+	// RFC 6749 section 3.1.2: redirect uris must be absolute and must not contain a fragment.
+	if err := validate.Var(req.RedirectUris, "required,min=1,dive,url,excludes=#"); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func validateGetAuthorizationCodeRequest(req *pb.GetAuthorizationCodeRequest) error {
+func validateExchangeTokenRequest(req *pb.ExchangeTokenRequest) error {
 	validate := validator.New()
 	if err := validate.Var(req.ClientId, "required,min=4"); err != nil {
 		return err
 	}
 
+	if err := validate.Var(req.ClientSecret, "omitempty,min=8"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.AuthorizationCode, "required,min=8"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.CodeVerifier, "omitempty,min=43,max=128"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.RedirectUri, "required,url"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateRefreshTokenRequest(req *pb.RefreshTokenRequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.RefreshToken, "required,jwt"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.ClientId, "required"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.ClientSecret, "omitempty,min=8"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.Scope, "omitempty,max=255"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateClientCredentialsTokenRequest(req *pb.ClientCredentialsTokenRequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.ClientId, "required"); err != nil {
+		return err
+	}
+
 	if err := validate.Var(req.ClientSecret, "required,min=8"); err != nil {
 		return err
 	}
 
-	if err := validate.Var(req.Username, "required,min=4,max=20"); err != nil {
+	if err := validate.Var(req.Scope, "omitempty,max=255"); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func validateExchangeTokenRequest(req *pb.ExchangeTokenRequest) error {
+func validateIntrospectTokenRequest(req *pb.IntrospectTokenRequest) error {
 	validate := validator.New()
-	if err := validate.Var(req.ClientId, "required,min=4"); err != nil {
+	if err := validate.Var(req.ClientId, "required"); err != nil {
 		return err
 	}
 
@@ -143,16 +432,34 @@ func validateExchangeTokenRequest(req *pb.ExchangeTokenRequest) error {
 		return err
 	}
 
-	if err := validate.Var(req.AuthorizationCode, "required,min=8"); err != nil {
+	// malformed tokens are reported as inactive, so only presence is checked here
+	if err := validate.Var(req.Token, "required"); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func validateRefreshTokenRequest(req *pb.RefreshTokenRequest) error {
+func validateRevokeTokenRequest(req *pb.RevokeTokenRequest) error {
 	validate := validator.New()
-	if err := validate.Var(req.RefreshToken, "required,jwt"); err != nil {
+	if err := validate.Var(req.ClientId, "required"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.ClientSecret, "omitempty,min=8"); err != nil {
+		return err
+	}
+
+	if err := validate.Var(req.Token, "required"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateUserInfoRequest(req *pb.UserInfoRequest) error {
+	validate := validator.New()
+	if err := validate.Var(req.AccessToken, "required,jwt"); err != nil {
 		return err
 	}
 
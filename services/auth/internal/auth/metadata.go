@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/pkg/pb"
+	"github.com/sirupsen/logrus"
+)
+
+// Paths of the HTTP endpoints, relative to the public URL of the server.
+const (
+	AuthorizationPath               = "/authorize"
+	TokenPath                       = "/token"
+	RevocationPath                  = "/revoke"
+	IntrospectionPath               = "/introspect"
+	UserInfoPath                    = "/userinfo"
+	JWKSPath                        = "/.well-known/jwks.json"
+	AuthorizationServerMetadataPath = "/.well-known/oauth-authorization-server"
+	OpenIDConfigurationPath         = "/.well-known/openid-configuration"
+)
+
+// Grant types accepted by the token endpoint (RFC 6749 section 4).
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// Client authentication methods accepted by the token endpoint (RFC 8414 section 2).
+const (
+	ClientSecretBasic = "client_secret_basic"
+	ClientSecretPost  = "client_secret_post"
+	ClientAuthNone    = "none" // public clients, bound by PKCE instead
+)
+
+// ResponseTypeCode is the only supported response type of the authorization endpoint.
+const ResponseTypeCode = "code"
+
+type MetadataService struct {
+	pb.UnimplementedOAuthServiceServer
+	issuer    string
+	publicURL string
+	algorithm string
+	oidc      bool // ID tokens are only issued when tokens are signed with asymmetric keys
+}
+
+// NewMetadataService creates a new instance of MetadataService describing the running configuration.
+func NewMetadataService(jwtConfig *config.JWTConfig, serverConfig *config.ServerConfig) *MetadataService {
+	return &MetadataService{
+		issuer:    jwtConfig.GetIssuer(),
+		publicURL: serverConfig.GetPublicURL(),
+		algorithm: jwtConfig.GetAlgorithm(),
+		oidc:      !strings.HasPrefix(jwtConfig.GetAlgorithm(), "HS"),
+	}
+}
+
+// GetServerMetadata returns the authorization server metadata (RFC 8414 section 2), which doubles as
+// the OpenID Provider metadata (OIDC Discovery section 3), so clients can configure themselves.
+// OpenID Connect is not advertised while tokens are signed with a shared secret.
+func (m *MetadataService) GetServerMetadata(ctx context.Context, req *pb.GetServerMetadataRequest) (*pb.GetServerMetadataResponse, error) {
+	logrus.WithContext(ctx).Info("server metadata requested")
+
+	metadata := &pb.GetServerMetadataResponse{
+		Issuer:                            m.issuer,
+		AuthorizationEndpoint:             m.publicURL + AuthorizationPath,
+		TokenEndpoint:                     m.publicURL + TokenPath,
+		RevocationEndpoint:                m.publicURL + RevocationPath,
+		IntrospectionEndpoint:             m.publicURL + IntrospectionPath,
+		JwksUri:                           m.publicURL + JWKSPath,
+		ResponseTypesSupported:            []string{ResponseTypeCode},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials},
+		TokenEndpointAuthMethodsSupported: []string{ClientSecretBasic, ClientSecretPost, ClientAuthNone},
+		CodeChallengeMethodsSupported:     []string{CodeChallengeMethodS256, CodeChallengeMethodPlain},
+	}
+
+	if m.oidc {
+		metadata.UserinfoEndpoint = m.publicURL + UserInfoPath
+		metadata.ScopesSupported = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+		metadata.IdTokenSigningAlgValuesSupported = []string{m.algorithm}
+		metadata.SubjectTypesSupported = []string{"public"}
+		metadata.ClaimsSupported = []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "email", "email_verified"}
+	}
+
+	return metadata, nil
+}
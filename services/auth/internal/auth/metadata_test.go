@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/integration"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/pkg/pb"
+)
+
+func TestGetServerMetadata_HappyPath(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+	os.Setenv("OAUTH_PUBLIC_URL", "https://auth.test/")
+	defer os.Unsetenv("OAUTH_PUBLIC_URL")
+	os.Setenv("OAUTH_JWT_ALGORITHM", "ES256")
+
+	jwtConfig, err := config.NewJWTConfig()
+	assert.Equal(t, err, nil)
+	serverConfig, err := config.NewServerConfig()
+	assert.Equal(t, err, nil)
+
+	rsp, err := NewMetadataService(jwtConfig, serverConfig).GetServerMetadata(context.Background(), &pb.GetServerMetadataRequest{})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.Issuer, jwtConfig.GetIssuer())
+	assert.Equal(t, rsp.AuthorizationEndpoint, "https://auth.test/authorize")
+	assert.Equal(t, rsp.UserinfoEndpoint, "https://auth.test/userinfo")
+	assert.Equal(t, rsp.JwksUri, "https://auth.test/.well-known/jwks.json")
+	assert.Equal(t, rsp.IdTokenSigningAlgValuesSupported, []string{jwtConfig.GetAlgorithm()})
+	assert.Equal(t, rsp.ResponseTypesSupported, []string{ResponseTypeCode})
+	assert.Equal(t, rsp.ScopesSupported, []string{ScopeOpenID, ScopeProfile, ScopeEmail})
+}
+
+func TestGetServerMetadata_SharedSecret(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	jwtConfig, err := config.NewJWTConfig()
+	assert.Equal(t, err, nil)
+	serverConfig, err := config.NewServerConfig()
+	assert.Equal(t, err, nil)
+
+	rsp, err := NewMetadataService(jwtConfig, serverConfig).GetServerMetadata(context.Background(), &pb.GetServerMetadataRequest{})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.UserinfoEndpoint, "")
+	assert.Equal(t, len(rsp.IdTokenSigningAlgValuesSupported), 0)
+	assert.Equal(t, len(rsp.ScopesSupported), 0)
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/throttle"
+	"github.com/ramyadmz/goauth/internal/data"
+	"github.com/ramyadmz/goauth/pkg/pb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VerifyMFA completes a login of a user with MFA enabled: the challenge returned by LoginUser and a
+// code of the user's authenticator, or one of the user's recovery codes, are exchanged for a session.
+func (u *UserAuthService) VerifyMFA(ctx context.Context, req *pb.VerifyMFARequest) (*pb.VerifyMFAResponse, error) {
+	logger := logrus.WithContext(ctx)
+	logger.Info("mfa verification request recieved")
+
+	challenge, err := u.mfaManager.GetChallenge(ctx, req.MfaChallenge)
+	if err != nil {
+		if err == credentials.ErrInvalidMFAChallenge {
+			logger.Warn("invalid or expired mfa challenge: %w", err)
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired mfa challenge")
+		}
+		logger.Error("error fetching mfa challenge: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+	logger = logger.WithField("userID", challenge.UserID)
+
+	// Codes are throttled per user, since a new challenge is only a password away
+	throttleKeys := throttle.WithIP(ctx, throttle.MFAKey(challenge.UserID))
+	if err := u.throttler.Attempt(ctx, throttleKeys...); err != nil {
+		return nil, err
+	}
+
+	if err := u.mfaManager.Verify(ctx, challenge.UserID, req.Code); err != nil {
+		if err != credentials.ErrInvalidMFACode {
+			u.throttler.Release(ctx, throttleKeys...)
+		}
+		return nil, mfaError(logger, err)
+	}
+	u.throttler.Succeed(ctx, throttleKeys...)
+
+	// A challenge can only be completed once, even by concurrent calls with valid codes
+	if err := u.mfaManager.EndChallenge(ctx, challenge.ID); err != nil {
+		if err == credentials.ErrInvalidMFAChallenge {
+			logger.Warn("mfa challenge already completed: %w", err)
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired mfa challenge")
+		}
+		logger.Error("error completing mfa challenge: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	session, err := u.sessionManager.Start(ctx, challenge.UserID)
+	if err != nil {
+		logger.Error("error generating authentication session: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	logger.Info("user logged in successfully with mfa")
+	return &pb.VerifyMFAResponse{
+		SessionId: session.SessionID,
+	}, nil
+}
+
+// EnrollMFA generates a new TOTP secret and recovery codes for the user of the session. MFA is only
+// enabled once the enrollment is confirmed with ConfirmMFA.
+func (u *UserAuthService) EnrollMFA(ctx context.Context, req *pb.EnrollMFARequest) (*pb.EnrollMFAResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("session_id", req.SessionId)
+	logger.Info("mfa enrollment request recieved")
+
+	userID, err := u.sessionUser(ctx, logger, req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.dal.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == data.ErrUserNotFound {
+			logger.Warn("session user doesn't exist: %w", err)
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired session")
+		}
+		logger.Error("error fetching user: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	enrollment, err := u.mfaManager.Enroll(ctx, user.ID, user.Username)
+	if err != nil {
+		return nil, mfaError(logger, err)
+	}
+
+	logger.Info("mfa enrollment started successfully")
+	return &pb.EnrollMFAResponse{
+		Secret:        enrollment.Secret,
+		Uri:           enrollment.URI,
+		RecoveryCodes: enrollment.RecoveryCodes,
+	}, nil
+}
+
+// ConfirmMFA enables MFA for the user of the session with a first code of the enrolled authenticator.
+func (u *UserAuthService) ConfirmMFA(ctx context.Context, req *pb.ConfirmMFARequest) (*pb.ConfirmMFAResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("session_id", req.SessionId)
+	logger.Info("mfa confirmation request recieved")
+
+	userID, err := u.sessionUser(ctx, logger, req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.checkMFACode(ctx, userID, req.Code, u.mfaManager.Confirm); err != nil {
+		return nil, mfaError(logger, err)
+	}
+
+	logger.Info("mfa enabled successfully")
+	return &pb.ConfirmMFAResponse{}, nil
+}
+
+// DisableMFA disables MFA for the user of the session. A current code is required, so that a stolen
+// session alone isn't enough to remove the second factor.
+func (u *UserAuthService) DisableMFA(ctx context.Context, req *pb.DisableMFARequest) (*pb.DisableMFAResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("session_id", req.SessionId)
+	logger.Info("mfa disable request recieved")
+
+	userID, err := u.sessionUser(ctx, logger, req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.checkMFACode(ctx, userID, req.Code, u.mfaManager.Disable); err != nil {
+		return nil, mfaError(logger, err)
+	}
+
+	logger.Info("mfa disabled successfully")
+	return &pb.DisableMFAResponse{}, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user of the session. Like DisableMFA, it
+// requires a current code.
+func (u *UserAuthService) RegenerateRecoveryCodes(ctx context.Context, req *pb.RegenerateRecoveryCodesRequest) (*pb.RegenerateRecoveryCodesResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("session_id", req.SessionId)
+	logger.Info("recovery codes regeneration request recieved")
+
+	userID, err := u.sessionUser(ctx, logger, req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	err = u.checkMFACode(ctx, userID, req.Code, func(ctx context.Context, userID int64, code string) error {
+		recoveryCodes, err = u.mfaManager.RegenerateRecoveryCodes(ctx, userID, code)
+		return err
+	})
+	if err != nil {
+		return nil, mfaError(logger, err)
+	}
+
+	logger.Info("recovery codes regenerated successfully")
+	return &pb.RegenerateRecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// CountRecoveryCodes returns how many recovery codes the user of the session has left.
+func (u *UserAuthService) CountRecoveryCodes(ctx context.Context, req *pb.CountRecoveryCodesRequest) (*pb.CountRecoveryCodesResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("session_id", req.SessionId)
+	logger.Info("recovery codes count request recieved")
+
+	userID, err := u.sessionUser(ctx, logger, req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining, err := u.mfaManager.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, mfaError(logger, err)
+	}
+
+	return &pb.CountRecoveryCodesResponse{
+		Remaining: int32(remaining),
+	}, nil
+}
+
+// sessionUser returns the id of the user a session belongs to.
+func (u *UserAuthService) sessionUser(ctx context.Context, logger *logrus.Entry, sessionID string) (int64, error) {
+	session, err := u.sessionManager.Get(ctx, sessionID)
+	if err != nil {
+		if err == credentials.ErrInvalidSession {
+			logger.Error("invalid or expired session: %w", err)
+			return 0, status.Errorf(codes.Unauthenticated, "invalid or expired session")
+		}
+		logger.Error("error validating session: %w", err)
+		return 0, status.Errorf(codes.Internal, "Internal server error")
+	}
+	return session.Subject.(int64), nil
+}
+
+// checkMFACode runs check with a code of the user's authenticator, throttling wrong codes.
+func (u *UserAuthService) checkMFACode(ctx context.Context, userID int64, code string, check func(ctx context.Context, userID int64, code string) error) error {
+	throttleKeys := throttle.WithIP(ctx, throttle.MFAKey(userID))
+	if err := u.throttler.Attempt(ctx, throttleKeys...); err != nil {
+		return err
+	}
+
+	if err := check(ctx, userID, code); err != nil {
+		if err != credentials.ErrInvalidMFACode {
+			u.throttler.Release(ctx, throttleKeys...)
+		}
+		return err
+	}
+
+	u.throttler.Succeed(ctx, throttleKeys...)
+	return nil
+}
+
+// mfaError translates an error of the MFA manager into a gRPC error. Errors that already are gRPC
+// errors, such as throttling errors, are returned as they are.
+func mfaError(logger *logrus.Entry, err error) error {
+	switch err {
+	case credentials.ErrInvalidMFACode:
+		logger.Warn("invalid mfa code: %w", err)
+		return status.Errorf(codes.Unauthenticated, "invalid mfa code")
+	case credentials.ErrMFANotEnabled:
+		logger.Warn("mfa not enabled: %w", err)
+		return status.Errorf(codes.FailedPrecondition, "mfa is not enabled")
+	case credentials.ErrMFAAlreadyEnabled:
+		logger.Warn("mfa already enabled: %w", err)
+		return status.Errorf(codes.FailedPrecondition, "mfa is already enabled")
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	logger.Error("error checking mfa: %w", err)
+	return status.Errorf(codes.Internal, "Internal server error")
+}
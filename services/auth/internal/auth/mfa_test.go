@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	credMock "github.com/ramyadmz/goauth/internal/credentials/mock"
+	"github.com/ramyadmz/goauth/internal/data"
+	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
+	"github.com/ramyadmz/goauth/internal/mail"
+	"github.com/ramyadmz/goauth/pkg/pb"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoginUser_MFARequired(t *testing.T) {
+	password := uuid.NewString()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	user := &data.User{
+		ID:             rand.Int63(),
+		Username:       uuid.NewString(),
+		HashedPassword: hashedPassword,
+	}
+	challenge := &credentials.MFAChallenge{
+		ID:     uuid.NewString(),
+		UserID: user.ID,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByUsername", mock.Anything, user.Username).Return(user, nil)
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("IsEnabled", mock.Anything, user.ID).Return(true, nil)
+	mockMFAManager.On("StartChallenge", mock.Anything, user.ID).Return(challenge, nil)
+
+	// No session may be started before the second factor is verified.
+	mockSessionManager := &credMock.SessionManager{}
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	rsp, err := authService.LoginUser(context.Background(), &pb.UserLoginRequest{
+		Username: user.Username,
+		Password: password,
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.MfaRequired, true)
+	assert.Equal(t, rsp.MfaChallenge, challenge.ID)
+	assert.Equal(t, rsp.SessionId, "")
+	mockSessionManager.AssertNotCalled(t, "Start", mock.Anything, mock.Anything)
+}
+
+func TestVerifyMFA_HappyPath(t *testing.T) {
+	sessionID := uuid.NewString()
+	challenge := &credentials.MFAChallenge{
+		ID:        uuid.NewString(),
+		UserID:    rand.Int63(),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("GetChallenge", mock.Anything, challenge.ID).Return(challenge, nil)
+	mockMFAManager.On("Verify", mock.Anything, challenge.UserID, "123456").Return(nil)
+	mockMFAManager.On("EndChallenge", mock.Anything, challenge.ID).Return(nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Start", mock.Anything, challenge.UserID).Return(credentials.Session{
+		SessionID: sessionID,
+		Subject:   challenge.UserID,
+	}, nil)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	rsp, err := authService.VerifyMFA(context.Background(), &pb.VerifyMFARequest{
+		MfaChallenge: challenge.ID,
+		Code:         "123456",
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.SessionId, sessionID)
+}
+
+func TestVerifyMFA_InvalidChallenge(t *testing.T) {
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("GetChallenge", mock.Anything, mock.Anything).Return((*credentials.MFAChallenge)(nil), credentials.ErrInvalidMFAChallenge)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, &credMock.SessionManager{}, mockMFAManager, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.VerifyMFA(context.Background(), &pb.VerifyMFARequest{
+		MfaChallenge: uuid.NewString(),
+		Code:         "123456",
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestVerifyMFA_InvalidCodeIsThrottled(t *testing.T) {
+	challenge := &credentials.MFAChallenge{
+		ID:        uuid.NewString(),
+		UserID:    rand.Int63(),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("GetChallenge", mock.Anything, challenge.ID).Return(challenge, nil)
+	mockMFAManager.On("Verify", mock.Anything, challenge.UserID, mock.Anything).Return(credentials.ErrInvalidMFACode)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, &credMock.SessionManager{}, mockMFAManager, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	verify := func() error {
+		_, err := authService.VerifyMFA(context.Background(), &pb.VerifyMFARequest{
+			MfaChallenge: challenge.ID,
+			Code:         "000000",
+		})
+		return err
+	}
+
+	for i := 0; i <= config.DefaultThrottleFreeAttempts; i++ {
+		assert.Equal(t, status.Code(verify()), codes.Unauthenticated)
+	}
+	assert.Equal(t, status.Code(verify()), codes.ResourceExhausted)
+	mockMFAManager.AssertNumberOfCalls(t, "Verify", config.DefaultThrottleFreeAttempts+1)
+}
+
+func TestVerifyMFA_ChallengeAlreadyCompleted(t *testing.T) {
+	challenge := &credentials.MFAChallenge{
+		ID:        uuid.NewString(),
+		UserID:    rand.Int63(),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("GetChallenge", mock.Anything, challenge.ID).Return(challenge, nil)
+	mockMFAManager.On("Verify", mock.Anything, challenge.UserID, mock.Anything).Return(nil)
+	mockMFAManager.On("EndChallenge", mock.Anything, challenge.ID).Return(credentials.ErrInvalidMFAChallenge)
+
+	mockSessionManager := &credMock.SessionManager{}
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.VerifyMFA(context.Background(), &pb.VerifyMFARequest{
+		MfaChallenge: challenge.ID,
+		Code:         "123456",
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	mockSessionManager.AssertNotCalled(t, "Start", mock.Anything, mock.Anything)
+}
+
+func TestEnrollMFA_HappyPath(t *testing.T) {
+	sessionID := uuid.NewString()
+	user := &data.User{
+		ID:       rand.Int63(),
+		Username: uuid.NewString(),
+	}
+	enrollment := &credentials.MFAEnrollment{
+		Secret:        "JBSWY3DPEHPK3PXP",
+		URI:           "otpauth://totp/GoAuth:" + user.Username + "?secret=JBSWY3DPEHPK3PXP",
+		RecoveryCodes: []string{"abcde-fghij", "klmno-pqrst"},
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   user.ID,
+	}, nil)
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("Enroll", mock.Anything, user.ID, user.Username).Return(enrollment, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	rsp, err := authService.EnrollMFA(context.Background(), &pb.EnrollMFARequest{SessionId: sessionID})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.Secret, enrollment.Secret)
+	assert.Equal(t, rsp.Uri, enrollment.URI)
+	assert.Equal(t, rsp.RecoveryCodes, enrollment.RecoveryCodes)
+}
+
+func TestEnrollMFA_AlreadyEnabled(t *testing.T) {
+	sessionID := uuid.NewString()
+	user := &data.User{
+		ID:       rand.Int63(),
+		Username: uuid.NewString(),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   user.ID,
+	}, nil)
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("Enroll", mock.Anything, user.ID, user.Username).Return((*credentials.MFAEnrollment)(nil), credentials.ErrMFAAlreadyEnabled)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.EnrollMFA(context.Background(), &pb.EnrollMFARequest{SessionId: sessionID})
+
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+}
+
+func TestConfirmMFA_HappyPath(t *testing.T) {
+	sessionID := uuid.NewString()
+	userID := rand.Int63()
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("Confirm", mock.Anything, userID, "123456").Return(nil)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ConfirmMFA(context.Background(), &pb.ConfirmMFARequest{
+		SessionId: sessionID,
+		Code:      "123456",
+	})
+
+	assert.Equal(t, err, nil)
+}
+
+func TestDisableMFA_InvalidCode(t *testing.T) {
+	sessionID := uuid.NewString()
+	userID := rand.Int63()
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("Disable", mock.Anything, userID, "000000").Return(credentials.ErrInvalidMFACode)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.DisableMFA(context.Background(), &pb.DisableMFARequest{
+		SessionId: sessionID,
+		Code:      "000000",
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestDisableMFA_Unauthenticated(t *testing.T) {
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return((*credentials.Session)(nil), credentials.ErrInvalidSession)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.DisableMFA(context.Background(), &pb.DisableMFARequest{
+		SessionId: uuid.NewString(),
+		Code:      "123456",
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestRegenerateRecoveryCodes_HappyPath(t *testing.T) {
+	sessionID := uuid.NewString()
+	userID := rand.Int63()
+	recoveryCodes := []string{"abcde-fghij", "klmno-pqrst"}
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("RegenerateRecoveryCodes", mock.Anything, userID, "123456").Return(recoveryCodes, nil)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	rsp, err := authService.RegenerateRecoveryCodes(context.Background(), &pb.RegenerateRecoveryCodesRequest{
+		SessionId: sessionID,
+		Code:      "123456",
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.RecoveryCodes, recoveryCodes)
+}
+
+func TestCountRecoveryCodes_NotEnabled(t *testing.T) {
+	sessionID := uuid.NewString()
+	userID := rand.Int63()
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	mockMFAManager := &credMock.MFAManager{}
+	mockMFAManager.On("CountRecoveryCodes", mock.Anything, userID).Return(0, credentials.ErrMFANotEnabled)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.CountRecoveryCodes(context.Background(), &pb.CountRecoveryCodesRequest{SessionId: sessionID})
+
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/data"
+)
+
+// OpenID Connect scopes (OIDC Core section 5.4).
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// hasScope reports whether scope contains the given scope token.
+func hasScope(scope, token string) bool {
+	for _, s := range parseScope(scope) {
+		if s == token {
+			return true
+		}
+	}
+	return false
+}
+
+// userIdentity returns the claims about user that the granted scope allows to be released.
+func userIdentity(user *data.User, scope string) credentials.Identity {
+	identity := credentials.Identity{
+		Subject: user.ID,
+	}
+	if hasScope(scope, ScopeProfile) {
+		identity.PreferredUsername = user.Username
+	}
+	if hasScope(scope, ScopeEmail) {
+		identity.Email = user.Email
+		identity.EmailVerified = &user.EmailVerified
+	}
+	return identity
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/throttle"
+	"github.com/ramyadmz/goauth/internal/data"
+	"github.com/ramyadmz/goauth/internal/mail"
+	"github.com/ramyadmz/goauth/pkg/pb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestPasswordReset mails a password reset token to the user with the given username or email
+// address, at most once per resend interval. It responds the same whether or not such a user exists
+// or an email was sent, so that it can't be used to find accounts.
+func (u *UserAuthService) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("username", req.Username).WithField("email", req.Email)
+	logger.Info("password reset request recieved")
+
+	var user *data.User
+	var err error
+	if len(req.Email) > 0 {
+		user, err = u.dal.GetUserByEmail(ctx, req.Email)
+	} else {
+		user, err = u.dal.GetUserByUsername(ctx, req.Username)
+	}
+	if err != nil {
+		if err == data.ErrUserNotFound {
+			logger.Warn("no user to reset the password of")
+			return &pb.RequestPasswordResetResponse{}, nil
+		}
+		logger.Error("error fetching user: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+	logger = logger.WithField("userID", user.ID)
+
+	latest, err := u.dal.GetLatestPasswordReset(ctx, user.ID)
+	if err != nil && err != data.ErrPasswordResetNotFound {
+		logger.Error("error fetching password reset: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+	if err == nil && time.Since(latest.CreatedAt) < u.resetConfig.GetResendInterval() {
+		logger.Warn("password reset requested too soon")
+		return &pb.RequestPasswordResetResponse{}, nil
+	}
+
+	// Failing to mail the token is only logged, since an error would tell that the account exists
+	if err := u.sendPasswordResetEmail(ctx, user); err != nil {
+		logger.Error("error sending password reset email: %w", err)
+		return &pb.RequestPasswordResetResponse{}, nil
+	}
+
+	logger.Info("password reset email sent successfully")
+	return &pb.RequestPasswordResetResponse{}, nil
+}
+
+// ResetPassword sets a new password with a reset token. Since the old password may be known to
+// someone else, every session of the user is ended and every refresh token revoked.
+func (u *UserAuthService) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
+	logger := logrus.WithContext(ctx)
+	logger.Info("password reset recieved")
+
+	// The token is only consumed by an acceptable password, so that the user can try another one
+	reset, err := u.dal.GetPasswordReset(ctx, hashToken(req.Token))
+	if err != nil {
+		return nil, resetError(logger, err)
+	}
+	logger = logger.WithField("userID", reset.UserID)
+
+	user, err := u.dal.GetUserByID(ctx, reset.UserID)
+	if err != nil {
+		logger.Error("error fetching user: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	if err := u.checkPassword(ctx, logger, "new_password", req.NewPassword, user); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := u.hasher.Hash(req.NewPassword)
+	if err != nil {
+		logger.Error("error hashing password: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	if _, err := u.dal.UsePasswordReset(ctx, hashToken(req.Token), []byte(hashedPassword)); err != nil {
+		return nil, resetError(logger, err)
+	}
+
+	if err := u.passwordPolicy.Record(ctx, reset.UserID, hashedPassword); err != nil {
+		logger.Error("error recording password history: %w", err)
+	}
+
+	if err := u.revokeUserCredentials(ctx, reset.UserID); err != nil {
+		logger.Error("error revoking credentials after password reset: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	logger.Info("password reset successfully")
+	return &pb.ResetPasswordResponse{}, nil
+}
+
+// ChangePassword replaces the password of the user of the session, given the current one. The other
+// sessions of the user are ended on request, keeping the caller's session.
+func (u *UserAuthService) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("session_id", req.SessionId)
+	logger.Info("password change request recieved")
+
+	userID, err := u.sessionUser(ctx, logger, req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.WithField("userID", userID)
+
+	user, err := u.dal.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == data.ErrUserNotFound {
+			logger.Warn("session user doesn't exist: %w", err)
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired session")
+		}
+		logger.Error("error fetching user: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	// Wrong current passwords count against the same limit as failed logins
+	throttleKeys := throttle.WithIP(ctx, throttle.UserKey(user.Username))
+	if err := u.throttler.Attempt(ctx, throttleKeys...); err != nil {
+		return nil, err
+	}
+
+	err = u.hasher.Compare(string(user.HashedPassword), req.CurrentPassword)
+	if err != nil {
+		if err == credentials.ErrInvalidPass {
+			logger.Warn("invalid current password: %w", err)
+			return nil, status.Errorf(codes.PermissionDenied, "invalid current password")
+		}
+		logger.Error("error comparing password: %w", err)
+		u.throttler.Release(ctx, throttleKeys...)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+	u.throttler.Succeed(ctx, throttleKeys...)
+
+	if req.NewPassword == req.CurrentPassword {
+		return nil, status.Errorf(codes.InvalidArgument, "new password must differ from the current one")
+	}
+
+	if err := u.checkPassword(ctx, logger, "new_password", req.NewPassword, user); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := u.hasher.Hash(req.NewPassword)
+	if err != nil {
+		logger.Error("error hashing password: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	if err := u.dal.UpdateUserPassword(ctx, user.ID, []byte(hashedPassword)); err != nil {
+		logger.Error("error updating password: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	if err := u.passwordPolicy.Record(ctx, user.ID, hashedPassword); err != nil {
+		logger.Error("error recording password history: %w", err)
+	}
+
+	if req.EndOtherSessions {
+		if err := u.sessionManager.EndOthers(ctx, req.SessionId); err != nil {
+			if err == credentials.ErrInvalidSession {
+				// The caller's own session ended in the meantime, so there's nothing to keep
+				err = u.sessionManager.EndAll(ctx, user.ID)
+			}
+			if err != nil {
+				logger.Error("error ending other sessions: %w", err)
+				return nil, status.Errorf(codes.Internal, "Internal server error")
+			}
+		}
+	}
+
+	logger.Info("password changed successfully")
+	return &pb.ChangePasswordResponse{}, nil
+}
+
+// CleanupPasswordResets periodically removes expired reset tokens until ctx is canceled.
+func (u *UserAuthService) CleanupPasswordResets(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := u.dal.DeleteExpiredPasswordResets(ctx); err != nil {
+				logrus.WithContext(ctx).Error("error cleaning up password resets: %w", err)
+			}
+		}
+	}
+}
+
+// checkPassword checks a new password of user against the password policy. Violations are returned
+// as an InvalidArgument error with a field violation of field per broken rule.
+func (u *UserAuthService) checkPassword(ctx context.Context, logger *logrus.Entry, field, password string, user *data.User) error {
+	violations, err := u.passwordPolicy.Check(ctx, password, user)
+	if err != nil {
+		logger.Error("error checking password policy: %w", err)
+		return status.Errorf(codes.Internal, "Internal server error")
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	rules := make([]string, 0, len(violations))
+	details := &errdetails.BadRequest{}
+	for _, violation := range violations {
+		rules = append(rules, violation.Rule)
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: violation.Message,
+		})
+	}
+	logger.WithField("rules", rules).Warn("password rejected by password policy")
+
+	st := status.New(codes.InvalidArgument, "password doesn't meet the password policy: "+violations[0].Message)
+	if detailed, err := st.WithDetails(details); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// resetError converts an error of a password reset token to a gRPC error.
+func resetError(logger *logrus.Entry, err error) error {
+	if err == data.ErrPasswordResetNotFound {
+		logger.Warn("invalid or expired reset token: %w", err)
+		return status.Errorf(codes.InvalidArgument, "invalid or expired reset token")
+	}
+	logger.Error("error resetting password: %w", err)
+	return status.Errorf(codes.Internal, "Internal server error")
+}
+
+// revokeUserCredentials ends every session of the user and revokes every refresh token issued on the
+// user's behalf.
+func (u *UserAuthService) revokeUserCredentials(ctx context.Context, userID int64) error {
+	if err := u.sessionManager.EndAll(ctx, userID); err != nil {
+		return err
+	}
+	return u.dal.RevokeTokenFamiliesByUserID(ctx, userID)
+}
+
+// sendPasswordResetEmail issues a new reset token for the user and mails it. Only the hash of the
+// token is stored.
+func (u *UserAuthService) sendPasswordResetEmail(ctx context.Context, user *data.User) error {
+	token, err := generateSecret(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.dal.CreatePasswordReset(ctx, data.CreatePasswordResetParams{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(u.resetConfig.GetExpiration()),
+	})
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nYou can reset your password with the following token:\n\n%s\n\n", user.Username, token)
+	if link := u.resetConfig.GetURL(); len(link) > 0 {
+		fmt.Fprintf(&body, "or by opening this link:\n\n%s\n\n", withQuery(link, "token", token))
+	}
+	fmt.Fprintf(&body, "The token expires in %s. If you didn't ask to reset your password, you can ignore this email.\n", u.resetConfig.GetExpiration())
+
+	return u.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    body.String(),
+	})
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	credMock "github.com/ramyadmz/goauth/internal/credentials/mock"
+	"github.com/ramyadmz/goauth/internal/data"
+	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
+	"github.com/ramyadmz/goauth/internal/mail"
+	"github.com/ramyadmz/goauth/pkg/pb"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequestPasswordReset_HappyPath(t *testing.T) {
+	t.Setenv("OAUTH_PASSWORD_RESET_URL", "https://app.test/reset")
+
+	user := &data.User{
+		ID:       rand.Int63(),
+		Username: uuid.NewString(),
+		Email:    "user@test.com",
+	}
+
+	var params data.CreatePasswordResetParams
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	mockDAL.On("GetLatestPasswordReset", mock.Anything, user.ID).Return((*data.PasswordReset)(nil), data.ErrPasswordResetNotFound)
+	mockDAL.On("CreatePasswordReset", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		params = args.Get(1).(data.CreatePasswordResetParams)
+	}).Return(&data.PasswordReset{}, nil)
+
+	mailer := mail.NewMemoryMailer()
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mailer, newEmailConfig(), newResetConfig())
+	_, err := authService.RequestPasswordReset(context.Background(), &pb.RequestPasswordResetRequest{Email: user.Email})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mailer.Messages()), 1)
+	assert.Equal(t, mailer.Messages()[0].To, user.Email)
+	assert.Equal(t, params.UserID, user.ID)
+	assert.Equal(t, strings.Contains(mailer.Messages()[0].Body, "https://app.test/reset?token="), true)
+
+	// Only the hash of the mailed token is stored
+	assert.Equal(t, strings.Contains(mailer.Messages()[0].Body, params.TokenHash), false)
+}
+
+func TestRequestPasswordReset_UnknownUser(t *testing.T) {
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByUsername", mock.Anything, mock.Anything).Return((*data.User)(nil), data.ErrUserNotFound)
+
+	mailer := mail.NewMemoryMailer()
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mailer, newEmailConfig(), newResetConfig())
+	_, err := authService.RequestPasswordReset(context.Background(), &pb.RequestPasswordResetRequest{Username: "nobody"})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mailer.Messages()), 0)
+	mockDAL.AssertNotCalled(t, "CreatePasswordReset", mock.Anything, mock.Anything)
+}
+
+func TestRequestPasswordReset_TooSoon(t *testing.T) {
+	user := &data.User{
+		ID:       rand.Int63(),
+		Username: uuid.NewString(),
+		Email:    "user@test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByUsername", mock.Anything, user.Username).Return(user, nil)
+	mockDAL.On("GetLatestPasswordReset", mock.Anything, user.ID).Return(&data.PasswordReset{
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+	}, nil)
+
+	mailer := mail.NewMemoryMailer()
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mailer, newEmailConfig(), newResetConfig())
+	_, err := authService.RequestPasswordReset(context.Background(), &pb.RequestPasswordResetRequest{Username: user.Username})
+
+	// The response doesn't reveal that a reset was requested recently
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mailer.Messages()), 0)
+	mockDAL.AssertNotCalled(t, "CreatePasswordReset", mock.Anything, mock.Anything)
+}
+
+func TestRequestPasswordReset_MailerFailure(t *testing.T) {
+	user := &data.User{
+		ID:       rand.Int63(),
+		Username: uuid.NewString(),
+		Email:    "user@test.com",
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByUsername", mock.Anything, user.Username).Return(user, nil)
+	mockDAL.On("GetLatestPasswordReset", mock.Anything, user.ID).Return((*data.PasswordReset)(nil), data.ErrPasswordResetNotFound)
+	mockDAL.On("CreatePasswordReset", mock.Anything, mock.Anything).Return(&data.PasswordReset{}, nil)
+
+	authService := NewUserAuthService(mockDAL, &credMock.SessionManager{}, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), failingMailer{}, newEmailConfig(), newResetConfig())
+	rsp, err := authService.RequestPasswordReset(context.Background(), &pb.RequestPasswordResetRequest{Username: user.Username})
+
+	// The response is the same as for an unknown user
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, rsp, nil)
+}
+
+func TestResetPassword_HappyPath(t *testing.T) {
+	token := uuid.NewString()
+	password := uuid.NewString()
+	userID := rand.Int63()
+
+	reset := &data.PasswordReset{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+	}
+
+	var hashedPassword []byte
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetPasswordReset", mock.Anything, hashToken(token)).Return(reset, nil)
+	mockDAL.On("GetUserByID", mock.Anything, userID).Return(&data.User{ID: userID, Username: "alice"}, nil)
+	mockDAL.On("GetPasswordHistory", mock.Anything, userID, config.DefaultPasswordHistory).Return([]*data.PasswordHistory{}, nil)
+	mockDAL.On("UsePasswordReset", mock.Anything, hashToken(token), mock.Anything).Run(func(args mock.Arguments) {
+		hashedPassword = args.Get(2).([]byte)
+	}).Return(reset, nil)
+	mockDAL.On("AddPasswordHistory", mock.Anything, userID, mock.Anything, config.DefaultPasswordHistory).Return(nil)
+	mockDAL.On("RevokeTokenFamiliesByUserID", mock.Anything, userID).Return(nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("EndAll", mock.Anything, userID).Return(nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
+		Token:       token,
+		NewPassword: password,
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, bcrypt.CompareHashAndPassword(hashedPassword, []byte(password)), nil)
+	mockSessionManager.AssertExpectations(t)
+	mockDAL.AssertExpectations(t)
+}
+
+func TestResetPassword_InvalidToken(t *testing.T) {
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetPasswordReset", mock.Anything, mock.Anything).Return((*data.PasswordReset)(nil), data.ErrPasswordResetNotFound)
+
+	mockSessionManager := &credMock.SessionManager{}
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
+		Token:       uuid.NewString(),
+		NewPassword: uuid.NewString(),
+	})
+
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	mockSessionManager.AssertNotCalled(t, "EndAll", mock.Anything, mock.Anything)
+}
+
+func TestChangePassword_HappyPath(t *testing.T) {
+	sessionID := uuid.NewString()
+	password := uuid.NewString()
+	newPassword := uuid.NewString()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	user := &data.User{
+		ID:             rand.Int63(),
+		Username:       uuid.NewString(),
+		HashedPassword: hashedPassword,
+	}
+
+	var updatedPassword []byte
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+	mockDAL.On("GetPasswordHistory", mock.Anything, user.ID, config.DefaultPasswordHistory).Return([]*data.PasswordHistory{}, nil)
+	mockDAL.On("UpdateUserPassword", mock.Anything, user.ID, mock.Anything).Run(func(args mock.Arguments) {
+		updatedPassword = args.Get(2).([]byte)
+	}).Return(nil)
+	mockDAL.On("AddPasswordHistory", mock.Anything, user.ID, mock.Anything, config.DefaultPasswordHistory).Return(nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   user.ID,
+	}, nil)
+	mockSessionManager.On("EndOthers", mock.Anything, sessionID).Return(nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ChangePassword(context.Background(), &pb.ChangePasswordRequest{
+		SessionId:        sessionID,
+		CurrentPassword:  password,
+		NewPassword:      newPassword,
+		EndOtherSessions: true,
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, bcrypt.CompareHashAndPassword(updatedPassword, []byte(newPassword)), nil)
+	mockSessionManager.AssertExpectations(t)
+	mockSessionManager.AssertNotCalled(t, "EndAll", mock.Anything, mock.Anything)
+}
+
+func TestChangePassword_WrongCurrentPassword(t *testing.T) {
+	sessionID := uuid.NewString()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.MinCost)
+	user := &data.User{
+		ID:             rand.Int63(),
+		Username:       uuid.NewString(),
+		HashedPassword: hashedPassword,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   user.ID,
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ChangePassword(context.Background(), &pb.ChangePasswordRequest{
+		SessionId:        sessionID,
+		CurrentPassword:  uuid.NewString(),
+		NewPassword:      uuid.NewString(),
+		EndOtherSessions: true,
+	})
+
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	mockDAL.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything, mock.Anything)
+	mockSessionManager.AssertNotCalled(t, "EndOthers", mock.Anything, mock.Anything)
+}
+
+func TestChangePassword_InvalidSession(t *testing.T) {
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return((*credentials.Session)(nil), credentials.ErrInvalidSession)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ChangePassword(context.Background(), &pb.ChangePasswordRequest{
+		SessionId:       uuid.NewString(),
+		CurrentPassword: uuid.NewString(),
+		NewPassword:     uuid.NewString(),
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestChangePassword_PolicyViolation(t *testing.T) {
+	sessionID := uuid.NewString()
+	password := uuid.NewString()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	user := &data.User{
+		ID:             rand.Int63(),
+		Username:       "alice",
+		HashedPassword: hashedPassword,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+	mockDAL.On("GetPasswordHistory", mock.Anything, user.ID, config.DefaultPasswordHistory).Return([]*data.PasswordHistory{}, nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   user.ID,
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ChangePassword(context.Background(), &pb.ChangePasswordRequest{
+		SessionId:       sessionID,
+		CurrentPassword: password,
+		NewPassword:     "alice",
+	})
+
+	// Every broken rule is reported
+	st := status.Convert(err)
+	assert.Equal(t, st.Code(), codes.InvalidArgument)
+	assert.Equal(t, len(st.Details()), 1)
+	details := st.Details()[0].(*errdetails.BadRequest)
+	assert.Equal(t, len(details.FieldViolations), 2)
+	assert.Equal(t, details.FieldViolations[0].Field, "new_password")
+	mockDAL.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChangePassword_SameRulesAsRegistration(t *testing.T) {
+	sessionID := uuid.NewString()
+	password := uuid.NewString()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	user := &data.User{
+		ID:             rand.Int63(),
+		Username:       "alice",
+		Email:          "alice@test.com",
+		HashedPassword: hashedPassword,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+	mockDAL.On("GetPasswordHistory", mock.Anything, mock.Anything, config.DefaultPasswordHistory).Return([]*data.PasswordHistory{}, nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   user.ID,
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &credMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+
+	// A password too short to register with can't be set by a change either
+	_, registerErr := authService.RegisterUser(context.Background(), &pb.RegisterUserRequest{
+		Username: user.Username,
+		Email:    user.Email,
+		Password: "short",
+	})
+	_, changeErr := authService.ChangePassword(context.Background(), &pb.ChangePasswordRequest{
+		SessionId:       sessionID,
+		CurrentPassword: password,
+		NewPassword:     "short",
+	})
+
+	assert.Equal(t, status.Code(registerErr), codes.InvalidArgument)
+	assert.Equal(t, status.Code(changeErr), codes.InvalidArgument)
+	assert.Equal(t, status.Convert(changeErr).Message(), status.Convert(registerErr).Message())
+	mockDAL.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+	mockDAL.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything, mock.Anything)
+}
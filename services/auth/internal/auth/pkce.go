@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// Code challenge methods defined by RFC 7636.
+const (
+	CodeChallengeMethodPlain = "plain"
+	CodeChallengeMethodS256  = "S256"
+)
+
+// verifyCodeChallenge reports whether verifier matches the code challenge that
+// was stored with an authorization, using the given challenge method.
+func verifyCodeChallenge(verifier, challenge, method string) bool {
+	var computed string
+	switch method {
+	case CodeChallengeMethodS256:
+		sum := sha256.Sum256([]byte(verifier))
+		computed = base64.RawURLEncoding.EncodeToString(sum[:])
+	case CodeChallengeMethodPlain, "":
+		computed = verifier
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/credentials/bearer"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Match lists values a caller must hold. Every value of AllOf is required and, when AnyOf is not
+// empty, at least one of its values.
+type Match struct {
+	AllOf []string `json:"all_of,omitempty"`
+	AnyOf []string `json:"any_of,omitempty"`
+}
+
+// Requirement is what a caller needs to be allowed to call a method: both the scopes granted to its
+// access token and the roles of its user must match.
+type Requirement struct {
+	Scopes Match `json:"scopes"`
+	Roles  Match `json:"roles"`
+}
+
+// Policy maps full gRPC method names to the requirements for calling them. Methods without a
+// requirement are not restricted beyond authentication.
+type Policy struct {
+	requirements map[string]Requirement
+}
+
+// NewPolicy creates an empty Policy.
+func NewPolicy() *Policy {
+	return &Policy{
+		requirements: make(map[string]Requirement),
+	}
+}
+
+// LoadPolicy reads a policy from a JSON file mapping method names to requirements, such as
+// {"/proto.OAuthService/RegisterClient": {"roles": {"any_of": ["admin"]}}}.
+func LoadPolicy(path string) (*Policy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	defer file.Close()
+
+	// Unknown fields are rejected so that a misspelled requirement can't silently allow a call.
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	requirements := make(map[string]Requirement)
+	if err := decoder.Decode(&requirements); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+
+	policy := NewPolicy()
+	for method, requirement := range requirements {
+		policy.Require(method, requirement)
+	}
+	return policy, nil
+}
+
+// Require sets the requirement for calling method, replacing any previous one.
+func (p *Policy) Require(method string, requirement Requirement) *Policy {
+	p.requirements[method] = requirement
+	return p
+}
+
+// UnaryInterceptor returns a unary server interceptor enforcing the policy. It relies on the claims
+// put in the context by the bearer authenticator, so it must be chained after it.
+func (p *Policy) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := p.authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor returns a stream server interceptor enforcing the policy.
+func (p *Policy) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := p.authorize(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// authorize checks the claims of the call against the requirement of method.
+func (p *Policy) authorize(ctx context.Context, method string) error {
+	requirement, ok := p.requirements[method]
+	if !ok {
+		return nil
+	}
+
+	logger := logrus.WithContext(ctx).WithField("method", method)
+
+	claims, ok := bearer.ClaimsFromContext(ctx)
+	if !ok {
+		logger.Warn("unauthenticated call to restricted method")
+		return status.Errorf(codes.Unauthenticated, "missing bearer token")
+	}
+
+	if missing := requirement.Scopes.missing(parseScope(claims.Scope)); len(missing) > 0 {
+		logger.WithField("scope", claims.Scope).Warn("call rejected for insufficient scope")
+		return status.Errorf(codes.PermissionDenied, "insufficient scope: requires %s", missing)
+	}
+
+	if missing := requirement.Roles.missing(claims.Roles); len(missing) > 0 {
+		logger.WithField("roles", claims.Roles).Warn("call rejected for insufficient roles")
+		return status.Errorf(codes.PermissionDenied, "insufficient role: requires %s", missing)
+	}
+
+	return nil
+}
+
+// missing describes what held lacks to match m, or returns an empty string when it matches.
+func (m Match) missing(held []string) string {
+	set := make(map[string]bool, len(held))
+	for _, value := range held {
+		set[value] = true
+	}
+
+	var absent []string
+	for _, value := range m.AllOf {
+		if !set[value] {
+			absent = append(absent, value)
+		}
+	}
+	if len(absent) > 0 {
+		return "all of " + strings.Join(absent, ", ")
+	}
+
+	if len(m.AnyOf) == 0 {
+		return ""
+	}
+	for _, value := range m.AnyOf {
+		if set[value] {
+			return ""
+		}
+	}
+	return "any of " + strings.Join(m.AnyOf, ", ")
+}
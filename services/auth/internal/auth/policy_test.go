@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/bearer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const restrictedMethod = "/proto.OAuthService/Restricted"
+
+func callWithPolicy(policy *Policy, ctx context.Context) error {
+	_, err := policy.UnaryInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: restrictedMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	return err
+}
+
+func withClaims(scope string, roles ...string) context.Context {
+	return bearer.NewContext(context.Background(), &credentials.Claims{Scope: scope, Roles: roles})
+}
+
+func TestPolicy_AllOfScopes(t *testing.T) {
+	policy := NewPolicy().Require(restrictedMethod, Requirement{
+		Scopes: Match{AllOf: []string{"read", "write"}},
+	})
+
+	assert.Equal(t, callWithPolicy(policy, withClaims("write read")), nil)
+
+	err := callWithPolicy(policy, withClaims("read"))
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	assert.Equal(t, status.Convert(err).Message(), "insufficient scope: requires all of write")
+}
+
+func TestPolicy_AnyOfRoles(t *testing.T) {
+	policy := NewPolicy().Require(restrictedMethod, Requirement{
+		Roles: Match{AnyOf: []string{"admin", "operator"}},
+	})
+
+	assert.Equal(t, callWithPolicy(policy, withClaims("", "operator")), nil)
+
+	err := callWithPolicy(policy, withClaims("", "viewer"))
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	assert.Equal(t, status.Convert(err).Message(), "insufficient role: requires any of admin, operator")
+}
+
+func TestPolicy_ScopesAndRoles(t *testing.T) {
+	policy := NewPolicy().Require(restrictedMethod, Requirement{
+		Scopes: Match{AllOf: []string{"clients"}},
+		Roles:  Match{AllOf: []string{"admin"}},
+	})
+
+	assert.Equal(t, callWithPolicy(policy, withClaims("clients", "admin")), nil)
+	assert.Equal(t, status.Code(callWithPolicy(policy, withClaims("clients"))), codes.PermissionDenied)
+	assert.Equal(t, status.Code(callWithPolicy(policy, withClaims("", "admin"))), codes.PermissionDenied)
+}
+
+func TestPolicy_Unauthenticated(t *testing.T) {
+	policy := NewPolicy().Require(restrictedMethod, Requirement{
+		Scopes: Match{AnyOf: []string{"read"}},
+	})
+
+	err := callWithPolicy(policy, context.Background())
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestPolicy_UnrestrictedMethod(t *testing.T) {
+	assert.Equal(t, callWithPolicy(NewPolicy(), context.Background()), nil)
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	err := os.WriteFile(path, []byte(`{"`+restrictedMethod+`": {"scopes": {"any_of": ["read", "write"]}, "roles": {"all_of": ["admin"]}}}`), 0o600)
+	assert.Equal(t, err, nil)
+
+	policy, err := LoadPolicy(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, callWithPolicy(policy, withClaims("write", "admin")), nil)
+	assert.Equal(t, status.Code(callWithPolicy(policy, withClaims("write"))), codes.PermissionDenied)
+}
+
+func TestLoadPolicy_UnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	err := os.WriteFile(path, []byte(`{"`+restrictedMethod+`": {"scopes": {"one_of": ["read"]}}}`), 0o600)
+	assert.Equal(t, err, nil)
+
+	_, err = LoadPolicy(path)
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"net"
+	"net/url"
+)
+
+// matchRedirectURI reports whether requested is one of the client's registered redirect URIs.
+// URIs are compared exactly, except that loopback redirects of native apps may use any port
+// as described in RFC 8252 section 7.3.
+func matchRedirectURI(registered []string, requested string) bool {
+	for _, uri := range registered {
+		if uri == requested {
+			return true
+		}
+	}
+
+	reqURL, err := url.Parse(requested)
+	if err != nil || !isLoopbackRedirect(reqURL) {
+		return false
+	}
+
+	for _, uri := range registered {
+		regURL, err := url.Parse(uri)
+		if err != nil || !isLoopbackRedirect(regURL) {
+			continue
+		}
+
+		if regURL.Hostname() == reqURL.Hostname() &&
+			regURL.EscapedPath() == reqURL.EscapedPath() &&
+			regURL.RawQuery == reqURL.RawQuery &&
+			regURL.User.String() == reqURL.User.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isLoopbackRedirect reports whether u is an http redirect to a loopback IP literal.
+func isLoopbackRedirect(u *url.URL) bool {
+	if u.Scheme != "http" || len(u.Fragment) > 0 {
+		return false
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchRedirectURI(t *testing.T) {
+	registered := []string{
+		"https://client.test/callback",
+		"http://127.0.0.1/native",
+		"http://[::1]:8080/native",
+	}
+
+	tests := []struct {
+		name      string
+		requested string
+		want      bool
+	}{
+		{"exact match", "https://client.test/callback", true},
+		{"different path", "https://client.test/callback/other", false},
+		{"extra query", "https://client.test/callback?next=/", false},
+		{"different port on https", "https://client.test:8443/callback", false},
+		{"ipv4 loopback any port", "http://127.0.0.1:51004/native", true},
+		{"ipv6 loopback any port", "http://[::1]:6000/native", true},
+		{"loopback different path", "http://127.0.0.1:51004/other", false},
+		{"loopback different host", "http://127.0.0.2:51004/native", false},
+		{"localhost name is not relaxed", "http://localhost:51004/native", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchRedirectURI(registered, tt.requested))
+		})
+	}
+}
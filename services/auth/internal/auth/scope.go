@@ -0,0 +1,50 @@
+package auth
+
+import "strings"
+
+// parseScope splits a space-delimited scope string (RFC 6749 section 3.3) into its scope tokens.
+func parseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// normalizeScope returns scope with duplicate tokens removed and tokens separated by a single space,
+// keeping the order in which they first appear.
+func normalizeScope(scope string) string {
+	seen := make(map[string]struct{})
+	var tokens []string
+	for _, s := range parseScope(scope) {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		tokens = append(tokens, s)
+	}
+	return strings.Join(tokens, " ")
+}
+
+// isValidScope reports whether every scope token only uses the characters allowed by RFC 6749 section 3.3.
+func isValidScope(scope string) bool {
+	for _, s := range parseScope(scope) {
+		for _, c := range s {
+			if c < 0x21 || c == 0x22 || c == 0x5c || c > 0x7e {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isScopeSubset reports whether every scope token in requested is also present in allowed.
+func isScopeSubset(requested, allowed string) bool {
+	allowedSet := make(map[string]struct{})
+	for _, s := range parseScope(allowed) {
+		allowedSet[s] = struct{}{}
+	}
+
+	for _, s := range parseScope(requested) {
+		if _, ok := allowedSet[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"single", "read", "read"},
+		{"extra whitespace", "  read \t write  ", "read write"},
+		{"duplicates keep first position", "write read write", "write read"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeScope(tt.scope))
+		})
+	}
+}
+
+func TestIsValidScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope string
+		want  bool
+	}{
+		{"empty", "", true},
+		{"simple tokens", "read write", true},
+		{"uri like token", "https://api.test/orders:read", true},
+		{"double quote", `read "write"`, false},
+		{"backslash", `read\write`, false},
+		{"non ascii", "lecture écriture", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isValidScope(tt.scope))
+		})
+	}
+}
+
+func TestIsScopeSubset(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		allowed   string
+		want      bool
+	}{
+		{"empty request", "", "read", true},
+		{"equal", "read write", "write read", true},
+		{"narrower", "read", "read write", true},
+		{"wider", "read admin", "read write", false},
+		{"nothing allowed", "read", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isScopeSubset(tt.requested, tt.allowed))
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/ramyadmz/goauth/pkg/pb"
+)
+
+// PublicMethods are the RPCs that can be called without a bearer token. They authenticate the caller
+// themselves, with a session, client credentials or a token in the request, or need no authentication.
+var PublicMethods = []string{
+	"/proto.OAuthService/RegisterUser",
+	"/proto.OAuthService/UserLogin",
+	"/proto.OAuthService/UserLogout",
+	"/proto.OAuthService/VerifyEmail",
+	"/proto.OAuthService/ResendVerificationEmail",
+	"/proto.OAuthService/RequestPasswordReset",
+	"/proto.OAuthService/ResetPassword",
+	"/proto.OAuthService/ChangePassword",
+	"/proto.OAuthService/Authorize",
+	"/proto.OAuthService/UserConsent",
+	"/proto.OAuthService/VerifyMFA",
+	"/proto.OAuthService/EnrollMFA",
+	"/proto.OAuthService/ConfirmMFA",
+	"/proto.OAuthService/DisableMFA",
+	"/proto.OAuthService/RegenerateRecoveryCodes",
+	"/proto.OAuthService/CountRecoveryCodes",
+	"/proto.OAuthService/RegisterClient",
+	"/proto.OAuthService/ExchangeToken",
+	"/proto.OAuthService/RefreshToken",
+	"/proto.OAuthService/ClientCredentialsToken",
+	"/proto.OAuthService/IntrospectToken",
+	"/proto.OAuthService/RevokeToken",
+	"/proto.OAuthService/UserInfo",
+	"/proto.OAuthService/GetJWKS",
+	"/proto.OAuthService/GetServerMetadata",
+}
+
+// OAuthServer implements the OAuthService gRPC service by dispatching each RPC to the service owning it.
+type OAuthServer struct {
+	pb.UnimplementedOAuthServiceServer
+	users    *UserAuthService
+	clients  *ClientAuthService
+	metadata *MetadataService
+}
+
+// NewOAuthServer creates a new instance of OAuthServer serving the given services.
+func NewOAuthServer(users *UserAuthService, clients *ClientAuthService, metadata *MetadataService) *OAuthServer {
+	return &OAuthServer{
+		users:    users,
+		clients:  clients,
+		metadata: metadata,
+	}
+}
+
+func (s *OAuthServer) RegisterUser(ctx context.Context, req *pb.RegisterUserRequest) (*pb.RegisterUserResponse, error) {
+	return s.users.RegisterUser(ctx, req)
+}
+
+func (s *OAuthServer) UserLogin(ctx context.Context, req *pb.UserLoginRequest) (*pb.UserLoginResponse, error) {
+	return s.users.LoginUser(ctx, req)
+}
+
+func (s *OAuthServer) UserLogout(ctx context.Context, req *pb.UserLogoutRequest) (*pb.UserLogoutResponse, error) {
+	return s.users.LogoutUser(ctx, req)
+}
+
+func (s *OAuthServer) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
+	return s.users.VerifyEmail(ctx, req)
+}
+
+func (s *OAuthServer) ResendVerificationEmail(ctx context.Context, req *pb.ResendVerificationEmailRequest) (*pb.ResendVerificationEmailResponse, error) {
+	return s.users.ResendVerificationEmail(ctx, req)
+}
+
+func (s *OAuthServer) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetResponse, error) {
+	return s.users.RequestPasswordReset(ctx, req)
+}
+
+func (s *OAuthServer) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
+	return s.users.ResetPassword(ctx, req)
+}
+
+func (s *OAuthServer) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	return s.users.ChangePassword(ctx, req)
+}
+
+func (s *OAuthServer) Authorize(ctx context.Context, req *pb.AuthorizeRequest) (*pb.AuthorizeResponse, error) {
+	return s.users.Authorize(ctx, req)
+}
+
+func (s *OAuthServer) UserConsent(ctx context.Context, req *pb.UserConsentRequest) (*pb.UserConsentResponse, error) {
+	return s.users.ConsentUser(ctx, req)
+}
+
+func (s *OAuthServer) VerifyMFA(ctx context.Context, req *pb.VerifyMFARequest) (*pb.VerifyMFAResponse, error) {
+	return s.users.VerifyMFA(ctx, req)
+}
+
+func (s *OAuthServer) EnrollMFA(ctx context.Context, req *pb.EnrollMFARequest) (*pb.EnrollMFAResponse, error) {
+	return s.users.EnrollMFA(ctx, req)
+}
+
+func (s *OAuthServer) ConfirmMFA(ctx context.Context, req *pb.ConfirmMFARequest) (*pb.ConfirmMFAResponse, error) {
+	return s.users.ConfirmMFA(ctx, req)
+}
+
+func (s *OAuthServer) DisableMFA(ctx context.Context, req *pb.DisableMFARequest) (*pb.DisableMFAResponse, error) {
+	return s.users.DisableMFA(ctx, req)
+}
+
+func (s *OAuthServer) RegenerateRecoveryCodes(ctx context.Context, req *pb.RegenerateRecoveryCodesRequest) (*pb.RegenerateRecoveryCodesResponse, error) {
+	return s.users.RegenerateRecoveryCodes(ctx, req)
+}
+
+func (s *OAuthServer) CountRecoveryCodes(ctx context.Context, req *pb.CountRecoveryCodesRequest) (*pb.CountRecoveryCodesResponse, error) {
+	return s.users.CountRecoveryCodes(ctx, req)
+}
+
+func (s *OAuthServer) RegisterClient(ctx context.Context, req *pb.RegisterClientRequest) (*pb.RegisterClientResponse, error) {
+	return s.clients.RegisterClient(ctx, req)
+}
+
+func (s *OAuthServer) ExchangeToken(ctx context.Context, req *pb.ExchangeTokenRequest) (*pb.ExchangeTokenResponse, error) {
+	return s.clients.ExchangeToken(ctx, req)
+}
+
+func (s *OAuthServer) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
+	return s.clients.RefreshToken(ctx, req)
+}
+
+func (s *OAuthServer) ClientCredentialsToken(ctx context.Context, req *pb.ClientCredentialsTokenRequest) (*pb.ClientCredentialsTokenResponse, error) {
+	return s.clients.ClientCredentialsToken(ctx, req)
+}
+
+func (s *OAuthServer) IntrospectToken(ctx context.Context, req *pb.IntrospectTokenRequest) (*pb.IntrospectTokenResponse, error) {
+	return s.clients.IntrospectToken(ctx, req)
+}
+
+func (s *OAuthServer) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenResponse, error) {
+	return s.clients.RevokeToken(ctx, req)
+}
+
+func (s *OAuthServer) UserInfo(ctx context.Context, req *pb.UserInfoRequest) (*pb.UserInfoResponse, error) {
+	return s.clients.UserInfo(ctx, req)
+}
+
+func (s *OAuthServer) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.GetJWKSResponse, error) {
+	return s.clients.GetJWKS(ctx, req)
+}
+
+func (s *OAuthServer) GetServerMetadata(ctx context.Context, req *pb.GetServerMetadataRequest) (*pb.GetServerMetadataResponse, error) {
+	return s.metadata.GetServerMetadata(ctx, req)
+}
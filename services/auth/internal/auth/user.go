@@ -2,35 +2,55 @@ package auth
 
 import (
 	"context"
-	"errors"
 	"time"
 
+	"github.com/ramyadmz/goauth/internal/config"
 	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/policy"
+	"github.com/ramyadmz/goauth/internal/credentials/throttle"
 	"github.com/ramyadmz/goauth/internal/data"
+	"github.com/ramyadmz/goauth/internal/mail"
 	"github.com/ramyadmz/goauth/pkg/pb"
 	"github.com/sirupsen/logrus"
 
-	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 const (
-	DefaultCost    = 10 // default cost which is passed into GenerateFromPassword hash function
 	SessionExpTime = 24 * time.Hour
+
+	AuthorizationRequestExpTime = 10 * time.Minute
 )
 
+// ErrorAccessDenied is the RFC 6749 error code returned when the user denies an authorization request.
+const ErrorAccessDenied = "access_denied"
+
 type UserAuthService struct {
 	pb.UnimplementedOAuthServiceServer
 	dal            data.DataProvider
 	sessionManager credentials.SessionManager
+	mfaManager     credentials.MFAManager
+	hasher         credentials.SecureHasher
+	passwordPolicy *policy.PasswordPolicy
+	throttler      *throttle.Throttler
+	mailer         mail.Mailer
+	emailConfig    *config.EmailVerificationConfig
+	resetConfig    *config.PasswordResetConfig
 }
 
 // NewUserAuthService creates a new instance of ClientAuthService with the provided dependencies.
-func NewUserAuthService(dal data.DataProvider, sessionManager credentials.SessionManager) *UserAuthService {
+func NewUserAuthService(dal data.DataProvider, sessionManager credentials.SessionManager, mfaManager credentials.MFAManager, hasher credentials.SecureHasher, passwordPolicy *policy.PasswordPolicy, throttler *throttle.Throttler, mailer mail.Mailer, emailConfig *config.EmailVerificationConfig, resetConfig *config.PasswordResetConfig) *UserAuthService {
 	return &UserAuthService{
 		dal:            dal,
 		sessionManager: sessionManager,
+		mfaManager:     mfaManager,
+		hasher:         hasher,
+		passwordPolicy: passwordPolicy,
+		throttler:      throttler,
+		mailer:         mailer,
+		emailConfig:    emailConfig,
+		resetConfig:    resetConfig,
 	}
 }
 
@@ -38,17 +58,21 @@ func (u *UserAuthService) RegisterUser(ctx context.Context, req *pb.RegisterUser
 	logger := logrus.WithContext(ctx).WithField("username", req.Username).WithField("email", req.Email)
 	logger.Info("register request recieved")
 
+	if err := u.checkPassword(ctx, logger, "password", req.Password, &data.User{Username: req.Username, Email: req.Email}); err != nil {
+		return nil, err
+	}
+
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), DefaultCost)
+	hashedPassword, err := u.hasher.Hash(req.Password)
 	if err != nil {
 		logger.Error("Error hashing password: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
 	// Create the user
-	_, err = u.dal.CreateUser(ctx, data.CreateUserParams{
+	user, err := u.dal.CreateUser(ctx, data.CreateUserParams{
 		Username:       req.Username,
-		HashedPassword: hashedPassword,
+		HashedPassword: []byte(hashedPassword),
 		Email:          req.Email,
 	})
 	if err != nil {
@@ -56,6 +80,15 @@ func (u *UserAuthService) RegisterUser(ctx context.Context, req *pb.RegisterUser
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
+	if err := u.passwordPolicy.Record(ctx, user.ID, hashedPassword); err != nil {
+		logger.Error("error recording password history: %w", err)
+	}
+
+	// The account exists either way; a failed email can be resent with ResendVerificationEmail
+	if err := u.sendVerificationEmail(ctx, user); err != nil {
+		logger.Error("error sending verification email: %w", err)
+	}
+
 	// Successful registration
 	logger.Info("user registered successfully")
 	return &pb.RegisterUserResponse{}, nil
@@ -65,6 +98,12 @@ func (u *UserAuthService) LoginUser(ctx context.Context, req *pb.UserLoginReques
 	logger := logrus.WithContext(ctx).WithField("username", req.Username)
 	logger.Info("login request recieved")
 
+	// Count the attempt before spending a password comparison on it, rejecting throttled ones
+	throttleKeys := throttle.WithIP(ctx, throttle.UserKey(req.Username))
+	if err := u.throttler.Attempt(ctx, throttleKeys...); err != nil {
+		return nil, err
+	}
+
 	// Fetch user by username
 	userData, err := u.dal.GetUserByUsername(ctx, req.Username)
 	if err != nil {
@@ -73,19 +112,53 @@ func (u *UserAuthService) LoginUser(ctx context.Context, req *pb.UserLoginReques
 			return nil, status.Errorf(codes.Unauthenticated, "invalid username or password")
 		}
 		logger.Error("error retrieving user by username: %w", err)
+		u.throttler.Release(ctx, throttleKeys...)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
 	// Check if the password is correct
-	err = bcrypt.CompareHashAndPassword(userData.HashedPassword, []byte(req.Password))
+	err = u.hasher.Compare(string(userData.HashedPassword), req.Password)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		if err == credentials.ErrInvalidPass {
 			logger.Error("invalid username or password: %w", err)
 			return nil, status.Errorf(codes.Unauthenticated, "invalid username or password")
 		}
 		logger.Error("error comparing password: %w", err)
+		u.throttler.Release(ctx, throttleKeys...)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+	u.throttler.Succeed(ctx, throttleKeys...)
+
+	// The password is only known now, so this is when a hash with outdated parameters can be upgraded
+	if u.hasher.NeedsRehash(string(userData.HashedPassword)) {
+		u.rehashPassword(ctx, logger, userData.ID, req.Password)
+	}
+
+	// Only checked once the password is known to be right, so that it doesn't reveal anything to others
+	if u.emailConfig.IsRequired() && !userData.EmailVerified {
+		logger.Warn("email address not verified")
+		return nil, status.Errorf(codes.FailedPrecondition, "email address is not verified")
+	}
+
+	// Users with MFA enabled only get a session once they present a code
+	mfaEnabled, err := u.mfaManager.IsEnabled(ctx, userData.ID)
+	if err != nil {
+		logger.Error("error checking mfa: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
+	if mfaEnabled {
+		challenge, err := u.mfaManager.StartChallenge(ctx, userData.ID)
+		if err != nil {
+			logger.Error("error starting mfa challenge: %w", err)
+			return nil, status.Errorf(codes.Internal, "Internal server error")
+		}
+
+		logger.Info("mfa required to complete login")
+		return &pb.UserLoginResponse{
+			MfaRequired:  true,
+			MfaChallenge: challenge.ID,
+		}, nil
+	}
 
 	// Generate a new session id
 	session, err := u.sessionManager.Start(ctx, userData.ID)
@@ -119,12 +192,11 @@ func (u *UserAuthService) LogoutUser(ctx context.Context, req *pb.UserLogoutRequ
 	return &pb.UserLogoutResponse{}, nil
 }
 
-func (u *UserAuthService) ConsentUser(ctx context.Context, req *pb.UserConsentRequest) (*pb.UserConsentResponse, error) {
+func (u *UserAuthService) Authorize(ctx context.Context, req *pb.AuthorizeRequest) (*pb.AuthorizeResponse, error) {
 	logger := logrus.WithContext(ctx).WithField("session_id", req.SessionId).WithField("client_id", req.ClientId)
-	logger.Info("consent request recieved")
+	logger.Info("authorize request recieved")
 
-	// Validate the token
-	claims, err := u.sessionManager.Get(ctx, req.SessionId)
+	session, err := u.sessionManager.Get(ctx, req.SessionId)
 	if err != nil {
 		if err == credentials.ErrInvalidSession {
 			logger.Error("invalid or expired session: %w", err)
@@ -134,7 +206,72 @@ func (u *UserAuthService) ConsentUser(ctx context.Context, req *pb.UserConsentRe
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	client, err := u.dal.GetClientByID(ctx, req.ClientId)
+	client, err := u.redirectClient(ctx, req.ClientId, req.RedirectUri)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := normalizeScope(req.Scope)
+	if len(scope) == 0 {
+		scope = client.Scope
+	}
+
+	if !isScopeSubset(scope, client.Scope) {
+		logger.Warn("requested scope exceeds client scope")
+		return nil, ErrInvalidScope
+	}
+
+	// Public clients can't authenticate at the token endpoint, so PKCE is the only
+	// thing binding the code to the party that started the flow.
+	if client.IsPublic && len(req.CodeChallenge) == 0 {
+		logger.Warn("public client authorize without code challenge")
+		return nil, status.Errorf(codes.InvalidArgument, "code challenge is required for public clients")
+	}
+
+	challengeMethod := req.CodeChallengeMethod
+	if len(req.CodeChallenge) > 0 && len(challengeMethod) == 0 {
+		challengeMethod = CodeChallengeMethodPlain
+	}
+
+	authRequest, err := u.dal.CreateAuthorizationRequest(ctx, data.CreateAuthorizationRequestParams{
+		SessionID:           session.SessionID,
+		UserID:              session.Subject.(int64),
+		ClientID:            client.ID,
+		RedirectURI:         req.RedirectUri,
+		Scope:               scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: challengeMethod,
+		Nonce:               req.Nonce,
+		AuthTime:            session.CreatedAt,
+		ExpiresAt:           time.Now().Add(AuthorizationRequestExpTime),
+	})
+	if err != nil {
+		logger.Error("error creating authorization request: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	logger.WithField("request_id", authRequest.ID).Info("authorization request created successfully")
+	return &pb.AuthorizeResponse{
+		RequestId:  authRequest.ID,
+		ClientName: client.Name,
+		Scope:      authRequest.Scope,
+	}, nil
+}
+
+// CheckRedirectURI reports whether the client exists and registered the redirect uri. Until both are
+// known to be valid, errors of an authorization request must not be redirected to the client
+// (RFC 6749 section 4.1.2.1).
+func (u *UserAuthService) CheckRedirectURI(ctx context.Context, clientID int64, redirectURI string) error {
+	_, err := u.redirectClient(ctx, clientID, redirectURI)
+	return err
+}
+
+// redirectClient fetches the client of an authorization request and checks it registered the redirect uri.
+func (u *UserAuthService) redirectClient(ctx context.Context, clientID int64, redirectURI string) (*data.Client, error) {
+	logger := logrus.WithContext(ctx).WithField("client_id", clientID)
+
+	client, err := u.dal.GetClientByID(ctx, clientID)
 	if err != nil {
 		if err == data.ErrClientNotFound {
 			logger.Warn("client doesnt exist")
@@ -144,12 +281,93 @@ func (u *UserAuthService) ConsentUser(ctx context.Context, req *pb.UserConsentRe
 		return nil, status.Errorf(codes.Internal, "Internal server error")
 	}
 
-	_, err = u.dal.CreateAuthorization(ctx, data.CreateAuthorizationParams{
-		UserID:   claims.Subject.(int64),
-		ClientID: client.ID,
-		Scope:    "", // todo add scope
-	})
+	if !matchRedirectURI(client.RedirectURIs, redirectURI) {
+		logger.WithField("redirect_uri", redirectURI).Warn("redirect uri is not registered for client")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid redirect uri")
+	}
 
+	return client, nil
+}
+
+func (u *UserAuthService) ConsentUser(ctx context.Context, req *pb.UserConsentRequest) (*pb.UserConsentResponse, error) {
+	logger := logrus.WithContext(ctx).WithField("session_id", req.SessionId).WithField("request_id", req.RequestId)
+	logger.Info("consent request recieved")
+
+	session, err := u.sessionManager.Get(ctx, req.SessionId)
+	if err != nil {
+		if err == credentials.ErrInvalidSession {
+			logger.Error("invalid or expired session: %w", err)
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired session")
+		}
+		logger.Error("error validating session: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	authRequest, err := u.dal.GetAuthorizationRequestByID(ctx, req.RequestId)
+	if err != nil {
+		if err == data.ErrAuthRequestNotFound {
+			logger.Warn("authorization request not found")
+			return nil, status.Errorf(codes.NotFound, "authorization request not found")
+		}
+		logger.Error("error fetching authorization request: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	// The request can only be answered from the session that started it.
+	if authRequest.SessionID != session.SessionID || authRequest.UserID != session.Subject.(int64) {
+		logger.Warn("authorization request belongs to another session")
+		return nil, status.Errorf(codes.PermissionDenied, "authorization request belongs to another session")
+	}
+
+	if authRequest.Status != data.AuthorizationRequestPending || time.Now().After(authRequest.ExpiresAt) {
+		logger.Warn("authorization request is no longer pending")
+		return nil, status.Errorf(codes.FailedPrecondition, "authorization request is expired or already resolved")
+	}
+
+	// The user may approve only part of the requested scope.
+	scope := authRequest.Scope
+	if req.Approved && len(parseScope(req.Scope)) > 0 {
+		if !isScopeSubset(req.Scope, authRequest.Scope) {
+			logger.WithField("scope", req.Scope).Warn("approved scope exceeds requested scope")
+			return nil, ErrInvalidScope
+		}
+		scope = normalizeScope(req.Scope)
+	}
+
+	decision := data.AuthorizationRequestDenied
+	if req.Approved {
+		decision = data.AuthorizationRequestApproved
+	}
+
+	authRequest, err = u.dal.ResolveAuthorizationRequest(ctx, authRequest.ID, decision)
+	if err != nil {
+		if err == data.ErrAuthRequestNotFound {
+			logger.Warn("authorization request was resolved concurrently")
+			return nil, status.Errorf(codes.FailedPrecondition, "authorization request is expired or already resolved")
+		}
+		logger.Error("error resolving authorization request: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	if !req.Approved {
+		logger.Info("user denied authorization request")
+		return &pb.UserConsentResponse{
+			State:       authRequest.State,
+			RedirectUri: authRequest.RedirectURI,
+			Error:       ErrorAccessDenied,
+		}, nil
+	}
+
+	authorization, err := u.dal.CreateAuthorization(ctx, data.CreateAuthorizationParams{
+		UserID:              authRequest.UserID,
+		ClientID:            authRequest.ClientID,
+		Scope:               scope,
+		CodeChallenge:       authRequest.CodeChallenge,
+		CodeChallengeMethod: authRequest.CodeChallengeMethod,
+		RedirectURI:         authRequest.RedirectURI,
+		Nonce:               authRequest.Nonce,
+		AuthTime:            authRequest.AuthTime,
+	})
 	if err != nil {
 		logger.Error("error creating authorization: %w", err)
 		return nil, status.Errorf(codes.Internal, "Internal server error")
@@ -157,5 +375,42 @@ func (u *UserAuthService) ConsentUser(ctx context.Context, req *pb.UserConsentRe
 
 	// Successful consent
 	logger.Info("user consent successfully")
-	return &pb.UserConsentResponse{}, nil
+	return &pb.UserConsentResponse{
+		AuthorizationCode: authorization.AuthCode,
+		State:             authRequest.State,
+		RedirectUri:       authRequest.RedirectURI,
+	}, nil
+}
+
+// CleanupAuthorizationRequests periodically removes expired authorization requests until ctx is done.
+func (u *UserAuthService) CleanupAuthorizationRequests(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := u.dal.DeleteExpiredAuthorizationRequests(ctx); err != nil {
+				logrus.WithContext(ctx).Error("error cleaning up authorization requests: %w", err)
+			}
+		}
+	}
+}
+
+// rehashPassword saves a new hash of the user's password, made with the current algorithm and
+// parameters. Failures are only logged, since the old hash keeps working.
+func (u *UserAuthService) rehashPassword(ctx context.Context, logger *logrus.Entry, userID int64, password string) {
+	hashedPassword, err := u.hasher.Hash(password)
+	if err != nil {
+		logger.Error("error rehashing password: %w", err)
+		return
+	}
+
+	if err := u.dal.UpdateUserPassword(ctx, userID, []byte(hashedPassword)); err != nil {
+		logger.Error("error saving rehashed password: %w", err)
+		return
+	}
+	logger.Info("password rehashed successfully")
 }
@@ -4,15 +4,21 @@ import (
 	"context"
 	"errors"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-playground/assert/v2"
 	"github.com/google/uuid"
+	"github.com/ramyadmz/goauth/internal/config"
 	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/hasher"
 	sessionMock "github.com/ramyadmz/goauth/internal/credentials/mock"
+	"github.com/ramyadmz/goauth/internal/credentials/policy"
+	"github.com/ramyadmz/goauth/internal/credentials/throttle"
 	"github.com/ramyadmz/goauth/internal/data"
 	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
+	"github.com/ramyadmz/goauth/internal/mail"
 	"github.com/ramyadmz/goauth/pkg/pb"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
@@ -20,9 +26,41 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// newThrottler returns a throttler with the default configuration and fresh in-memory counters.
+func newThrottler() *throttle.Throttler {
+	cnfg, _ := config.NewThrottleConfig()
+	return throttle.NewThrottler(cnfg, throttle.NewMemoryStore())
+}
+
+func newHasher() *hasher.BcryptHasher {
+	return hasher.NewBcryptHasher(bcrypt.MinCost)
+}
+
+func newPasswordPolicy(dal data.DataProvider) *policy.PasswordPolicy {
+	cnfg, _ := config.NewPasswordPolicyConfig()
+	return policy.NewPasswordPolicy(cnfg, dal, newHasher())
+}
+
+func newEmailConfig() *config.EmailVerificationConfig {
+	cnfg, _ := config.NewEmailVerificationConfig()
+	return cnfg
+}
+
+func newResetConfig() *config.PasswordResetConfig {
+	cnfg, _ := config.NewPasswordResetConfig()
+	return cnfg
+}
+
+// failingMailer is a mailer that can't send anything.
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg mail.Message) error {
+	return errors.New("mail server unavailable")
+}
+
 func TestRegisterUser_HappyPath(t *testing.T) {
 	password := "password"
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
 
 	user := &data.User{
 		ID:             100,
@@ -33,10 +71,16 @@ func TestRegisterUser_HappyPath(t *testing.T) {
 		UpdatedAt:      time.Now(),
 	}
 
+	var verification data.CreateEmailVerificationParams
 	mockDAL := &dalMock.DataProvider{}
 	mockDAL.On("CreateUser", mock.Anything, mock.Anything).Return(user, nil)
+	mockDAL.On("AddPasswordHistory", mock.Anything, user.ID, mock.Anything, config.DefaultPasswordHistory).Return(nil)
+	mockDAL.On("CreateEmailVerification", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		verification = args.Get(1).(data.CreateEmailVerificationParams)
+	}).Return(&data.EmailVerification{}, nil)
 
-	authService := NewUserAuthService(mockDAL, &sessionMock.SessionManager{})
+	mailer := mail.NewMemoryMailer()
+	authService := NewUserAuthService(mockDAL, &sessionMock.SessionManager{}, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mailer, newEmailConfig(), newResetConfig())
 
 	_, err := authService.RegisterUser(context.Background(), &pb.RegisterUserRequest{
 		Username: user.Username,
@@ -45,27 +89,105 @@ func TestRegisterUser_HappyPath(t *testing.T) {
 	})
 
 	assert.Equal(t, err, nil)
+	assert.Equal(t, verification.UserID, user.ID)
+	assert.Equal(t, verification.Email, user.Email)
+
+	// The mailed token is only stored hashed
+	messages := mailer.Messages()
+	assert.Equal(t, len(messages), 1)
+	assert.Equal(t, messages[0].To, user.Email)
+	assert.Equal(t, strings.Contains(messages[0].Body, verification.TokenHash), false)
 }
 
 func TestRegisterUser_InternalError(t *testing.T) {
 	mockDAL := &dalMock.DataProvider{}
 	mockDAL.On("CreateUser", mock.Anything, mock.Anything).Return(&data.User{}, errors.New("Error creating user in database"))
 
-	authService := NewUserAuthService(mockDAL, &sessionMock.SessionManager{})
+	authService := NewUserAuthService(mockDAL, &sessionMock.SessionManager{}, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
 
 	_, err := authService.RegisterUser(context.Background(), &pb.RegisterUserRequest{
 		Username: mock.Anything,
-		Password: mock.Anything,
+		Password: uuid.NewString(),
 		Email:    mock.Anything,
 	})
 
 	assert.Equal(t, status.Code(err), codes.Internal)
 }
 
+func TestLoginUser_Rehash(t *testing.T) {
+	password := uuid.NewString()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	user := &data.User{
+		ID:             rand.Int63(),
+		Username:       uuid.NewString(),
+		HashedPassword: hashedPassword,
+	}
+
+	var rehashedPassword []byte
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByUsername", mock.Anything, user.Username).Return(user, nil)
+	mockDAL.On("UpdateUserPassword", mock.Anything, user.ID, mock.Anything).Run(func(args mock.Arguments) {
+		rehashedPassword = args.Get(2).([]byte)
+	}).Return(nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Start", mock.Anything, user.ID).Return(credentials.Session{SessionID: uuid.NewString()}, nil)
+
+	mockMFAManager := &sessionMock.MFAManager{}
+	mockMFAManager.On("IsEnabled", mock.Anything, user.ID).Return(false, nil)
+
+	// The password was hashed with a lower cost than the service's
+	secureHasher := hasher.NewBcryptHasher(bcrypt.MinCost + 1)
+	authService := NewUserAuthService(mockDAL, mockSessionManager, mockMFAManager, secureHasher, newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+
+	_, err := authService.LoginUser(context.Background(), &pb.UserLoginRequest{
+		Username: user.Username,
+		Password: password,
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, secureHasher.NeedsRehash(string(rehashedPassword)), false)
+	assert.Equal(t, secureHasher.Compare(string(rehashedPassword), password), nil)
+}
+
+func TestLoginUser_ImportedHash(t *testing.T) {
+	t.Setenv("OAUTH_HASH_ALGORITHM", config.HashAlgorithmBcrypt)
+	t.Setenv("OAUTH_BCRYPT_COST", "4")
+	hasherConfig, _ := config.NewHasherConfig()
+
+	user := &data.User{
+		ID:             rand.Int63(),
+		Username:       uuid.NewString(),
+		HashedPassword: []byte("pbkdf2_sha256$1000$seasalt$mQnueSakb748zqBAC1tmWVZsZbi2zPGZarEzTGdfmso="),
+	}
+
+	var rehashedPassword []byte
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByUsername", mock.Anything, user.Username).Return(user, nil)
+	mockDAL.On("UpdateUserPassword", mock.Anything, user.ID, mock.Anything).Run(func(args mock.Arguments) {
+		rehashedPassword = args.Get(2).([]byte)
+	}).Return(nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Start", mock.Anything, user.ID).Return(credentials.Session{SessionID: uuid.NewString()}, nil)
+
+	mockMFAManager := &sessionMock.MFAManager{}
+	mockMFAManager.On("IsEnabled", mock.Anything, user.ID).Return(false, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, mockMFAManager, hasher.NewHasher(hasherConfig), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.LoginUser(context.Background(), &pb.UserLoginRequest{
+		Username: user.Username,
+		Password: "correct horse",
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, bcrypt.CompareHashAndPassword(rehashedPassword, []byte("correct horse")), nil)
+}
+
 func TestLoginUser_HappyPath(t *testing.T) {
 	sessionID := uuid.NewString()
 	password := uuid.NewString()
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
 	userID := rand.Int63()
 	expiresAt := time.Now().Add(1 * time.Hour)
 
@@ -88,7 +210,10 @@ func TestLoginUser_HappyPath(t *testing.T) {
 		ExpiresAt: expiresAt,
 	}, nil)
 
-	authService := NewUserAuthService(mockDAL, mockSessionManager)
+	mockMFAManager := &sessionMock.MFAManager{}
+	mockMFAManager.On("IsEnabled", mock.Anything, userID).Return(false, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, mockMFAManager, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
 
 	rsp, err := authService.LoginUser(context.Background(), &pb.UserLoginRequest{
 		Username: user.Username,
@@ -97,13 +222,14 @@ func TestLoginUser_HappyPath(t *testing.T) {
 
 	assert.Equal(t, err, nil)
 	assert.Equal(t, rsp.SessionId, sessionID)
+	assert.Equal(t, rsp.MfaRequired, false)
 }
 
 func TestLoginUser_Unauthenticated(t *testing.T) {
 	userID := rand.Int63()
 	fakePassword := uuid.NewString()
 	password := uuid.NewString()
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
 
 	user := &data.User{
 		ID:             userID,
@@ -117,7 +243,7 @@ func TestLoginUser_Unauthenticated(t *testing.T) {
 	mockDAL := &dalMock.DataProvider{}
 	mockDAL.On("GetUserByUsername", mock.Anything, mock.Anything).Return(user, nil)
 
-	authService := NewUserAuthService(mockDAL, &sessionMock.SessionManager{})
+	authService := NewUserAuthService(mockDAL, &sessionMock.SessionManager{}, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
 
 	_, err := authService.LoginUser(context.Background(), &pb.UserLoginRequest{
 		Username: user.Username,
@@ -127,11 +253,43 @@ func TestLoginUser_Unauthenticated(t *testing.T) {
 	assert.Equal(t, status.Code(err), codes.Unauthenticated)
 }
 
+func TestLoginUser_Throttled(t *testing.T) {
+	password := uuid.NewString()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+
+	user := &data.User{
+		ID:             rand.Int63(),
+		Username:       uuid.NewString(),
+		HashedPassword: hashedPassword,
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserByUsername", mock.Anything, user.Username).Return(user, nil)
+
+	authService := NewUserAuthService(mockDAL, &sessionMock.SessionManager{}, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+
+	for i := 0; i <= config.DefaultThrottleFreeAttempts; i++ {
+		_, err := authService.LoginUser(context.Background(), &pb.UserLoginRequest{
+			Username: user.Username,
+			Password: uuid.NewString(),
+		})
+		assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	}
+
+	// Even the right password is rejected without being checked until the delay has passed.
+	_, err := authService.LoginUser(context.Background(), &pb.UserLoginRequest{
+		Username: user.Username,
+		Password: password,
+	})
+	assert.Equal(t, status.Code(err), codes.ResourceExhausted)
+	mockDAL.AssertNumberOfCalls(t, "GetUserByUsername", config.DefaultThrottleFreeAttempts+1)
+}
+
 func TestLogoutUser_HappyPath(t *testing.T) {
 	mockSessionManager := &sessionMock.SessionManager{}
 	mockSessionManager.On("End", mock.Anything, mock.Anything).Return(nil)
 
-	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager)
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
 
 	_, err := authService.LogoutUser(context.Background(), &pb.UserLogoutRequest{
 		SessionId: mock.Anything,
@@ -144,7 +302,7 @@ func TestLogoutUser_Unauthenticated(t *testing.T) {
 	mockSessionManager := &sessionMock.SessionManager{}
 	mockSessionManager.On("End", mock.Anything, mock.Anything).Return(credentials.ErrInvalidSession)
 
-	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager)
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
 
 	_, err := authService.LogoutUser(context.Background(), &pb.UserLogoutRequest{
 		SessionId: mock.Anything,
@@ -153,52 +311,375 @@ func TestLogoutUser_Unauthenticated(t *testing.T) {
 	assert.Equal(t, status.Code(err), codes.Unauthenticated)
 }
 
-func TestConsentUser_HappyPath(t *testing.T) {
-	clientID := rand.Int63()
-	clientSecret := uuid.NewString()
+func TestAuthorize_HappyPath(t *testing.T) {
 	userID := rand.Int63()
 	sessionID := uuid.NewString()
-
-	hashedSec, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), 10)
+	authTime := time.Now().Add(-1 * time.Minute)
 	client := &data.Client{
-		ID:           clientID,
-		HashedSecret: hashedSec,
+		ID:           rand.Int63(),
 		Name:         uuid.NewString(),
 		Website:      uuid.NewString(),
-		Scope:        "read",
+		Scope:        "read write",
+		RedirectURIs: []string{"https://client.test/callback"},
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
+	authRequest := &data.AuthorizationRequest{
+		ID:        uuid.NewString(),
+		SessionID: sessionID,
+		UserID:    userID,
+		ClientID:  client.ID,
+		Scope:     "read",
+		Status:    data.AuthorizationRequestPending,
+	}
+
 	mockDAL := &dalMock.DataProvider{}
 	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
-	mockDAL.On("CreateAuthorization", mock.Anything, mock.Anything, mock.Anything).Return(&data.Authorization{}, nil)
+	mockDAL.On("CreateAuthorizationRequest", mock.Anything, mock.MatchedBy(func(params data.CreateAuthorizationRequestParams) bool {
+		return params.SessionID == sessionID && params.UserID == userID && params.State == "xyz" && params.Scope == "read" &&
+			params.Nonce == "n-0S6_WzA2Mj" && params.AuthTime.Equal(authTime)
+	})).Return(authRequest, nil)
 
 	mockSessionManager := &sessionMock.SessionManager{}
 	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
 		SessionID: sessionID,
 		Subject:   userID,
+		CreatedAt: authTime,
 	}, nil)
 
-	authService := NewUserAuthService(mockDAL, mockSessionManager)
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	rsp, err := authService.Authorize(context.Background(), &pb.AuthorizeRequest{
+		SessionId:   sessionID,
+		ClientId:    client.ID,
+		RedirectUri: client.RedirectURIs[0],
+		Scope:       "read",
+		State:       "xyz",
+		Nonce:       "n-0S6_WzA2Mj",
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.RequestId, authRequest.ID)
+	assert.Equal(t, rsp.ClientName, client.Name)
+	assert.Equal(t, rsp.Scope, "read")
+}
+
+func TestAuthorize_Unauthenticated(t *testing.T) {
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{}, credentials.ErrInvalidSession)
+
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.Authorize(context.Background(), &pb.AuthorizeRequest{
+		ClientId:  rand.Int63(),
+		SessionId: mock.Anything,
+	})
+
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestAuthorize_PublicClientWithoutCodeChallenge(t *testing.T) {
+	client := &data.Client{
+		ID:           rand.Int63(),
+		Name:         uuid.NewString(),
+		Website:      uuid.NewString(),
+		IsPublic:     true,
+		RedirectURIs: []string{"http://127.0.0.1/callback"},
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
+		SessionID: uuid.NewString(),
+		Subject:   rand.Int63(),
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.Authorize(context.Background(), &pb.AuthorizeRequest{
+		ClientId:    client.ID,
+		SessionId:   uuid.NewString(),
+		RedirectUri: client.RedirectURIs[0],
+	})
+
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestAuthorize_UnregisteredRedirectURI(t *testing.T) {
+	client := &data.Client{
+		ID:           rand.Int63(),
+		Name:         uuid.NewString(),
+		Website:      uuid.NewString(),
+		RedirectURIs: []string{"https://client.test/callback"},
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
+		SessionID: uuid.NewString(),
+		Subject:   rand.Int63(),
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.Authorize(context.Background(), &pb.AuthorizeRequest{
+		ClientId:    client.ID,
+		SessionId:   uuid.NewString(),
+		RedirectUri: "https://attacker.test/callback",
+	})
+
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestAuthorize_ScopeExceedsClientScope(t *testing.T) {
+	client := &data.Client{
+		ID:           rand.Int63(),
+		Name:         uuid.NewString(),
+		Website:      uuid.NewString(),
+		Scope:        "read",
+		RedirectURIs: []string{"https://client.test/callback"},
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, mock.Anything).Return(client, nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
+		SessionID: uuid.NewString(),
+		Subject:   rand.Int63(),
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.Authorize(context.Background(), &pb.AuthorizeRequest{
+		ClientId:    client.ID,
+		SessionId:   uuid.NewString(),
+		RedirectUri: client.RedirectURIs[0],
+		Scope:       "read admin",
+	})
+
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestConsentUser_HappyPath(t *testing.T) {
+	userID := rand.Int63()
+	sessionID := uuid.NewString()
+	authRequest := &data.AuthorizationRequest{
+		ID:          uuid.NewString(),
+		SessionID:   sessionID,
+		UserID:      userID,
+		ClientID:    rand.Int63(),
+		RedirectURI: "https://client.test/callback",
+		Scope:       "read",
+		State:       "xyz",
+		Status:      data.AuthorizationRequestPending,
+		ExpiresAt:   time.Now().Add(AuthorizationRequestExpTime),
+	}
+	authorization := &data.Authorization{
+		UserID:   userID,
+		ClientID: authRequest.ClientID,
+		AuthCode: uuid.NewString(),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetAuthorizationRequestByID", mock.Anything, authRequest.ID).Return(authRequest, nil)
+	mockDAL.On("ResolveAuthorizationRequest", mock.Anything, authRequest.ID, data.AuthorizationRequestApproved).Return(authRequest, nil)
+	mockDAL.On("CreateAuthorization", mock.Anything, mock.MatchedBy(func(params data.CreateAuthorizationParams) bool {
+		return params.Scope == authRequest.Scope && params.RedirectURI == authRequest.RedirectURI
+	})).Return(authorization, nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	rsp, err := authService.ConsentUser(context.Background(), &pb.UserConsentRequest{
+		SessionId: sessionID,
+		RequestId: authRequest.ID,
+		Approved:  true,
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.AuthorizationCode, authorization.AuthCode)
+	assert.Equal(t, rsp.State, authRequest.State)
+	assert.Equal(t, rsp.RedirectUri, authRequest.RedirectURI)
+}
+
+func TestConsentUser_PartialScope(t *testing.T) {
+	userID := rand.Int63()
+	sessionID := uuid.NewString()
+	authRequest := &data.AuthorizationRequest{
+		ID:        uuid.NewString(),
+		SessionID: sessionID,
+		UserID:    userID,
+		Scope:     "read write",
+		Status:    data.AuthorizationRequestPending,
+		ExpiresAt: time.Now().Add(AuthorizationRequestExpTime),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetAuthorizationRequestByID", mock.Anything, authRequest.ID).Return(authRequest, nil)
+	mockDAL.On("ResolveAuthorizationRequest", mock.Anything, authRequest.ID, data.AuthorizationRequestApproved).Return(authRequest, nil)
+	mockDAL.On("CreateAuthorization", mock.Anything, mock.MatchedBy(func(params data.CreateAuthorizationParams) bool {
+		return params.Scope == "read"
+	})).Return(&data.Authorization{AuthCode: uuid.NewString()}, nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
 	_, err := authService.ConsentUser(context.Background(), &pb.UserConsentRequest{
-		ClientId:  client.ID,
 		SessionId: sessionID,
+		RequestId: authRequest.ID,
+		Approved:  true,
+		Scope:     "read",
 	})
 
 	assert.Equal(t, err, nil)
 }
 
-func TestConsentUser_Unauthenticated(t *testing.T) {
+func TestConsentUser_ScopeExceedsRequest(t *testing.T) {
+	userID := rand.Int63()
+	sessionID := uuid.NewString()
+	authRequest := &data.AuthorizationRequest{
+		ID:        uuid.NewString(),
+		SessionID: sessionID,
+		UserID:    userID,
+		Scope:     "read",
+		Status:    data.AuthorizationRequestPending,
+		ExpiresAt: time.Now().Add(AuthorizationRequestExpTime),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetAuthorizationRequestByID", mock.Anything, authRequest.ID).Return(authRequest, nil)
 
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ConsentUser(context.Background(), &pb.UserConsentRequest{
+		SessionId: sessionID,
+		RequestId: authRequest.ID,
+		Approved:  true,
+		Scope:     "read admin",
+	})
+
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	mockDAL.AssertNotCalled(t, "ResolveAuthorizationRequest", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConsentUser_Denied(t *testing.T) {
+	userID := rand.Int63()
+	sessionID := uuid.NewString()
+	authRequest := &data.AuthorizationRequest{
+		ID:          uuid.NewString(),
+		SessionID:   sessionID,
+		UserID:      userID,
+		RedirectURI: "https://client.test/callback",
+		State:       "xyz",
+		Status:      data.AuthorizationRequestPending,
+		ExpiresAt:   time.Now().Add(AuthorizationRequestExpTime),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetAuthorizationRequestByID", mock.Anything, authRequest.ID).Return(authRequest, nil)
+	mockDAL.On("ResolveAuthorizationRequest", mock.Anything, authRequest.ID, data.AuthorizationRequestDenied).Return(authRequest, nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	rsp, err := authService.ConsentUser(context.Background(), &pb.UserConsentRequest{
+		SessionId: sessionID,
+		RequestId: authRequest.ID,
+	})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rsp.Error, ErrorAccessDenied)
+	assert.Equal(t, rsp.AuthorizationCode, "")
+	assert.Equal(t, rsp.State, authRequest.State)
+	mockDAL.AssertNotCalled(t, "CreateAuthorization", mock.Anything, mock.Anything)
+}
+
+func TestConsentUser_Unauthenticated(t *testing.T) {
 	mockSessionManager := &sessionMock.SessionManager{}
 	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{}, credentials.ErrInvalidSession)
 
-	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager)
+	authService := NewUserAuthService(&dalMock.DataProvider{}, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(&dalMock.DataProvider{}), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
 	_, err := authService.ConsentUser(context.Background(), &pb.UserConsentRequest{
-		ClientId:  rand.Int63(),
 		SessionId: mock.Anything,
+		RequestId: uuid.NewString(),
 	})
 
 	assert.Equal(t, status.Code(err), codes.Unauthenticated)
 }
+
+func TestConsentUser_OtherSession(t *testing.T) {
+	authRequest := &data.AuthorizationRequest{
+		ID:        uuid.NewString(),
+		SessionID: uuid.NewString(),
+		UserID:    rand.Int63(),
+		Status:    data.AuthorizationRequestPending,
+		ExpiresAt: time.Now().Add(AuthorizationRequestExpTime),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetAuthorizationRequestByID", mock.Anything, authRequest.ID).Return(authRequest, nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
+		SessionID: uuid.NewString(),
+		Subject:   rand.Int63(),
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ConsentUser(context.Background(), &pb.UserConsentRequest{
+		SessionId: uuid.NewString(),
+		RequestId: authRequest.ID,
+		Approved:  true,
+	})
+
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	mockDAL.AssertNotCalled(t, "ResolveAuthorizationRequest", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConsentUser_AlreadyResolved(t *testing.T) {
+	userID := rand.Int63()
+	sessionID := uuid.NewString()
+	authRequest := &data.AuthorizationRequest{
+		ID:        uuid.NewString(),
+		SessionID: sessionID,
+		UserID:    userID,
+		Status:    data.AuthorizationRequestApproved,
+		ExpiresAt: time.Now().Add(AuthorizationRequestExpTime),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetAuthorizationRequestByID", mock.Anything, authRequest.ID).Return(authRequest, nil)
+
+	mockSessionManager := &sessionMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, mock.Anything).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	authService := NewUserAuthService(mockDAL, mockSessionManager, &sessionMock.MFAManager{}, newHasher(), newPasswordPolicy(mockDAL), newThrottler(), mail.NewMemoryMailer(), newEmailConfig(), newResetConfig())
+	_, err := authService.ConsentUser(context.Background(), &pb.UserConsentRequest{
+		SessionId: sessionID,
+		RequestId: authRequest.ID,
+		Approved:  true,
+	})
+
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+)
+
+// Password hashing algorithms, selecting how new passwords and client secrets are hashed.
+const (
+	HashAlgorithmBcrypt   = "bcrypt"
+	HashAlgorithmScrypt   = "scrypt"
+	HashAlgorithmArgon2id = "argon2id"
+)
+
+const (
+	DefaultHashAlgorithm = HashAlgorithmArgon2id
+	DefaultBcryptCost    = 10
+
+	// scrypt and argon2id defaults follow the OWASP password storage recommendations.
+	DefaultScryptLogN        = 17 // N = 2^17
+	DefaultScryptR           = 8
+	DefaultScryptP           = 1
+	DefaultArgon2Memory      = 19456 // KiB
+	DefaultArgon2Iterations  = 2
+	DefaultArgon2Parallelism = 1
+
+	// Defaults of the hash parameters of Firebase projects.
+	DefaultFirebaseRounds  = 8
+	DefaultFirebaseMemCost = 14
+)
+
+// HasherConfig holds the configurations of password and client secret hashing. Hashes made with
+// another algorithm or other parameters are still verified, and upgraded on the next login.
+type HasherConfig struct {
+	algorithm         string
+	bcryptCost        int
+	scryptLogN        int
+	scryptR           int
+	scryptP           int
+	argon2Memory      int
+	argon2Iterations  int
+	argon2Parallelism int
+
+	// Project wide parameters of password hashes imported from Firebase, which are only verified if a
+	// signer key is set.
+	firebaseSignerKey     []byte
+	firebaseSaltSeparator []byte
+	firebaseRounds        int
+	firebaseMemCost       int
+}
+
+// NewHasherConfig returns a new instance of HasherConfig and
+// loads its values from environment variables or provides defaults.
+func NewHasherConfig() (*HasherConfig, error) {
+	config := &HasherConfig{
+		algorithm: DefaultHashAlgorithm,
+	}
+
+	if algorithm := os.Getenv("OAUTH_HASH_ALGORITHM"); len(algorithm) > 0 {
+		if algorithm != HashAlgorithmBcrypt && algorithm != HashAlgorithmScrypt && algorithm != HashAlgorithmArgon2id {
+			return nil, errors.New("OAUTH_HASH_ALGORITHM must be one of bcrypt, scrypt or argon2id")
+		}
+		config.algorithm = algorithm
+	}
+
+	ints := []struct {
+		key      string
+		def, min int
+		value    *int
+	}{
+		{"OAUTH_BCRYPT_COST", DefaultBcryptCost, 4, &config.bcryptCost},
+		{"OAUTH_SCRYPT_LOG_N", DefaultScryptLogN, 1, &config.scryptLogN},
+		{"OAUTH_SCRYPT_R", DefaultScryptR, 1, &config.scryptR},
+		{"OAUTH_SCRYPT_P", DefaultScryptP, 1, &config.scryptP},
+		{"OAUTH_ARGON2_MEMORY", DefaultArgon2Memory, 8, &config.argon2Memory},
+		{"OAUTH_ARGON2_ITERATIONS", DefaultArgon2Iterations, 1, &config.argon2Iterations},
+		{"OAUTH_ARGON2_PARALLELISM", DefaultArgon2Parallelism, 1, &config.argon2Parallelism},
+		{"OAUTH_FIREBASE_ROUNDS", DefaultFirebaseRounds, 1, &config.firebaseRounds},
+		{"OAUTH_FIREBASE_MEM_COST", DefaultFirebaseMemCost, 1, &config.firebaseMemCost},
+	}
+	for _, i := range ints {
+		value, err := intFromEnv(i.key, i.def, i.min)
+		if err != nil {
+			return nil, err
+		}
+		*i.value = value
+	}
+
+	if config.bcryptCost > 31 {
+		return nil, errors.New("OAUTH_BCRYPT_COST environment variable is not valid")
+	}
+	if config.argon2Parallelism > 255 {
+		return nil, errors.New("OAUTH_ARGON2_PARALLELISM environment variable is not valid")
+	}
+
+	// Firebase exports these base64 encoded, along with the rounds and memory cost
+	var err error
+	config.firebaseSignerKey, err = base64.StdEncoding.DecodeString(os.Getenv("OAUTH_FIREBASE_SIGNER_KEY"))
+	if err != nil {
+		return nil, errors.New("OAUTH_FIREBASE_SIGNER_KEY environment variable must be base64 encoded")
+	}
+	config.firebaseSaltSeparator, err = base64.StdEncoding.DecodeString(os.Getenv("OAUTH_FIREBASE_SALT_SEPARATOR"))
+	if err != nil {
+		return nil, errors.New("OAUTH_FIREBASE_SALT_SEPARATOR environment variable must be base64 encoded")
+	}
+
+	return config, nil
+}
+
+func (c HasherConfig) GetAlgorithm() string             { return c.algorithm }
+func (c HasherConfig) GetBcryptCost() int               { return c.bcryptCost }
+func (c HasherConfig) GetScryptLogN() int               { return c.scryptLogN }
+func (c HasherConfig) GetScryptR() int                  { return c.scryptR }
+func (c HasherConfig) GetScryptP() int                  { return c.scryptP }
+func (c HasherConfig) GetArgon2Memory() int             { return c.argon2Memory }
+func (c HasherConfig) GetArgon2Iterations() int         { return c.argon2Iterations }
+func (c HasherConfig) GetArgon2Parallelism() int        { return c.argon2Parallelism }
+func (c HasherConfig) GetFirebaseSignerKey() []byte     { return c.firebaseSignerKey }
+func (c HasherConfig) GetFirebaseSaltSeparator() []byte { return c.firebaseSaltSeparator }
+func (c HasherConfig) GetFirebaseRounds() int           { return c.firebaseRounds }
+func (c HasherConfig) GetFirebaseMemCost() int          { return c.firebaseMemCost }
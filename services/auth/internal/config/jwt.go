@@ -1,12 +1,32 @@
 package config
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// supportedAlgorithms are the JWS algorithms tokens can be signed with.
+var supportedAlgorithms = map[string]bool{
+	"HS256": true, "HS384": true, "HS512": true,
+	"RS256": true, "RS384": true, "RS512": true,
+	"PS256": true, "PS384": true, "PS512": true,
+	"ES256": true, "ES384": true, "ES512": true,
+	"EdDSA": true,
+}
+
 const (
 	DefaultAlgorithm    = "HS256"
 	DefaultHeaderName   = "Authorization"
@@ -16,6 +36,8 @@ const (
 // JWTConfig holds the JWT configurations.
 type JWTConfig struct {
 	secret                string
+	privateKey            crypto.Signer // signing key for asymmetric algorithms
+	keyID                 string
 	issuer                string
 	audience              string
 	algorithm             string
@@ -46,11 +68,42 @@ func NewJWTConfig() (*JWTConfig, error) {
 		config.headerPrefix = headerPrefix
 	}
 
-	secret := os.Getenv("OAUTH_JWT_SECRET")
-	if len(secret) == 0 {
-		return nil, errors.New("OAUTH_JWT_SECRET environment variable is required")
+	if !supportedAlgorithms[config.algorithm] {
+		return nil, fmt.Errorf("OAUTH_JWT_ALGORITHM %s is not supported", config.algorithm)
+	}
+
+	if strings.HasPrefix(config.algorithm, "HS") {
+		secret := os.Getenv("OAUTH_JWT_SECRET")
+		if len(secret) == 0 {
+			return nil, errors.New("OAUTH_JWT_SECRET environment variable is required")
+		}
+		config.secret = secret
+	} else if keyFile := os.Getenv("OAUTH_JWT_PRIVATE_KEY_FILE"); len(keyFile) > 0 {
+		// Without a key file, asymmetric signing keys come from the key store.
+		keyPEM, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OAUTH_JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+
+		privateKey, err := ParsePrivateKeyPEM(keyPEM)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := CheckKeyAlgorithm(config.algorithm, privateKey); err != nil {
+			return nil, err
+		}
+		config.privateKey = privateKey
+
+		config.keyID, err = KeyThumbprint(privateKey.Public())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if keyID := os.Getenv("OAUTH_JWT_KEY_ID"); len(keyID) > 0 {
+		config.keyID = keyID
 	}
-	config.secret = secret
 
 	issuer := os.Getenv("OAUTH_JWT_ISSUER")
 	if len(issuer) == 0 {
@@ -80,6 +133,8 @@ func NewJWTConfig() (*JWTConfig, error) {
 }
 
 func (c JWTConfig) GetSecret() string                       { return c.secret }
+func (c JWTConfig) GetPrivateKey() crypto.Signer            { return c.privateKey }
+func (c JWTConfig) GetKeyID() string                        { return c.keyID }
 func (c JWTConfig) GetIssuer() string                       { return c.issuer }
 func (c JWTConfig) GetAudience() string                     { return c.audience }
 func (c JWTConfig) GetAlgorithm() string                    { return c.algorithm }
@@ -87,3 +142,70 @@ func (c JWTConfig) GetExpirationTime() time.Duration        { return c.expiratio
 func (c JWTConfig) GetRefreshExpirationTime() time.Duration { return c.refreshExpirationTime }
 func (c JWTConfig) GetHeaderName() string                   { return c.headerName }
 func (c JWTConfig) GetHeaderPrefix() string                 { return c.headerPrefix }
+
+// ParsePrivateKeyPEM parses an RSA, ECDSA or Ed25519 private key from a PKCS #8, PKCS #1 or SEC 1 PEM block.
+func ParsePrivateKeyPEM(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported private key PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// KeyThumbprint derives a key id from the SHA-256 digest of the DER encoded public key.
+func KeyThumbprint(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:16]), nil
+}
+
+// CheckKeyAlgorithm makes sure the private key can be used with the signing algorithm.
+func CheckKeyAlgorithm(algorithm string, key crypto.Signer) error {
+	var ok bool
+	switch algorithm {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		_, ok = key.(*rsa.PrivateKey)
+	case "ES256":
+		ecKey, isEC := key.(*ecdsa.PrivateKey)
+		ok = isEC && ecKey.Curve == elliptic.P256()
+	case "ES384":
+		ecKey, isEC := key.(*ecdsa.PrivateKey)
+		ok = isEC && ecKey.Curve == elliptic.P384()
+	case "ES512":
+		ecKey, isEC := key.(*ecdsa.PrivateKey)
+		ok = isEC && ecKey.Curve == elliptic.P521()
+	case "EdDSA":
+		_, ok = key.(ed25519.PrivateKey)
+	default:
+		return fmt.Errorf("unsupported signing algorithm %s", algorithm)
+	}
+
+	if !ok {
+		return fmt.Errorf("private key of type %T can't be used with %s", key, algorithm)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultKeyRotationInterval = 30 * 24 * time.Hour
+	DefaultKeyRefreshInterval  = time.Minute
+)
+
+// KeyConfig holds the signing key store configurations.
+type KeyConfig struct {
+	rotationInterval time.Duration
+	refreshInterval  time.Duration
+	encryptionKey    []byte // AES-256 key private signing keys are encrypted with at rest
+}
+
+// NewKeyConfig returns a new instance of KeyConfig and
+// loads its values from environment variables or provides defaults.
+func NewKeyConfig() (*KeyConfig, error) {
+	config := &KeyConfig{
+		rotationInterval: DefaultKeyRotationInterval,
+		refreshInterval:  DefaultKeyRefreshInterval,
+	}
+
+	if rotation := os.Getenv("OAUTH_JWT_KEY_ROTATION_INTERVAL"); len(rotation) > 0 {
+		seconds, err := strconv.Atoi(rotation)
+		if err != nil || seconds <= 0 {
+			return nil, errors.New("OAUTH_JWT_KEY_ROTATION_INTERVAL environment variable is not valid")
+		}
+		config.rotationInterval = time.Duration(seconds) * time.Second
+	}
+
+	if refresh := os.Getenv("OAUTH_JWT_KEY_REFRESH_INTERVAL"); len(refresh) > 0 {
+		seconds, err := strconv.Atoi(refresh)
+		if err != nil || seconds <= 0 {
+			return nil, errors.New("OAUTH_JWT_KEY_REFRESH_INTERVAL environment variable is not valid")
+		}
+		config.refreshInterval = time.Duration(seconds) * time.Second
+	}
+
+	encryptionKey := os.Getenv("OAUTH_JWT_KEY_ENCRYPTION_KEY")
+	if len(encryptionKey) == 0 {
+		return nil, errors.New("OAUTH_JWT_KEY_ENCRYPTION_KEY environment variable is required")
+	}
+	key, err := base64.StdEncoding.DecodeString(encryptionKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("OAUTH_JWT_KEY_ENCRYPTION_KEY must be a base64 encoded 32 byte key")
+	}
+	config.encryptionKey = key
+
+	if config.refreshInterval >= config.rotationInterval {
+		return nil, errors.New("OAUTH_JWT_KEY_REFRESH_INTERVAL must be shorter than OAUTH_JWT_KEY_ROTATION_INTERVAL")
+	}
+
+	return config, nil
+}
+
+func (c KeyConfig) GetRotationInterval() time.Duration { return c.rotationInterval }
+func (c KeyConfig) GetRefreshInterval() time.Duration  { return c.refreshInterval }
+func (c KeyConfig) GetEncryptionKey() []byte           { return c.encryptionKey }
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Mail transports, selecting how emails are delivered.
+const (
+	MailTransportSMTP   = "smtp"   // sent through an SMTP server
+	MailTransportFile   = "file"   // written to a file, or stdout, for local development
+	MailTransportMemory = "memory" // kept in memory, for tests
+)
+
+const (
+	DefaultMailTransport = MailTransportFile
+	DefaultMailFrom      = "no-reply@localhost"
+	DefaultSMTPPort      = 587
+	DefaultSMTPTimeout   = 10 * time.Second
+
+	DefaultEmailVerificationExpiration     = 24 * time.Hour
+	DefaultEmailVerificationResendInterval = time.Minute
+)
+
+// MailConfig holds the configurations of outgoing emails.
+type MailConfig struct {
+	transport    string
+	from         string
+	smtpHost     string
+	smtpPort     int
+	smtpUsername string
+	smtpPassword string
+	smtpTimeout  time.Duration // limit on connecting to the SMTP server and sending an email through it
+	file         string        // file the file transport appends to, stdout if empty
+}
+
+// NewMailConfig returns a new instance of MailConfig and
+// loads its values from environment variables or provides defaults.
+func NewMailConfig() (*MailConfig, error) {
+	config := &MailConfig{
+		transport:   DefaultMailTransport,
+		from:        DefaultMailFrom,
+		smtpPort:    DefaultSMTPPort,
+		smtpTimeout: DefaultSMTPTimeout,
+	}
+
+	if transport := os.Getenv("OAUTH_MAIL_TRANSPORT"); len(transport) > 0 {
+		if transport != MailTransportSMTP && transport != MailTransportFile && transport != MailTransportMemory {
+			return nil, errors.New("OAUTH_MAIL_TRANSPORT must be one of smtp, file or memory")
+		}
+		config.transport = transport
+	}
+
+	if from := os.Getenv("OAUTH_MAIL_FROM"); len(from) > 0 {
+		config.from = from
+	}
+
+	config.smtpHost = os.Getenv("OAUTH_SMTP_HOST")
+	if config.transport == MailTransportSMTP && len(config.smtpHost) == 0 {
+		return nil, errors.New("OAUTH_SMTP_HOST environment variable is required for the smtp transport")
+	}
+
+	var err error
+	if config.smtpPort, err = intFromEnv("OAUTH_SMTP_PORT", config.smtpPort, 1); err != nil {
+		return nil, err
+	}
+
+	timeout, err := intFromEnv("OAUTH_SMTP_TIMEOUT", int(config.smtpTimeout/time.Second), 1)
+	if err != nil {
+		return nil, err
+	}
+	config.smtpTimeout = time.Duration(timeout) * time.Second
+
+	config.smtpUsername = os.Getenv("OAUTH_SMTP_USERNAME")
+	config.smtpPassword = os.Getenv("OAUTH_SMTP_PASSWORD")
+	config.file = os.Getenv("OAUTH_MAIL_FILE")
+
+	return config, nil
+}
+
+func (c MailConfig) GetTransport() string          { return c.transport }
+func (c MailConfig) GetFrom() string               { return c.from }
+func (c MailConfig) GetSMTPHost() string           { return c.smtpHost }
+func (c MailConfig) GetSMTPPort() int              { return c.smtpPort }
+func (c MailConfig) GetSMTPUsername() string       { return c.smtpUsername }
+func (c MailConfig) GetSMTPPassword() string       { return c.smtpPassword }
+func (c MailConfig) GetSMTPTimeout() time.Duration { return c.smtpTimeout }
+func (c MailConfig) GetFile() string               { return c.file }
+
+// EmailVerificationConfig holds the configurations of email address verification.
+type EmailVerificationConfig struct {
+	required       bool          // whether users must verify their email address before logging in
+	expiration     time.Duration // lifetime of a verification token
+	resendInterval time.Duration // minimum time between two verification emails to a user
+	url            string        // optional link the token is appended to, pointing at a page calling VerifyEmail
+}
+
+// NewEmailVerificationConfig returns a new instance of EmailVerificationConfig and
+// loads its values from environment variables or provides defaults.
+func NewEmailVerificationConfig() (*EmailVerificationConfig, error) {
+	config := &EmailVerificationConfig{
+		expiration:     DefaultEmailVerificationExpiration,
+		resendInterval: DefaultEmailVerificationResendInterval,
+	}
+
+	if required := os.Getenv("OAUTH_EMAIL_VERIFICATION_REQUIRED"); len(required) > 0 {
+		value, err := strconv.ParseBool(required)
+		if err != nil {
+			return nil, errors.New("OAUTH_EMAIL_VERIFICATION_REQUIRED environment variable must be a boolean")
+		}
+		config.required = value
+	}
+
+	durations := []struct {
+		key   string
+		value *time.Duration
+	}{
+		{"OAUTH_EMAIL_VERIFICATION_EXPIRATION", &config.expiration},
+		{"OAUTH_EMAIL_VERIFICATION_RESEND_INTERVAL", &config.resendInterval},
+	}
+	for _, d := range durations {
+		seconds, err := intFromEnv(d.key, int(*d.value/time.Second), 1)
+		if err != nil {
+			return nil, err
+		}
+		*d.value = time.Duration(seconds) * time.Second
+	}
+
+	config.url = os.Getenv("OAUTH_EMAIL_VERIFICATION_URL")
+
+	return config, nil
+}
+
+func (c EmailVerificationConfig) IsRequired() bool                 { return c.required }
+func (c EmailVerificationConfig) GetExpiration() time.Duration     { return c.expiration }
+func (c EmailVerificationConfig) GetResendInterval() time.Duration { return c.resendInterval }
+func (c EmailVerificationConfig) GetURL() string                   { return c.url }
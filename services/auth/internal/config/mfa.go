@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"time"
+)
+
+const (
+	DefaultMFAIssuer              = "GoAuth"
+	DefaultMFAChallengeExpiration = 5 * time.Minute
+	DefaultMFARecoveryCodes       = 10
+)
+
+// MFAConfig holds the multi-factor authentication configurations.
+type MFAConfig struct {
+	issuer              string // shown by authenticator apps next to the account name
+	encryptionKey       []byte // AES-256 key TOTP secrets are encrypted with at rest
+	challengeExpiration time.Duration
+	recoveryCodes       int // number of recovery codes generated at a time
+}
+
+// NewMFAConfig returns a new instance of MFAConfig and
+// loads its values from environment variables or provides defaults.
+func NewMFAConfig() (*MFAConfig, error) {
+	config := &MFAConfig{
+		issuer:              DefaultMFAIssuer,
+		challengeExpiration: DefaultMFAChallengeExpiration,
+		recoveryCodes:       DefaultMFARecoveryCodes,
+	}
+
+	if issuer := os.Getenv("OAUTH_MFA_ISSUER"); len(issuer) > 0 {
+		config.issuer = issuer
+	}
+
+	encryptionKey := os.Getenv("OAUTH_MFA_ENCRYPTION_KEY")
+	if len(encryptionKey) == 0 {
+		return nil, errors.New("OAUTH_MFA_ENCRYPTION_KEY environment variable is required")
+	}
+	key, err := base64.StdEncoding.DecodeString(encryptionKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("OAUTH_MFA_ENCRYPTION_KEY must be a base64 encoded 32 byte key")
+	}
+	config.encryptionKey = key
+
+	seconds, err := intFromEnv("OAUTH_MFA_CHALLENGE_EXPIRATION", int(config.challengeExpiration/time.Second), 1)
+	if err != nil {
+		return nil, err
+	}
+	config.challengeExpiration = time.Duration(seconds) * time.Second
+
+	if config.recoveryCodes, err = intFromEnv("OAUTH_MFA_RECOVERY_CODES", config.recoveryCodes, 1); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (c MFAConfig) GetIssuer() string                     { return c.issuer }
+func (c MFAConfig) GetEncryptionKey() []byte              { return c.encryptionKey }
+func (c MFAConfig) GetChallengeExpiration() time.Duration { return c.challengeExpiration }
+func (c MFAConfig) GetRecoveryCodes() int                 { return c.recoveryCodes }
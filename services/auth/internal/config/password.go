@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPasswordResetExpiration     = 30 * time.Minute
+	DefaultPasswordResetResendInterval = time.Minute
+
+	DefaultPasswordMinLength = 8
+	DefaultPasswordMaxLength = 128
+	DefaultPasswordHistory   = 5
+)
+
+// PasswordResetConfig holds the configurations of self-service password resets.
+type PasswordResetConfig struct {
+	expiration     time.Duration // lifetime of a reset token
+	resendInterval time.Duration // minimum time between two reset emails to a user
+	url            string        // optional link the token is appended to, pointing at a page calling ResetPassword
+}
+
+// NewPasswordResetConfig returns a new instance of PasswordResetConfig and
+// loads its values from environment variables or provides defaults.
+func NewPasswordResetConfig() (*PasswordResetConfig, error) {
+	config := &PasswordResetConfig{
+		expiration:     DefaultPasswordResetExpiration,
+		resendInterval: DefaultPasswordResetResendInterval,
+	}
+
+	durations := []struct {
+		key   string
+		value *time.Duration
+	}{
+		{"OAUTH_PASSWORD_RESET_EXPIRATION", &config.expiration},
+		{"OAUTH_PASSWORD_RESET_RESEND_INTERVAL", &config.resendInterval},
+	}
+	for _, d := range durations {
+		seconds, err := intFromEnv(d.key, int(*d.value/time.Second), 1)
+		if err != nil {
+			return nil, err
+		}
+		*d.value = time.Duration(seconds) * time.Second
+	}
+
+	config.url = os.Getenv("OAUTH_PASSWORD_RESET_URL")
+
+	return config, nil
+}
+
+func (c PasswordResetConfig) GetExpiration() time.Duration     { return c.expiration }
+func (c PasswordResetConfig) GetResendInterval() time.Duration { return c.resendInterval }
+func (c PasswordResetConfig) GetURL() string                   { return c.url }
+
+// PasswordPolicyConfig holds the rules new passwords have to follow.
+type PasswordPolicyConfig struct {
+	minLength     int // in characters
+	maxLength     int
+	requireLower  bool
+	requireUpper  bool
+	requireDigit  bool
+	requireSymbol bool
+	history       int    // number of recent passwords that can't be reused, 0 to allow any
+	breachedDir   string // optional directory of breached password hashes, one file per SHA-1 prefix
+}
+
+// NewPasswordPolicyConfig returns a new instance of PasswordPolicyConfig and
+// loads its values from environment variables or provides defaults.
+func NewPasswordPolicyConfig() (*PasswordPolicyConfig, error) {
+	config := &PasswordPolicyConfig{}
+
+	var err error
+	if config.minLength, err = intFromEnv("OAUTH_PASSWORD_MIN_LENGTH", DefaultPasswordMinLength, 1); err != nil {
+		return nil, err
+	}
+	if config.maxLength, err = intFromEnv("OAUTH_PASSWORD_MAX_LENGTH", DefaultPasswordMaxLength, config.minLength); err != nil {
+		return nil, err
+	}
+	if config.history, err = intFromEnv("OAUTH_PASSWORD_HISTORY", DefaultPasswordHistory, 0); err != nil {
+		return nil, err
+	}
+
+	bools := []struct {
+		key   string
+		value *bool
+	}{
+		{"OAUTH_PASSWORD_REQUIRE_LOWER", &config.requireLower},
+		{"OAUTH_PASSWORD_REQUIRE_UPPER", &config.requireUpper},
+		{"OAUTH_PASSWORD_REQUIRE_DIGIT", &config.requireDigit},
+		{"OAUTH_PASSWORD_REQUIRE_SYMBOL", &config.requireSymbol},
+	}
+	for _, b := range bools {
+		if value := os.Getenv(b.key); len(value) > 0 {
+			*b.value, err = strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s environment variable must be a boolean", b.key)
+			}
+		}
+	}
+
+	if dir := os.Getenv("OAUTH_PASSWORD_BREACHED_DIR"); len(dir) > 0 {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			return nil, errors.New("OAUTH_PASSWORD_BREACHED_DIR environment variable must be a directory")
+		}
+		config.breachedDir = dir
+	}
+
+	return config, nil
+}
+
+func (c PasswordPolicyConfig) GetMinLength() int      { return c.minLength }
+func (c PasswordPolicyConfig) GetMaxLength() int      { return c.maxLength }
+func (c PasswordPolicyConfig) IsLowerRequired() bool  { return c.requireLower }
+func (c PasswordPolicyConfig) IsUpperRequired() bool  { return c.requireUpper }
+func (c PasswordPolicyConfig) IsDigitRequired() bool  { return c.requireDigit }
+func (c PasswordPolicyConfig) IsSymbolRequired() bool { return c.requireSymbol }
+func (c PasswordPolicyConfig) GetHistory() int        { return c.history }
+func (c PasswordPolicyConfig) GetBreachedDir() string { return c.breachedDir }
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	DefaultGRPCAddr = ":5051"
+	DefaultHTTPAddr = ":8080"
+)
+
+// ServerConfig holds the addresses the service listens on.
+type ServerConfig struct {
+	grpcAddr   string
+	httpAddr   string
+	publicURL  string // base URL the HTTP endpoints are reachable at
+	policyFile string // optional JSON file with per-method authorization requirements
+	loginURL   string // optional page users without a session are sent to from the authorization endpoint
+}
+
+// NewServerConfig returns a new instance of ServerConfig and
+// loads its values from environment variables or provides defaults.
+func NewServerConfig() (*ServerConfig, error) {
+	config := &ServerConfig{
+		grpcAddr: DefaultGRPCAddr,
+		httpAddr: DefaultHTTPAddr,
+	}
+
+	if grpcAddr := os.Getenv("OAUTH_GRPC_ADDR"); len(grpcAddr) > 0 {
+		config.grpcAddr = grpcAddr
+	}
+
+	if httpAddr := os.Getenv("OAUTH_HTTP_ADDR"); len(httpAddr) > 0 {
+		config.httpAddr = httpAddr
+	}
+
+	config.publicURL = "http://localhost" + config.httpAddr
+	if !strings.HasPrefix(config.httpAddr, ":") {
+		config.publicURL = "http://" + config.httpAddr
+	}
+
+	if publicURL := os.Getenv("OAUTH_PUBLIC_URL"); len(publicURL) > 0 {
+		parsed, err := url.Parse(publicURL)
+		if err != nil || len(parsed.Scheme) == 0 || len(parsed.Host) == 0 || len(parsed.RawQuery) > 0 || len(parsed.Fragment) > 0 {
+			return nil, errors.New("OAUTH_PUBLIC_URL environment variable is not valid")
+		}
+		config.publicURL = strings.TrimSuffix(publicURL, "/")
+	}
+
+	config.policyFile = os.Getenv("OAUTH_POLICY_FILE")
+
+	if loginURL := os.Getenv("OAUTH_LOGIN_URL"); len(loginURL) > 0 {
+		parsed, err := url.Parse(loginURL)
+		if err != nil || len(parsed.Fragment) > 0 {
+			return nil, errors.New("OAUTH_LOGIN_URL environment variable is not valid")
+		}
+		config.loginURL = loginURL
+	}
+
+	return config, nil
+}
+
+func (c ServerConfig) GetGRPCAddr() string   { return c.grpcAddr }
+func (c ServerConfig) GetHTTPAddr() string   { return c.httpAddr }
+func (c ServerConfig) GetPublicURL() string  { return c.publicURL }
+func (c ServerConfig) GetPolicyFile() string { return c.policyFile }
+func (c ServerConfig) GetLoginURL() string   { return c.loginURL }
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	ThrottleStoreMemory   = "memory"
+	ThrottleStorePostgres = "postgres"
+
+	DefaultThrottleFreeAttempts    = 3
+	DefaultThrottleBaseDelay       = time.Second
+	DefaultThrottleMaxDelay        = time.Minute
+	DefaultThrottleLockoutAttempts = 10
+	DefaultThrottleLockoutDuration = 15 * time.Minute
+	DefaultThrottleWindow          = time.Hour
+)
+
+// ThrottleConfig holds the configurations for throttling failed authentication attempts.
+type ThrottleConfig struct {
+	freeAttempts    int           // failures allowed before any delay is applied
+	baseDelay       time.Duration // delay after the first failure past the free attempts, doubled on every further one
+	maxDelay        time.Duration
+	lockoutAttempts int // failures after which the key is locked out
+	lockoutDuration time.Duration
+	window          time.Duration // failures older than this are forgotten
+	store           string
+}
+
+// NewThrottleConfig returns a new instance of ThrottleConfig and
+// loads its values from environment variables or provides defaults.
+func NewThrottleConfig() (*ThrottleConfig, error) {
+	config := &ThrottleConfig{
+		freeAttempts:    DefaultThrottleFreeAttempts,
+		baseDelay:       DefaultThrottleBaseDelay,
+		maxDelay:        DefaultThrottleMaxDelay,
+		lockoutAttempts: DefaultThrottleLockoutAttempts,
+		lockoutDuration: DefaultThrottleLockoutDuration,
+		window:          DefaultThrottleWindow,
+		store:           ThrottleStoreMemory,
+	}
+
+	var err error
+	if config.freeAttempts, err = intFromEnv("OAUTH_THROTTLE_FREE_ATTEMPTS", config.freeAttempts, 0); err != nil {
+		return nil, err
+	}
+	if config.lockoutAttempts, err = intFromEnv("OAUTH_THROTTLE_LOCKOUT_ATTEMPTS", config.lockoutAttempts, 1); err != nil {
+		return nil, err
+	}
+
+	durations := []struct {
+		key   string
+		value *time.Duration
+	}{
+		{"OAUTH_THROTTLE_BASE_DELAY", &config.baseDelay},
+		{"OAUTH_THROTTLE_MAX_DELAY", &config.maxDelay},
+		{"OAUTH_THROTTLE_LOCKOUT_DURATION", &config.lockoutDuration},
+		{"OAUTH_THROTTLE_WINDOW", &config.window},
+	}
+	for _, d := range durations {
+		seconds, err := intFromEnv(d.key, int(*d.value/time.Second), 1)
+		if err != nil {
+			return nil, err
+		}
+		*d.value = time.Duration(seconds) * time.Second
+	}
+
+	if config.lockoutAttempts <= config.freeAttempts {
+		return nil, errors.New("OAUTH_THROTTLE_LOCKOUT_ATTEMPTS must be greater than OAUTH_THROTTLE_FREE_ATTEMPTS")
+	}
+	if config.maxDelay < config.baseDelay {
+		return nil, errors.New("OAUTH_THROTTLE_MAX_DELAY must not be shorter than OAUTH_THROTTLE_BASE_DELAY")
+	}
+	if config.window < config.lockoutDuration || config.window < config.maxDelay {
+		return nil, errors.New("OAUTH_THROTTLE_WINDOW must not be shorter than OAUTH_THROTTLE_LOCKOUT_DURATION and OAUTH_THROTTLE_MAX_DELAY")
+	}
+
+	if store := os.Getenv("OAUTH_THROTTLE_STORE"); len(store) > 0 {
+		if store != ThrottleStoreMemory && store != ThrottleStorePostgres {
+			return nil, fmt.Errorf("OAUTH_THROTTLE_STORE must be %s or %s", ThrottleStoreMemory, ThrottleStorePostgres)
+		}
+		config.store = store
+	}
+
+	return config, nil
+}
+
+// intFromEnv reads an integer of at least min from the environment variable key, or returns def if it's not set.
+func intFromEnv(key string, def, min int) (int, error) {
+	value := os.Getenv(key)
+	if len(value) == 0 {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		return 0, fmt.Errorf("%s environment variable is not valid", key)
+	}
+	return n, nil
+}
+
+func (c ThrottleConfig) GetFreeAttempts() int              { return c.freeAttempts }
+func (c ThrottleConfig) GetBaseDelay() time.Duration       { return c.baseDelay }
+func (c ThrottleConfig) GetMaxDelay() time.Duration        { return c.maxDelay }
+func (c ThrottleConfig) GetLockoutAttempts() int           { return c.lockoutAttempts }
+func (c ThrottleConfig) GetLockoutDuration() time.Duration { return c.lockoutDuration }
+func (c ThrottleConfig) GetWindow() time.Duration          { return c.window }
+func (c ThrottleConfig) GetStore() string                  { return c.store }
@@ -0,0 +1,171 @@
+// Package bearer provides gRPC interceptors that authenticate calls with bearer access tokens.
+package bearer
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrMissingToken = status.Error(codes.Unauthenticated, "missing bearer token")
+	ErrInvalidToken = status.Error(codes.Unauthenticated, "invalid bearer token")
+)
+
+type claimsKey struct{}
+
+// Authenticator validates the bearer token of incoming calls and stores its claims in the call context.
+type Authenticator struct {
+	headerName    string // metadata key the token is read from
+	headerPrefix  string // scheme preceding the token, such as Bearer
+	tokenHandler  credentials.TokenHandler
+	publicMethods map[string]bool
+}
+
+// NewAuthenticator creates a new Authenticator reading tokens as configured in cnfg. Calls to the
+// public methods, given as full gRPC method names, are let through without a token.
+func NewAuthenticator(cnfg *config.JWTConfig, tokenHandler credentials.TokenHandler, publicMethods ...string) *Authenticator {
+	public := make(map[string]bool, len(publicMethods))
+	for _, method := range publicMethods {
+		public[method] = true
+	}
+
+	return &Authenticator{
+		headerName:    strings.ToLower(cnfg.GetHeaderName()),
+		headerPrefix:  cnfg.GetHeaderPrefix(),
+		tokenHandler:  tokenHandler,
+		publicMethods: public,
+	}
+}
+
+// UnaryInterceptor returns a unary server interceptor that authenticates calls.
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor returns a stream server interceptor that authenticates calls.
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticate returns ctx carrying the claims of the call's access token. Public methods are let
+// through unchanged when no token is presented, and with its claims when a valid one is.
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	logger := logrus.WithContext(ctx).WithField("method", method)
+
+	token, ok := a.extractToken(ctx)
+	if !ok {
+		if a.publicMethods[method] {
+			return ctx, nil
+		}
+		logger.Warn("call without bearer token rejected")
+		return nil, ErrMissingToken
+	}
+
+	claims, err := a.tokenHandler.Validate(ctx, token)
+	if err != nil {
+		if errors.Is(err, credentials.ErrInvalidToken) {
+			logger.Warn("invalid bearer token: %w", err)
+			return nil, ErrInvalidToken
+		}
+		logger.Error("error failed to validate bearer token: %w", err)
+		return nil, status.Errorf(codes.Internal, "Internal server error")
+	}
+
+	// Refresh tokens are only ever presented to the token endpoint.
+	if claims.TokenType != credentials.AccessToken {
+		logger.Warnf("%s token presented as bearer token", claims.TokenType)
+		return nil, ErrInvalidToken
+	}
+
+	return NewContext(ctx, claims), nil
+}
+
+// extractToken reads the token from the configured metadata key, such as "authorization: Bearer <token>".
+func (a *Authenticator) extractToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := md.Get(a.headerName)
+	if len(values) != 1 {
+		return "", false
+	}
+
+	scheme, token, found := strings.Cut(values[0], " ")
+	if !found || !strings.EqualFold(scheme, a.headerPrefix) {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, len(token) > 0
+}
+
+// serverStream overrides the context of a stream with the authenticated one.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+// NewContext returns a copy of ctx carrying the claims of an authenticated call.
+func NewContext(ctx context.Context, claims *credentials.Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the call's access token, if the call was authenticated.
+func ClaimsFromContext(ctx context.Context) (*credentials.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*credentials.Claims)
+	return claims, ok
+}
+
+// UserIDFromContext returns the id of the user the call's access token acts for. It reports false for
+// unauthenticated calls and for tokens issued to a client on its own behalf.
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok || claims.SubjectType != credentials.UserSubject {
+		return 0, false
+	}
+	userID, ok := claims.Subject.(int64)
+	return userID, ok
+}
+
+// ClientIDFromContext returns the id of the client the call's access token was issued to.
+func ClientIDFromContext(ctx context.Context) (int64, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return claims.ClientID, true
+}
+
+// ScopeFromContext returns the scope granted to the call's access token.
+func ScopeFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.Scope, true
+}
@@ -0,0 +1,157 @@
+package bearer
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	"github.com/ramyadmz/goauth/integration"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/mock"
+	testifyMock "github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	publicMethod    = "/test.Service/Public"
+	protectedMethod = "/test.Service/Protected"
+)
+
+func newAuthenticator(t *testing.T, tokenHandler credentials.TokenHandler) *Authenticator {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	cnfg, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+	return NewAuthenticator(cnfg, tokenHandler, publicMethod)
+}
+
+func withToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+// callUnary runs the unary interceptor for method and returns the context the handler was called with.
+func callUnary(a *Authenticator, ctx context.Context, method string) (context.Context, error) {
+	var handlerCtx context.Context
+	_, err := a.UnaryInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerCtx = ctx
+		return nil, nil
+	})
+	return handlerCtx, err
+}
+
+func TestUnaryInterceptor_ValidToken(t *testing.T) {
+	token := uuid.NewString()
+	claims := &credentials.Claims{
+		Subject:     rand.Int63(),
+		SubjectType: credentials.UserSubject,
+		ClientID:    rand.Int63(),
+		Scope:       "read",
+		TokenType:   credentials.AccessToken,
+	}
+
+	mockTokenHandler := &mock.TokenHandler{}
+	mockTokenHandler.On("Validate", testifyMock.Anything, token).Return(claims, nil)
+
+	ctx, err := callUnary(newAuthenticator(t, mockTokenHandler), withToken(token), protectedMethod)
+	assert.Equal(t, err, nil)
+
+	userID, ok := UserIDFromContext(ctx)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, userID, claims.Subject)
+
+	clientID, ok := ClientIDFromContext(ctx)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, clientID, claims.ClientID)
+
+	scope, _ := ScopeFromContext(ctx)
+	assert.Equal(t, scope, claims.Scope)
+}
+
+func TestUnaryInterceptor_MissingToken(t *testing.T) {
+	_, err := callUnary(newAuthenticator(t, &mock.TokenHandler{}), context.Background(), protectedMethod)
+	assert.Equal(t, err, ErrMissingToken)
+}
+
+func TestUnaryInterceptor_WrongScheme(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic "+uuid.NewString()))
+
+	_, err := callUnary(newAuthenticator(t, &mock.TokenHandler{}), ctx, protectedMethod)
+	assert.Equal(t, err, ErrMissingToken)
+}
+
+func TestUnaryInterceptor_InvalidToken(t *testing.T) {
+	mockTokenHandler := &mock.TokenHandler{}
+	mockTokenHandler.On("Validate", testifyMock.Anything, testifyMock.Anything).Return((*credentials.Claims)(nil), credentials.ErrInvalidToken)
+
+	_, err := callUnary(newAuthenticator(t, mockTokenHandler), withToken(uuid.NewString()), protectedMethod)
+	assert.Equal(t, err, ErrInvalidToken)
+}
+
+func TestUnaryInterceptor_RefreshToken(t *testing.T) {
+	mockTokenHandler := &mock.TokenHandler{}
+	mockTokenHandler.On("Validate", testifyMock.Anything, testifyMock.Anything).Return(&credentials.Claims{
+		TokenType: credentials.RefreshToken,
+	}, nil)
+
+	_, err := callUnary(newAuthenticator(t, mockTokenHandler), withToken(uuid.NewString()), protectedMethod)
+	assert.Equal(t, err, ErrInvalidToken)
+}
+
+func TestUnaryInterceptor_PublicMethod(t *testing.T) {
+	ctx, err := callUnary(newAuthenticator(t, &mock.TokenHandler{}), context.Background(), publicMethod)
+	assert.Equal(t, err, nil)
+
+	_, ok := ClaimsFromContext(ctx)
+	assert.Equal(t, ok, false)
+}
+
+func TestUnaryInterceptor_ClientToken(t *testing.T) {
+	mockTokenHandler := &mock.TokenHandler{}
+	mockTokenHandler.On("Validate", testifyMock.Anything, testifyMock.Anything).Return(&credentials.Claims{
+		Subject:     rand.Int63(),
+		SubjectType: credentials.ClientSubject,
+		TokenType:   credentials.AccessToken,
+	}, nil)
+
+	ctx, err := callUnary(newAuthenticator(t, mockTokenHandler), withToken(uuid.NewString()), protectedMethod)
+	assert.Equal(t, err, nil)
+
+	_, ok := UserIDFromContext(ctx)
+	assert.Equal(t, ok, false)
+}
+
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context { return s.ctx }
+
+func TestStreamInterceptor_ValidToken(t *testing.T) {
+	claims := &credentials.Claims{
+		Subject:     rand.Int63(),
+		SubjectType: credentials.UserSubject,
+		TokenType:   credentials.AccessToken,
+	}
+
+	mockTokenHandler := &mock.TokenHandler{}
+	mockTokenHandler.On("Validate", testifyMock.Anything, testifyMock.Anything).Return(claims, nil)
+
+	var handlerCtx context.Context
+	err := newAuthenticator(t, mockTokenHandler).StreamInterceptor()(nil, &testStream{ctx: withToken(uuid.NewString())}, &grpc.StreamServerInfo{FullMethod: protectedMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		handlerCtx = stream.Context()
+		return nil
+	})
+
+	assert.Equal(t, err, nil)
+	userID, ok := UserIDFromContext(handlerCtx)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, userID, claims.Subject)
+}
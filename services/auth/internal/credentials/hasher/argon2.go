@@ -0,0 +1,100 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"math"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idID = "argon2id"
+
+// Ensure Argon2idHasher implements the Algorithm interface.
+var _ Algorithm = new(Argon2idHasher)
+
+// Argon2idHasher hashes data with argon2id, encoded as
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<hash>.
+type Argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// NewArgon2idHasher creates a new Argon2idHasher using memory KiB of memory.
+func NewArgon2idHasher(memory, iterations uint32, parallelism uint8) *Argon2idHasher {
+	return &Argon2idHasher{
+		memory:      memory,
+		iterations:  iterations,
+		parallelism: parallelism,
+	}
+}
+
+// Hash returns the argon2id hash of data with a random salt.
+func (h *Argon2idHasher) Hash(data string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", credentials.ErrHashPassword
+	}
+
+	hashed := argon2.IDKey([]byte(data), salt, h.iterations, h.memory, h.parallelism, keySize)
+
+	return (&phc{
+		id:      argon2idID,
+		version: argon2.Version,
+		params:  []param{{"m", int(h.memory)}, {"t", int(h.iterations)}, {"p", int(h.parallelism)}},
+		salt:    salt,
+		hash:    hashed,
+	}).String(), nil
+}
+
+// Compare returns credentials.ErrInvalidPass if data doesn't match the hash.
+func (h *Argon2idHasher) Compare(hashed, data string) error {
+	p, memory, iterations, parallelism, err := parseArgon2id(hashed)
+	if err != nil {
+		return credentials.ErrVerifyPassword
+	}
+
+	key := argon2.IDKey([]byte(data), p.salt, iterations, memory, parallelism, uint32(len(p.hash)))
+	if subtle.ConstantTimeCompare(key, p.hash) != 1 {
+		return credentials.ErrInvalidPass
+	}
+	return nil
+}
+
+// NeedsRehash reports whether the hash wasn't made with argon2id and the hasher's parameters.
+func (h *Argon2idHasher) NeedsRehash(hashed string) bool {
+	p, memory, iterations, parallelism, err := parseArgon2id(hashed)
+	return err != nil || memory != h.memory || iterations != h.iterations || parallelism != h.parallelism || len(p.hash) != keySize
+}
+
+// Identify reports whether the hash was made with argon2id.
+func (h *Argon2idHasher) Identify(hashed string) bool {
+	return strings.HasPrefix(hashed, "$"+argon2idID+"$")
+}
+
+func parseArgon2id(hashed string) (p *phc, memory, iterations uint32, parallelism uint8, err error) {
+	p, err = parsePHC(hashed)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	if p.id != argon2idID || p.version != argon2.Version {
+		return nil, 0, 0, 0, errMalformedHash
+	}
+
+	m, err := p.param("m")
+	if err != nil || m > math.MaxUint32 {
+		return nil, 0, 0, 0, errMalformedHash
+	}
+	t, err := p.param("t")
+	if err != nil || t > math.MaxUint32 {
+		return nil, 0, 0, 0, errMalformedHash
+	}
+	threads, err := p.param("p")
+	if err != nil || threads > math.MaxUint8 {
+		return nil, 0, 0, 0, errMalformedHash
+	}
+	return p, uint32(m), uint32(t), uint8(threads), nil
+}
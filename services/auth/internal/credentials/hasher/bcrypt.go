@@ -0,0 +1,57 @@
+// Package hasher provides implementations of the SecureHasher interface.
+package hasher
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ensure BcryptHasher implements the Algorithm interface.
+var _ Algorithm = new(BcryptHasher)
+
+// BcryptHasher hashes data with bcrypt, in its own $2a$<cost>$<salt and hash> format.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a new BcryptHasher with the given cost.
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{
+		cost: cost,
+	}
+}
+
+// Hash returns the bcrypt hash of data.
+func (h *BcryptHasher) Hash(data string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(data), h.cost)
+	if err != nil {
+		return "", credentials.ErrHashPassword
+	}
+	return string(hashed), nil
+}
+
+// Compare returns credentials.ErrInvalidPass if data doesn't match the hash.
+func (h *BcryptHasher) Compare(hashed, data string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(data))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return credentials.ErrInvalidPass
+		}
+		return credentials.ErrVerifyPassword
+	}
+	return nil
+}
+
+// NeedsRehash reports whether the hash wasn't made with bcrypt and the hasher's cost.
+func (h *BcryptHasher) NeedsRehash(hashed string) bool {
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost != h.cost
+}
+
+// Identify reports whether the hash was made with bcrypt.
+func (h *BcryptHasher) Identify(hashed string) bool {
+	return strings.HasPrefix(hashed, "$2")
+}
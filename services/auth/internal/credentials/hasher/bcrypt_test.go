@@ -0,0 +1,29 @@
+package hasher
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptHasher(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+
+	hashed, err := hasher.Hash("correct horse")
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, hashed, "correct horse")
+
+	assert.Equal(t, hasher.Compare(hashed, "correct horse"), nil)
+	assert.Equal(t, hasher.Compare(hashed, "battery staple"), credentials.ErrInvalidPass)
+	assert.Equal(t, hasher.Compare("not a hash", "correct horse"), credentials.ErrVerifyPassword)
+}
+
+func TestBcryptHasher_NeedsRehash(t *testing.T) {
+	hashed, err := NewBcryptHasher(bcrypt.MinCost).Hash("correct horse")
+	assert.Equal(t, err, nil)
+
+	assert.Equal(t, NewBcryptHasher(bcrypt.MinCost).NeedsRehash(hashed), false)
+	assert.Equal(t, NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(hashed), true)
+}
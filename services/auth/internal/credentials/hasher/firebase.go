@@ -0,0 +1,79 @@
+package hasher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"golang.org/x/crypto/scrypt"
+)
+
+const firebaseScryptPrefix = "firebase_scrypt$"
+
+// Ensure FirebaseScryptVerifier implements the Verifier interface.
+var _ Verifier = new(FirebaseScryptVerifier)
+
+// FirebaseScryptVerifier verifies password hashes exported from Firebase Authentication, stored as
+// firebase_scrypt$<base64 salt>$<base64 hash>. Firebase encrypts the project's signer key with the
+// scrypt key of the password, so the signer key, salt separator, rounds and memory cost of the
+// project's hash parameters are needed to verify them.
+type FirebaseScryptVerifier struct {
+	signerKey     []byte
+	saltSeparator []byte
+	rounds        int
+	memCost       int
+}
+
+// NewFirebaseScryptVerifier creates a new FirebaseScryptVerifier with the hash parameters of a
+// Firebase project.
+func NewFirebaseScryptVerifier(signerKey, saltSeparator []byte, rounds, memCost int) *FirebaseScryptVerifier {
+	return &FirebaseScryptVerifier{
+		signerKey:     signerKey,
+		saltSeparator: saltSeparator,
+		rounds:        rounds,
+		memCost:       memCost,
+	}
+}
+
+// Compare returns credentials.ErrInvalidPass if data doesn't match the hash.
+func (v *FirebaseScryptVerifier) Compare(hashed, data string) error {
+	parts := strings.Split(strings.TrimPrefix(hashed, firebaseScryptPrefix), "$")
+	if len(parts) != 2 {
+		return credentials.ErrVerifyPassword
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return credentials.ErrVerifyPassword
+	}
+	expected, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil || len(expected) == 0 {
+		return credentials.ErrVerifyPassword
+	}
+
+	key, err := scrypt.Key([]byte(data), append(salt, v.saltSeparator...), 1<<v.memCost, v.rounds, 1, 32)
+	if err != nil {
+		return credentials.ErrVerifyPassword
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return credentials.ErrVerifyPassword
+	}
+
+	// The signer key is encrypted with AES-256 in CTR mode and an all zero IV
+	encrypted := make([]byte, len(v.signerKey))
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(encrypted, v.signerKey)
+
+	if subtle.ConstantTimeCompare(encrypted, expected) != 1 {
+		return credentials.ErrInvalidPass
+	}
+	return nil
+}
+
+// Identify reports whether the hash is a Firebase scrypt hash.
+func (v *FirebaseScryptVerifier) Identify(hashed string) bool {
+	return strings.HasPrefix(hashed, firebaseScryptPrefix)
+}
@@ -0,0 +1,77 @@
+package hasher
+
+import (
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+)
+
+// Ensure Hasher implements the SecureHasher interface from the credentials package.
+var _ credentials.SecureHasher = new(Hasher)
+
+// Verifier verifies hashes of a single format.
+type Verifier interface {
+	Identify(hashed string) bool       // Reports whether the hash has this format
+	Compare(hashed, data string) error // Compares hash and data
+}
+
+// Algorithm is a SecureHasher for a single hashing algorithm.
+type Algorithm interface {
+	credentials.SecureHasher
+	Identify(hashed string) bool // Reports whether the hash was made with this algorithm
+}
+
+// Hasher hashes data with the configured algorithm and verifies hashes made with any supported
+// algorithm, so that stored hashes keep working when the algorithm or its parameters change.
+// Formats of imported hashes are only verified, and always need a rehash.
+type Hasher struct {
+	preferred Algorithm
+	verifiers []Verifier
+}
+
+// NewHasher creates a new Hasher preferring the configured algorithm and parameters.
+func NewHasher(cnfg *config.HasherConfig) *Hasher {
+	bcryptHasher := NewBcryptHasher(cnfg.GetBcryptCost())
+	scryptHasher := NewScryptHasher(cnfg.GetScryptLogN(), cnfg.GetScryptR(), cnfg.GetScryptP())
+	argon2idHasher := NewArgon2idHasher(uint32(cnfg.GetArgon2Memory()), uint32(cnfg.GetArgon2Iterations()), uint8(cnfg.GetArgon2Parallelism()))
+
+	var preferred Algorithm
+	switch cnfg.GetAlgorithm() {
+	case config.HashAlgorithmBcrypt:
+		preferred = bcryptHasher
+	case config.HashAlgorithmScrypt:
+		preferred = scryptHasher
+	default:
+		preferred = argon2idHasher
+	}
+
+	verifiers := []Verifier{bcryptHasher, scryptHasher, argon2idHasher, NewPBKDF2Verifier(), NewSaltedSHAVerifier()}
+	if len(cnfg.GetFirebaseSignerKey()) > 0 {
+		verifiers = append(verifiers, NewFirebaseScryptVerifier(cnfg.GetFirebaseSignerKey(), cnfg.GetFirebaseSaltSeparator(), cnfg.GetFirebaseRounds(), cnfg.GetFirebaseMemCost()))
+	}
+
+	return &Hasher{
+		preferred: preferred,
+		verifiers: verifiers,
+	}
+}
+
+// Hash hashes data with the preferred algorithm.
+func (h *Hasher) Hash(data string) (string, error) {
+	return h.preferred.Hash(data)
+}
+
+// Compare verifies data with the algorithm the hash was made with. It returns
+// credentials.ErrVerifyPassword for hashes of unknown algorithms.
+func (h *Hasher) Compare(hashed, data string) error {
+	for _, verifier := range h.verifiers {
+		if verifier.Identify(hashed) {
+			return verifier.Compare(hashed, data)
+		}
+	}
+	return credentials.ErrVerifyPassword
+}
+
+// NeedsRehash reports whether the hash wasn't made with the preferred algorithm and parameters.
+func (h *Hasher) NeedsRehash(hashed string) bool {
+	return !h.preferred.Identify(hashed) || h.preferred.NeedsRehash(hashed)
+}
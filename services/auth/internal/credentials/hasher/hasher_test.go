@@ -0,0 +1,92 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+)
+
+func TestScryptHasher(t *testing.T) {
+	hasher := NewScryptHasher(10, 8, 1)
+
+	hashed, err := hasher.Hash("correct horse")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.HasPrefix(hashed, "$scrypt$ln=10,r=8,p=1$"), true)
+	assert.Equal(t, hasher.Identify(hashed), true)
+
+	assert.Equal(t, hasher.Compare(hashed, "correct horse"), nil)
+	assert.Equal(t, hasher.Compare(hashed, "battery staple"), credentials.ErrInvalidPass)
+	assert.Equal(t, hasher.Compare("$scrypt$ln=10$c2FsdA$aGFzaA", "correct horse"), credentials.ErrVerifyPassword)
+
+	assert.Equal(t, hasher.NeedsRehash(hashed), false)
+	assert.Equal(t, NewScryptHasher(11, 8, 1).NeedsRehash(hashed), true)
+
+	// Hashes are verified with the parameters they were made with
+	assert.Equal(t, NewScryptHasher(11, 8, 1).Compare(hashed, "correct horse"), nil)
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := NewArgon2idHasher(64, 1, 1)
+
+	hashed, err := hasher.Hash("correct horse")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=64,t=1,p=1$"), true)
+	assert.Equal(t, hasher.Identify(hashed), true)
+
+	assert.Equal(t, hasher.Compare(hashed, "correct horse"), nil)
+	assert.Equal(t, hasher.Compare(hashed, "battery staple"), credentials.ErrInvalidPass)
+	assert.Equal(t, hasher.Compare("$argon2id$v=19$m=64,t=1$c2FsdA$aGFzaA", "correct horse"), credentials.ErrVerifyPassword)
+
+	assert.Equal(t, hasher.NeedsRehash(hashed), false)
+	assert.Equal(t, NewArgon2idHasher(128, 1, 1).NeedsRehash(hashed), true)
+	assert.Equal(t, NewArgon2idHasher(64, 2, 1).NeedsRehash(hashed), true)
+	assert.Equal(t, NewArgon2idHasher(64, 2, 1).Compare(hashed, "correct horse"), nil)
+}
+
+// TestArgon2idHasher_Reference checks a hash made by the argon2 reference implementation.
+func TestArgon2idHasher_Reference(t *testing.T) {
+	hashed := "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+
+	assert.Equal(t, NewArgon2idHasher(64, 1, 1).Compare(hashed, "password"), nil)
+}
+
+func TestHasher(t *testing.T) {
+	t.Setenv("OAUTH_HASH_ALGORITHM", config.HashAlgorithmScrypt)
+	t.Setenv("OAUTH_SCRYPT_LOG_N", "10")
+	t.Setenv("OAUTH_BCRYPT_COST", "4")
+	cnfg, err := config.NewHasherConfig()
+	assert.Equal(t, err, nil)
+	hasher := NewHasher(cnfg)
+
+	hashed, err := hasher.Hash("correct horse")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.HasPrefix(hashed, "$scrypt$"), true)
+	assert.Equal(t, hasher.NeedsRehash(hashed), false)
+
+	// Hashes of the other algorithms are verified, but need to be upgraded
+	bcryptHashed, err := NewBcryptHasher(4).Hash("correct horse")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, hasher.Compare(bcryptHashed, "correct horse"), nil)
+	assert.Equal(t, hasher.Compare(bcryptHashed, "battery staple"), credentials.ErrInvalidPass)
+	assert.Equal(t, hasher.NeedsRehash(bcryptHashed), true)
+
+	assert.Equal(t, hasher.Compare("not a hash", "correct horse"), credentials.ErrVerifyPassword)
+}
+
+func TestParsePHC(t *testing.T) {
+	p, err := parsePHC("$argon2id$v=19$m=64,t=1,p=2$c2FsdA$aGFzaA")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, p.id, "argon2id")
+	assert.Equal(t, p.version, 19)
+	assert.Equal(t, string(p.salt), "salt")
+	assert.Equal(t, string(p.hash), "hash")
+	assert.Equal(t, p.String(), "$argon2id$v=19$m=64,t=1,p=2$c2FsdA$aGFzaA")
+
+	for _, malformed := range []string{"", "argon2id$c2FsdA$aGFzaA", "$argon2id$m=x$c2FsdA$aGFzaA", "$argon2id$c2FsdA$", "$argon2id$m=1$t=1$c2FsdA$aGFzaA"} {
+		_, err := parsePHC(malformed)
+		assert.Equal(t, err, errMalformedHash)
+	}
+}
@@ -0,0 +1,103 @@
+package hasher
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const pbkdf2SHA256Prefix = "pbkdf2_sha256$"
+
+// Ensure PBKDF2Verifier implements the Verifier interface.
+var _ Verifier = new(PBKDF2Verifier)
+
+// PBKDF2Verifier verifies PBKDF2-SHA256 hashes in Django's format,
+// pbkdf2_sha256$<iterations>$<salt>$<base64 hash>.
+type PBKDF2Verifier struct{}
+
+// NewPBKDF2Verifier creates a new PBKDF2Verifier.
+func NewPBKDF2Verifier() *PBKDF2Verifier {
+	return &PBKDF2Verifier{}
+}
+
+// Compare returns credentials.ErrInvalidPass if data doesn't match the hash.
+func (v *PBKDF2Verifier) Compare(hashed, data string) error {
+	parts := strings.Split(strings.TrimPrefix(hashed, pbkdf2SHA256Prefix), "$")
+	if len(parts) != 3 {
+		return credentials.ErrVerifyPassword
+	}
+
+	iterations, err := strconv.Atoi(parts[0])
+	if err != nil || iterations < 1 {
+		return credentials.ErrVerifyPassword
+	}
+	expected, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil || len(expected) == 0 {
+		return credentials.ErrVerifyPassword
+	}
+
+	key := pbkdf2.Key([]byte(data), []byte(parts[1]), iterations, len(expected), sha256.New)
+	if subtle.ConstantTimeCompare(key, expected) != 1 {
+		return credentials.ErrInvalidPass
+	}
+	return nil
+}
+
+// Identify reports whether the hash is a Django PBKDF2-SHA256 hash.
+func (v *PBKDF2Verifier) Identify(hashed string) bool {
+	return strings.HasPrefix(hashed, pbkdf2SHA256Prefix)
+}
+
+// Ensure SaltedSHAVerifier implements the Verifier interface.
+var _ Verifier = new(SaltedSHAVerifier)
+
+// SaltedSHAVerifier verifies salted SHA-1 and SHA-256 hashes of the salt followed by the data,
+// formatted as sha1$<salt>$<hex hash> or sha256$<salt>$<hex hash>.
+type SaltedSHAVerifier struct{}
+
+// NewSaltedSHAVerifier creates a new SaltedSHAVerifier.
+func NewSaltedSHAVerifier() *SaltedSHAVerifier {
+	return &SaltedSHAVerifier{}
+}
+
+// Compare returns credentials.ErrInvalidPass if data doesn't match the hash.
+func (v *SaltedSHAVerifier) Compare(hashed, data string) error {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 3 {
+		return credentials.ErrVerifyPassword
+	}
+
+	var h hash.Hash
+	switch parts[0] {
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return credentials.ErrVerifyPassword
+	}
+
+	expected, err := hex.DecodeString(parts[2])
+	if err != nil || len(expected) != h.Size() {
+		return credentials.ErrVerifyPassword
+	}
+
+	h.Write([]byte(parts[1] + data))
+	if subtle.ConstantTimeCompare(h.Sum(nil), expected) != 1 {
+		return credentials.ErrInvalidPass
+	}
+	return nil
+}
+
+// Identify reports whether the hash is a salted SHA-1 or SHA-256 hash.
+func (v *SaltedSHAVerifier) Identify(hashed string) bool {
+	return strings.HasPrefix(hashed, "sha1$") || strings.HasPrefix(hashed, "sha256$")
+}
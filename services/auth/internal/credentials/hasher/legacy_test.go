@@ -0,0 +1,71 @@
+package hasher
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+)
+
+func TestPBKDF2Verifier(t *testing.T) {
+	verifier := NewPBKDF2Verifier()
+	hashed := "pbkdf2_sha256$1000$seasalt$mQnueSakb748zqBAC1tmWVZsZbi2zPGZarEzTGdfmso="
+
+	assert.Equal(t, verifier.Identify(hashed), true)
+	assert.Equal(t, verifier.Compare(hashed, "correct horse"), nil)
+	assert.Equal(t, verifier.Compare(hashed, "battery staple"), credentials.ErrInvalidPass)
+	assert.Equal(t, verifier.Compare("pbkdf2_sha256$many$seasalt$mQnue", "correct horse"), credentials.ErrVerifyPassword)
+}
+
+func TestSaltedSHAVerifier(t *testing.T) {
+	verifier := NewSaltedSHAVerifier()
+	hashes := []string{
+		"sha1$seasalt$55eef163bf2e349b9946e3183eef620dde0247f5",
+		"sha256$seasalt$0fbd1a7f60f4463cf2c01eb76673cae75eefb5801679676022562d1494601e5b",
+	}
+
+	for _, hashed := range hashes {
+		assert.Equal(t, verifier.Identify(hashed), true)
+		assert.Equal(t, verifier.Compare(hashed, "correct horse"), nil)
+		assert.Equal(t, verifier.Compare(hashed, "battery staple"), credentials.ErrInvalidPass)
+	}
+	assert.Equal(t, verifier.Compare("sha1$seasalt$55eef163", "correct horse"), credentials.ErrVerifyPassword)
+}
+
+// TestFirebaseScryptVerifier checks the sample hash of Firebase's scrypt documentation.
+func TestFirebaseScryptVerifier(t *testing.T) {
+	signerKey, _ := base64.StdEncoding.DecodeString("jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA==")
+	saltSeparator, _ := base64.StdEncoding.DecodeString("Bw==")
+	verifier := NewFirebaseScryptVerifier(signerKey, saltSeparator, 8, 14)
+	hashed := "firebase_scrypt$42xEC+ixf3L2lw==$lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ=="
+
+	assert.Equal(t, verifier.Identify(hashed), true)
+	assert.Equal(t, verifier.Compare(hashed, "user1password"), nil)
+	assert.Equal(t, verifier.Compare(hashed, "user2password"), credentials.ErrInvalidPass)
+}
+
+func TestHasher_Legacy(t *testing.T) {
+	t.Setenv("OAUTH_HASH_ALGORITHM", config.HashAlgorithmBcrypt)
+	cnfg, err := config.NewHasherConfig()
+	assert.Equal(t, err, nil)
+	hasher := NewHasher(cnfg)
+
+	// Imported hashes are verified, but always upgraded
+	hashed := "pbkdf2_sha256$1000$seasalt$mQnueSakb748zqBAC1tmWVZsZbi2zPGZarEzTGdfmso="
+	assert.Equal(t, hasher.Compare(hashed, "correct horse"), nil)
+	assert.Equal(t, hasher.NeedsRehash(hashed), true)
+
+	// Firebase hashes can't be verified without the project's parameters
+	hashed = "firebase_scrypt$42xEC+ixf3L2lw==$lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ=="
+	assert.Equal(t, hasher.Compare(hashed, "user1password"), credentials.ErrVerifyPassword)
+
+	t.Setenv("OAUTH_FIREBASE_SIGNER_KEY", "jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA==")
+	t.Setenv("OAUTH_FIREBASE_SALT_SEPARATOR", "Bw==")
+	cnfg, err = config.NewHasherConfig()
+	assert.Equal(t, err, nil)
+	hasher = NewHasher(cnfg)
+	assert.Equal(t, hasher.Compare(hashed, "user1password"), nil)
+	assert.Equal(t, hasher.NeedsRehash(hashed), true)
+}
@@ -0,0 +1,107 @@
+package hasher
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errMalformedHash = errors.New("malformed hash")
+
+// phcEncoding encodes salts and hashes in PHC strings.
+var phcEncoding = base64.RawStdEncoding
+
+// param is a named numeric parameter of a PHC string.
+type param struct {
+	name  string
+	value int
+}
+
+// phc is a hash in the PHC string format, $id[$v=version][$name=value,...]$salt$hash, which carries the
+// algorithm and parameters it was made with.
+type phc struct {
+	id      string
+	version int // 0 if the algorithm isn't versioned
+	params  []param
+	salt    []byte
+	hash    []byte
+}
+
+// parsePHC parses a PHC string with a salt and a hash.
+func parsePHC(s string) (*phc, error) {
+	parts := strings.Split(s, "$")
+	if len(parts) < 4 || len(parts[0]) > 0 || len(parts[1]) == 0 {
+		return nil, errMalformedHash
+	}
+	p := &phc{id: parts[1]}
+	fields := parts[2 : len(parts)-2]
+
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "v=") {
+		version, err := strconv.Atoi(strings.TrimPrefix(fields[0], "v="))
+		if err != nil {
+			return nil, errMalformedHash
+		}
+		p.version = version
+		fields = fields[1:]
+	}
+
+	if len(fields) > 1 {
+		return nil, errMalformedHash
+	}
+	if len(fields) == 1 {
+		for _, field := range strings.Split(fields[0], ",") {
+			name, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, errMalformedHash
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errMalformedHash
+			}
+			p.params = append(p.params, param{name, n})
+		}
+	}
+
+	var err error
+	if p.salt, err = phcEncoding.DecodeString(parts[len(parts)-2]); err != nil {
+		return nil, errMalformedHash
+	}
+	if p.hash, err = phcEncoding.DecodeString(parts[len(parts)-1]); err != nil || len(p.hash) == 0 {
+		return nil, errMalformedHash
+	}
+	return p, nil
+}
+
+// param returns the value of the named parameter, or an error if it's missing or not positive.
+func (p *phc) param(name string) (int, error) {
+	for _, param := range p.params {
+		if param.name == name {
+			if param.value < 1 {
+				return 0, errMalformedHash
+			}
+			return param.value, nil
+		}
+	}
+	return 0, errMalformedHash
+}
+
+// String encodes the hash as a PHC string.
+func (p *phc) String() string {
+	var b strings.Builder
+	b.WriteString("$" + p.id)
+	if p.version > 0 {
+		b.WriteString("$v=" + strconv.Itoa(p.version))
+	}
+	for i, param := range p.params {
+		if i == 0 {
+			b.WriteString("$")
+		} else {
+			b.WriteString(",")
+		}
+		b.WriteString(param.name + "=" + strconv.Itoa(param.value))
+	}
+	b.WriteString("$" + phcEncoding.EncodeToString(p.salt))
+	b.WriteString("$" + phcEncoding.EncodeToString(p.hash))
+	return b.String()
+}
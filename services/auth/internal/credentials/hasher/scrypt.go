@@ -0,0 +1,101 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	scryptID = "scrypt"
+	saltSize = 16
+	keySize  = 32
+)
+
+// Ensure ScryptHasher implements the Algorithm interface.
+var _ Algorithm = new(ScryptHasher)
+
+// ScryptHasher hashes data with scrypt, encoded as $scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>.
+type ScryptHasher struct {
+	logN, r, p int
+}
+
+// NewScryptHasher creates a new ScryptHasher with a cost parameter N of 2^logN.
+func NewScryptHasher(logN, r, p int) *ScryptHasher {
+	return &ScryptHasher{
+		logN: logN,
+		r:    r,
+		p:    p,
+	}
+}
+
+// Hash returns the scrypt hash of data with a random salt.
+func (h *ScryptHasher) Hash(data string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", credentials.ErrHashPassword
+	}
+
+	hashed, err := scrypt.Key([]byte(data), salt, 1<<h.logN, h.r, h.p, keySize)
+	if err != nil {
+		return "", credentials.ErrHashPassword
+	}
+
+	return (&phc{
+		id:     scryptID,
+		params: []param{{"ln", h.logN}, {"r", h.r}, {"p", h.p}},
+		salt:   salt,
+		hash:   hashed,
+	}).String(), nil
+}
+
+// Compare returns credentials.ErrInvalidPass if data doesn't match the hash.
+func (h *ScryptHasher) Compare(hashed, data string) error {
+	p, logN, r, parallelism, err := parseScrypt(hashed)
+	if err != nil {
+		return credentials.ErrVerifyPassword
+	}
+
+	key, err := scrypt.Key([]byte(data), p.salt, 1<<logN, r, parallelism, len(p.hash))
+	if err != nil {
+		return credentials.ErrVerifyPassword
+	}
+	if subtle.ConstantTimeCompare(key, p.hash) != 1 {
+		return credentials.ErrInvalidPass
+	}
+	return nil
+}
+
+// NeedsRehash reports whether the hash wasn't made with scrypt and the hasher's parameters.
+func (h *ScryptHasher) NeedsRehash(hashed string) bool {
+	p, logN, r, parallelism, err := parseScrypt(hashed)
+	return err != nil || logN != h.logN || r != h.r || parallelism != h.p || len(p.hash) != keySize
+}
+
+// Identify reports whether the hash was made with scrypt.
+func (h *ScryptHasher) Identify(hashed string) bool {
+	return strings.HasPrefix(hashed, "$"+scryptID+"$")
+}
+
+func parseScrypt(hashed string) (p *phc, logN, r, parallelism int, err error) {
+	p, err = parsePHC(hashed)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	if p.id != scryptID {
+		return nil, 0, 0, 0, errMalformedHash
+	}
+	if logN, err = p.param("ln"); err != nil || logN > 62 {
+		return nil, 0, 0, 0, errMalformedHash
+	}
+	if r, err = p.param("r"); err != nil {
+		return nil, 0, 0, 0, err
+	}
+	if parallelism, err = p.param("p"); err != nil {
+		return nil, 0, 0, 0, err
+	}
+	return p, logN, r, parallelism, nil
+}
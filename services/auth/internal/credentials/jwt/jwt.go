@@ -2,12 +2,18 @@ package jwt
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"github.com/ramyadmz/goauth/internal/config"
 	cred "github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/data"
+	"github.com/sirupsen/logrus"
 )
 
 // Compile time check for TokenHandler interface satisfaction.
@@ -15,30 +21,90 @@ var _ cred.TokenHandler = new(JWTHandler)
 
 // JWTHandler manages JSON Web Token operations
 type JWTHandler struct {
-	config *config.JWTConfig // JWT configuration
+	config           *config.JWTConfig          // JWT configuration
+	dal              data.DataProvider          // Data access layer, holds the revocation denylist
+	keys             cred.KeyStore              // rotating signing keys, nil when only the configured key is used
+	signingKey       *verificationKey           // configured key new tokens are signed with
+	verificationKeys map[string]verificationKey // configured keys tokens are verified with, by kid
+}
+
+// verificationKey pairs a key with the signing method it is used with.
+type verificationKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{} // HMAC secret or private key
+	verify interface{} // HMAC secret or public key
 }
 
 // NewJWTHandler creates a new instance of JWTHandler
-func NewJWTHandler(cnfg *config.JWTConfig) *JWTHandler {
-	return &JWTHandler{
-		config: cnfg,
+func NewJWTHandler(cnfg *config.JWTConfig, dataProvider data.DataProvider) *JWTHandler {
+	handler := &JWTHandler{
+		config:           cnfg,
+		dal:              dataProvider,
+		verificationKeys: make(map[string]verificationKey),
+	}
+
+	key := verificationKey{
+		id:     cnfg.GetKeyID(),
+		method: jwt.GetSigningMethod(cnfg.GetAlgorithm()),
 	}
+	switch {
+	case cnfg.GetPrivateKey() != nil:
+		key.sign, key.verify = cnfg.GetPrivateKey(), cnfg.GetPrivateKey().Public()
+	case len(cnfg.GetSecret()) > 0:
+		key.sign, key.verify = []byte(cnfg.GetSecret()), []byte(cnfg.GetSecret())
+		// Shared secret tokens issued before kid headers were introduced carry no kid.
+		handler.verificationKeys[""] = key
+	default:
+		// Asymmetric keys are expected to come from a key store.
+		return handler
+	}
+
+	handler.signingKey = &key
+	handler.verificationKeys[key.id] = key
+	return handler
+}
+
+// NewJWTHandlerWithKeyStore creates a JWTHandler that signs with the key store's active key. Tokens
+// signed with the configured key, if any, keep verifying.
+func NewJWTHandlerWithKeyStore(cnfg *config.JWTConfig, dataProvider data.DataProvider, keys cred.KeyStore) *JWTHandler {
+	handler := NewJWTHandler(cnfg, dataProvider)
+	handler.keys = keys
+	return handler
 }
 
 type JWTClaims struct {
-	UserID    int64
-	Issuer    string
-	IssuedAt  int64
-	ExpiresAt int64
+	ID          string   `json:"jti"`
+	GrantID     string   `json:"gid,omitempty"`
+	Subject     string   `json:"sub"`
+	SubjectType string   `json:"sub_type"`
+	ClientID    int64    `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	TokenType   string   `json:"token_type"`
+	Issuer      string   `json:"iss"`
+	Audience    string   `json:"aud"`
+	IssuedAt    int64    `json:"iat"`
+	ExpiresAt   int64    `json:"exp"`
 }
 
 // Valid validates the JWT claims.
 func (j JWTClaims) Valid() error {
 	vErr := new(jwt.ValidationError)
-	if j.UserID <= 0 {
+	if len(j.ID) == 0 {
+		vErr.Errors |= jwt.ValidationErrorId
+	}
+
+	if id, err := strconv.ParseInt(j.Subject, 10, 64); err != nil || id <= 0 {
 		vErr.Errors |= jwt.ValidationErrorId
 	}
 
+	switch cred.SubjectType(j.SubjectType) {
+	case cred.UserSubject, cred.ClientSubject:
+	default:
+		vErr.Errors |= jwt.ValidationErrorClaimsInvalid
+	}
+
 	if j.IssuedAt > time.Now().Unix() {
 		vErr.Errors |= jwt.ValidationErrorIssuedAt
 	}
@@ -55,7 +121,41 @@ func (j JWTClaims) Valid() error {
 	return nil
 }
 
-// Generate takes a 'subject' and 'tokenType' as input and generates a new JSON Web Token
+// IDTokenClaims are the claims of an OpenID Connect ID token (OIDC Core section 2).
+type IDTokenClaims struct {
+	Issuer            string `json:"iss"`
+	Subject           string `json:"sub"`
+	Audience          string `json:"aud"`
+	IssuedAt          int64  `json:"iat"`
+	ExpiresAt         int64  `json:"exp"`
+	AuthTime          int64  `json:"auth_time,omitempty"`
+	Nonce             string `json:"nonce,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+}
+
+// Valid validates the ID token claims.
+func (i IDTokenClaims) Valid() error {
+	vErr := new(jwt.ValidationError)
+	if len(i.Subject) == 0 || len(i.Audience) == 0 {
+		vErr.Errors |= jwt.ValidationErrorClaimsInvalid
+	}
+
+	if i.ExpiresAt <= time.Now().Unix() {
+		vErr.Errors |= jwt.ValidationErrorExpired
+	}
+
+	if vErr.Errors > 0 {
+		vErr.Inner = cred.ErrInvalidClaims
+		return vErr
+	}
+
+	return nil
+}
+
+// Generate takes a 'subject' and 'tokenType' as input and generates a new JSON Web Token.
+// The subject is either a user id (int64) or a cred.Subject for tokens that carry client and scope information.
 func (j *JWTHandler) Generate(ctx context.Context, subject interface{}, tokenType cred.TokenType) (string, error) {
 	var expirationTime time.Duration
 
@@ -68,44 +168,169 @@ func (j *JWTHandler) Generate(ctx context.Context, subject interface{}, tokenTyp
 		return "", fmt.Errorf("invalid token type: %v", tokenType)
 	}
 
+	var sub cred.Subject
+	switch s := subject.(type) {
+	case int64:
+		sub = cred.Subject{Type: cred.UserSubject, ID: s}
+	case cred.Subject:
+		sub = s
+	default:
+		return "", fmt.Errorf("%w: unsupported subject type %T", cred.ErrInvalidClaims, subject)
+	}
+
 	// Define the claims for the token
 	jwtClaims := JWTClaims{
-		UserID:    subject.(int64),
-		Issuer:    j.config.GetIssuer(),
-		IssuedAt:  time.Now().Unix(),
-		ExpiresAt: jwt.TimeFunc().Add(expirationTime).Unix(),
+		ID:          uuid.NewString(),
+		GrantID:     sub.GrantID,
+		Subject:     strconv.FormatInt(sub.ID, 10),
+		SubjectType: string(sub.Type),
+		ClientID:    sub.ClientID,
+		Scope:       sub.Scope,
+		Roles:       sub.Roles,
+		TokenType:   string(tokenType),
+		Issuer:      j.config.GetIssuer(),
+		Audience:    j.config.GetAudience(),
+		IssuedAt:    time.Now().Unix(),
+		ExpiresAt:   jwt.TimeFunc().Add(expirationTime).Unix(),
 	}
 
 	if err := jwtClaims.Valid(); err != nil {
 		return "", err
 	}
 
-	// Create a new JWT token with the claims
-	token := jwt.NewWithClaims(jwt.GetSigningMethod(j.config.GetAlgorithm()), jwtClaims)
+	return j.sign(jwtClaims)
+}
 
-	// Sign the token and return it
-	signedToken, err := token.SignedString([]byte(j.config.GetSecret()))
-	if err != nil {
-		return "", fmt.Errorf("%w: %w", cred.ErrGenerateToken, err)
+// GenerateIDToken signs an OpenID Connect ID token for the identity. ID tokens share the access token
+// lifetime and are addressed to the client they are issued to.
+func (j *JWTHandler) GenerateIDToken(ctx context.Context, identity cred.Identity) (string, error) {
+	if !j.SupportsIDTokens() {
+		return "", fmt.Errorf("%w: %w", cred.ErrGenerateToken, cred.ErrSharedSecret)
 	}
 
-	return signedToken, nil
+	idClaims := IDTokenClaims{
+		Issuer:            j.config.GetIssuer(),
+		Subject:           strconv.FormatInt(identity.Subject, 10),
+		Audience:          strconv.FormatInt(identity.ClientID, 10),
+		IssuedAt:          time.Now().Unix(),
+		ExpiresAt:         jwt.TimeFunc().Add(j.config.GetExpirationTime()).Unix(),
+		Nonce:             identity.Nonce,
+		PreferredUsername: identity.PreferredUsername,
+		Email:             identity.Email,
+		EmailVerified:     identity.EmailVerified,
+	}
+	if !identity.AuthTime.IsZero() {
+		idClaims.AuthTime = identity.AuthTime.Unix()
+	}
+
+	if err := idClaims.Valid(); err != nil {
+		return "", err
+	}
+
+	return j.sign(idClaims)
 }
 
-// Validate validates a provided token string.
+// SupportsIDTokens reports whether ID tokens can be issued. Clients verify ID tokens themselves, so
+// they are only signed with asymmetric keys published in the key set, never with the shared secret.
+func (j *JWTHandler) SupportsIDTokens() bool {
+	return !strings.HasPrefix(j.config.GetAlgorithm(), "HS")
+}
+
+// Validate validates a provided token string and checks it against the revocation denylist.
 func (js *JWTHandler) Validate(ctx context.Context, token string) (*cred.Claims, error) {
-	claims := &JWTClaims{}
+	parsedClaims, err := js.parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := js.dal.IsTokenRevoked(ctx, parsedClaims.ID, parsedClaims.GrantID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", cred.ErrValidateToken, err)
+	}
+	if revoked {
+		return nil, cred.ErrInvalidToken
+	}
+
+	// Subject has already been checked by JWTClaims.Valid
+	subject, _ := strconv.ParseInt(parsedClaims.Subject, 10, 64)
+
+	return &cred.Claims{
+		ID:          parsedClaims.ID,
+		GrantID:     parsedClaims.GrantID,
+		Subject:     subject,
+		SubjectType: cred.SubjectType(parsedClaims.SubjectType),
+		ClientID:    parsedClaims.ClientID,
+		Scope:       parsedClaims.Scope,
+		Roles:       parsedClaims.Roles,
+		TokenType:   cred.TokenType(parsedClaims.TokenType),
+		Issuer:      parsedClaims.Issuer,
+		Audience:    parsedClaims.Audience,
+		IssuedAt:    time.Unix(parsedClaims.IssuedAt, 0),
+		ExpiresAt:   time.Unix(parsedClaims.ExpiresAt, 0),
+	}, nil
+}
 
-	jwtToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		if token.Method != jwt.GetSigningMethod(js.config.GetAlgorithm()) {
-			return nil, fmt.Errorf("%w: %v", cred.ErrSigningMethod, token.Method.Alg())
+// Invalidate revokes the token. Refresh tokens revoke their whole token family, which also covers the
+// later refresh tokens of the family and every access token minted from it, however long they live.
+// Other tokens are added to the revocation denylist until they expire. Tokens that have already
+// expired are left alone.
+func (js *JWTHandler) Invalidate(ctx context.Context, token string) error {
+	claims, err := js.parse(token)
+	if err != nil {
+		if errors.Is(err, cred.ErrInvalidToken) {
+			if expired := js.expiredClaims(token); expired {
+				return nil
+			}
+		}
+		return err
+	}
+
+	if cred.TokenType(claims.TokenType) == cred.RefreshToken && len(claims.GrantID) > 0 {
+		if err := js.dal.RevokeTokenFamily(ctx, claims.GrantID); err != nil {
+			return fmt.Errorf("%w: %w", cred.ErrRevokeToken, err)
 		}
-		return []byte(js.config.GetSecret()), nil
+		return nil
+	}
+
+	err = js.dal.RevokeToken(ctx, data.RevokeTokenParams{
+		TokenID:   claims.ID,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
 	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", cred.ErrRevokeToken, err)
+	}
+	return nil
+}
+
+// CleanupRevoked periodically removes denylist entries of tokens that have expired on their own,
+// until ctx is cancelled.
+func (js *JWTHandler) CleanupRevoked(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := js.dal.DeleteExpiredRevokedTokens(ctx); err != nil {
+				logrus.WithContext(ctx).Error("error cleaning up revoked tokens: %w", err)
+			}
+		}
+	}
+}
+
+// parse verifies the token signature and claims.
+func (js *JWTHandler) parse(token string) (*JWTClaims, error) {
+	claims := &JWTClaims{}
 
+	jwtToken, err := jwt.ParseWithClaims(token, claims, js.keyFunc)
 	if err != nil {
 		if ve, ok := err.(*jwt.ValidationError); ok {
-			if ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet|jwt.ValidationErrorId) != 0 {
+			if ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet|jwt.ValidationErrorId|jwt.ValidationErrorClaimsInvalid) != 0 {
+				return nil, cred.ErrInvalidToken
+			}
+			if errors.Is(ve.Inner, cred.ErrUnknownKey) {
 				return nil, cred.ErrInvalidToken
 			}
 		}
@@ -118,13 +343,102 @@ func (js *JWTHandler) Validate(ctx context.Context, token string) (*cred.Claims,
 		return nil, cred.ErrInvalidToken
 	}
 
-	return &cred.Claims{
-		Subject:   parsedClaims.UserID,
-		ExpiresAt: time.Unix(parsedClaims.ExpiresAt, 0),
-	}, nil
+	return parsedClaims, nil
 }
 
-// InvalidateToken validates a provided token string.
-func (js *JWTHandler) Invalidate(ctx context.Context, tokenString string) error {
-	panic("implement me")
+// expiredClaims reports whether the token has a valid signature but has expired.
+func (js *JWTHandler) expiredClaims(token string) bool {
+	_, err := jwt.ParseWithClaims(token, &JWTClaims{}, js.keyFunc)
+	ve, ok := err.(*jwt.ValidationError)
+	return ok && ve.Errors == jwt.ValidationErrorExpired
+}
+
+// KeySet returns the public keys tokens issued by this handler can be verified with as a JWK set.
+// Shared secrets are never published.
+func (js *JWTHandler) KeySet(ctx context.Context) (*cred.JWKSet, error) {
+	keySet := &cred.JWKSet{Keys: []cred.JWK{}}
+
+	if js.keys != nil {
+		for _, key := range js.keys.PublicKeys() {
+			jwk, err := cred.NewJWK(key.ID, key.Algorithm, key.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+			keySet.Keys = append(keySet.Keys, jwk)
+		}
+	}
+
+	if js.signingKey != nil && js.config.GetPrivateKey() != nil {
+		jwk, err := cred.NewJWK(js.signingKey.id, js.signingKey.method.Alg(), js.signingKey.verify)
+		if err != nil {
+			return nil, err
+		}
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+
+	return keySet, nil
+}
+
+// currentSigningKey returns the key new tokens are signed with, preferring the key store.
+func (js *JWTHandler) currentSigningKey() (*verificationKey, error) {
+	if js.keys != nil {
+		key, err := js.keys.SigningKey()
+		if err != nil {
+			return nil, err
+		}
+		return &verificationKey{
+			id:     key.ID,
+			method: jwt.GetSigningMethod(key.Algorithm),
+			sign:   key.PrivateKey,
+			verify: key.PublicKey,
+		}, nil
+	}
+
+	if js.signingKey == nil {
+		return nil, cred.ErrNoSigningKey
+	}
+	return js.signingKey, nil
+}
+
+// sign signs the claims with the current signing key and stamps its kid into the header.
+func (js *JWTHandler) sign(claims jwt.Claims) (string, error) {
+	key, err := js.currentSigningKey()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", cred.ErrGenerateToken, err)
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	if len(key.id) > 0 {
+		token.Header["kid"] = key.id
+	}
+
+	signedToken, err := token.SignedString(key.sign)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", cred.ErrGenerateToken, err)
+	}
+
+	return signedToken, nil
+}
+
+// keyFunc selects the verification key named by the token's kid header.
+func (js *JWTHandler) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := js.verificationKeys[kid]
+	if !ok && js.keys != nil {
+		if stored, err := js.keys.VerificationKey(kid); err == nil {
+			key, ok = verificationKey{
+				id:     stored.ID,
+				method: jwt.GetSigningMethod(stored.Algorithm),
+				verify: stored.PublicKey,
+			}, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", cred.ErrUnknownKey, kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("%w: %v", cred.ErrSigningMethod, token.Method.Alg())
+	}
+	return key.verify, nil
 }
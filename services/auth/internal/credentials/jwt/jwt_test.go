@@ -2,18 +2,39 @@ package jwt
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/go-playground/assert/v2"
+	gojwt "github.com/golang-jwt/jwt"
 	"github.com/ramyadmz/goauth/integration"
 	"github.com/ramyadmz/goauth/internal/config"
 	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/keys"
+	"github.com/ramyadmz/goauth/internal/data"
+	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
+	"github.com/stretchr/testify/mock"
 )
 
+// newMockDAL returns a data provider with an empty revocation denylist.
+func newMockDAL() *dalMock.DataProvider {
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("IsTokenRevoked", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+	return mockDAL
+}
+
 func TestGenerate_Success(t *testing.T) {
 	integration.SetUpLocalTestEnvs()
 	defer integration.UnSetLocalTestEnvs()
@@ -24,7 +45,7 @@ func TestGenerate_Success(t *testing.T) {
 		t.Fatalf("invalid jwt config: %s", err)
 	}
 
-	jwtHandler := NewJWTHandler(config)
+	jwtHandler := NewJWTHandler(config, newMockDAL())
 	token, err := jwtHandler.Generate(context.Background(), subject, credentials.AccessToken)
 	assert.Equal(t, nil, err)
 	assert.NotEqual(t, 0, len(token))
@@ -40,7 +61,7 @@ func TestGenerate_InvalidClaims(t *testing.T) {
 		t.Fatalf("invalid jwt config: %s", err)
 	}
 
-	jwtHandler := NewJWTHandler(config)
+	jwtHandler := NewJWTHandler(config, newMockDAL())
 	_, err = jwtHandler.Generate(context.Background(), subject, credentials.AccessToken)
 
 	assert.NotEqual(t, nil, err)
@@ -58,7 +79,7 @@ func TestValidate_Success(t *testing.T) {
 	}
 	expiresAt := time.Now().Add(config.GetExpirationTime())
 
-	jwtHandler := NewJWTHandler(config)
+	jwtHandler := NewJWTHandler(config, newMockDAL())
 	token, err := jwtHandler.Generate(context.Background(), subject, credentials.AccessToken)
 	if err != nil {
 		t.Fatalf("generating token failed: %s", err)
@@ -81,7 +102,7 @@ func TestValidate_ExpiredToken(t *testing.T) {
 		t.Fatalf("invalid jwt config: %s", err)
 	}
 
-	jwtHandler := NewJWTHandler(config)
+	jwtHandler := NewJWTHandler(config, newMockDAL())
 	expiredToken, err := jwtHandler.Generate(context.Background(), subject, credentials.AccessToken)
 	if err != nil {
 		t.Fatalf("generating token failed: %s", err)
@@ -93,3 +114,447 @@ func TestValidate_ExpiredToken(t *testing.T) {
 
 	assert.Equal(t, true, errors.Is(err, credentials.ErrInvalidToken))
 }
+
+func TestValidate_ClientSubject(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	clientID := rand.Int63()
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	jwtHandler := NewJWTHandler(config, newMockDAL())
+	token, err := jwtHandler.Generate(context.Background(), credentials.Subject{
+		Type:     credentials.ClientSubject,
+		ID:       clientID,
+		ClientID: clientID,
+		Scope:    "read write",
+	}, credentials.AccessToken)
+	if err != nil {
+		t.Fatalf("generating token failed: %s", err)
+	}
+
+	res, err := jwtHandler.Validate(context.Background(), token)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, clientID, res.Subject)
+	assert.Equal(t, credentials.ClientSubject, res.SubjectType)
+	assert.Equal(t, clientID, res.ClientID)
+	assert.Equal(t, "read write", res.Scope)
+}
+
+func TestValidate_UserRoles(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	jwtHandler := NewJWTHandler(config, newMockDAL())
+	token, err := jwtHandler.Generate(context.Background(), credentials.Subject{
+		Type:     credentials.UserSubject,
+		ID:       rand.Int63(),
+		ClientID: rand.Int63(),
+		Roles:    []string{"admin", "operator"},
+	}, credentials.AccessToken)
+	if err != nil {
+		t.Fatalf("generating token failed: %s", err)
+	}
+
+	res, err := jwtHandler.Validate(context.Background(), token)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"admin", "operator"}, res.Roles)
+}
+
+func TestGenerate_UnsupportedSubject(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	jwtHandler := NewJWTHandler(config, newMockDAL())
+	_, err = jwtHandler.Generate(context.Background(), "user", credentials.AccessToken)
+
+	assert.Equal(t, true, errors.Is(err, credentials.ErrInvalidClaims))
+}
+
+func TestValidate_RevokedToken(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("IsTokenRevoked", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+
+	jwtHandler := NewJWTHandler(config, mockDAL)
+	token, err := jwtHandler.Generate(context.Background(), rand.Int63(), credentials.AccessToken)
+	if err != nil {
+		t.Fatalf("generating token failed: %s", err)
+	}
+
+	_, err = jwtHandler.Validate(context.Background(), token)
+
+	assert.Equal(t, true, errors.Is(err, credentials.ErrInvalidToken))
+}
+
+func TestInvalidate_RefreshTokenRevokesGrant(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("RevokeTokenFamily", mock.Anything, "grant").Return(nil)
+
+	jwtHandler := NewJWTHandler(config, mockDAL)
+	token, err := jwtHandler.Generate(context.Background(), credentials.Subject{
+		Type:     credentials.UserSubject,
+		ID:       rand.Int63(),
+		ClientID: rand.Int63(),
+		GrantID:  "grant",
+	}, credentials.RefreshToken)
+	if err != nil {
+		t.Fatalf("generating token failed: %s", err)
+	}
+
+	err = jwtHandler.Invalidate(context.Background(), token)
+	assert.Equal(t, nil, err)
+
+	// The family stays revoked, unlike a denylist entry that is cleaned up once the token expires
+	mockDAL.AssertCalled(t, "RevokeTokenFamily", mock.Anything, "grant")
+	mockDAL.AssertNotCalled(t, "RevokeToken", mock.Anything, mock.Anything)
+}
+
+func TestInvalidate_AccessTokenKeepsGrant(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	var revoked data.RevokeTokenParams
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("RevokeToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		revoked = args.Get(1).(data.RevokeTokenParams)
+	}).Return(nil)
+
+	jwtHandler := NewJWTHandler(config, mockDAL)
+	token, err := jwtHandler.Generate(context.Background(), credentials.Subject{
+		Type:     credentials.UserSubject,
+		ID:       rand.Int63(),
+		ClientID: rand.Int63(),
+		GrantID:  "grant",
+	}, credentials.AccessToken)
+	if err != nil {
+		t.Fatalf("generating token failed: %s", err)
+	}
+
+	err = jwtHandler.Invalidate(context.Background(), token)
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, "", revoked.TokenID)
+	assert.Equal(t, "", revoked.GrantID)
+}
+
+func TestGenerateIDToken_Success(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	setUpKeyFile(t, "ES256", ecKey)
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	jwtHandler := NewJWTHandler(config, newMockDAL())
+	authTime := time.Now().Add(-5 * time.Minute)
+	emailVerified := true
+	token, err := jwtHandler.GenerateIDToken(context.Background(), credentials.Identity{
+		Subject:       42,
+		ClientID:      7,
+		Nonce:         "n-0S6_WzA2Mj",
+		AuthTime:      authTime,
+		Email:         "user@test.com",
+		EmailVerified: &emailVerified,
+	})
+	assert.Equal(t, nil, err)
+
+	claims := &IDTokenClaims{}
+	_, err = gojwt.ParseWithClaims(token, claims, jwtHandler.keyFunc)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, "7", claims.Audience)
+	assert.Equal(t, "n-0S6_WzA2Mj", claims.Nonce)
+	assert.Equal(t, authTime.Unix(), claims.AuthTime)
+	assert.Equal(t, "user@test.com", claims.Email)
+	assert.Equal(t, true, *claims.EmailVerified)
+	assert.Equal(t, "", claims.PreferredUsername)
+
+	// ID tokens must not be accepted where access tokens are expected.
+	_, err = jwtHandler.Validate(context.Background(), token)
+	assert.Equal(t, credentials.ErrInvalidToken, err)
+}
+
+func TestGenerateIDToken_UnverifiedEmail(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	setUpKeyFile(t, "ES256", ecKey)
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	jwtHandler := NewJWTHandler(config, newMockDAL())
+	emailVerified := false
+	token, err := jwtHandler.GenerateIDToken(context.Background(), credentials.Identity{
+		Subject:       42,
+		ClientID:      7,
+		Email:         "user@test.com",
+		EmailVerified: &emailVerified,
+	})
+	assert.Equal(t, nil, err)
+
+	// The claim is released as false instead of being left out
+	claims := &IDTokenClaims{}
+	_, err = gojwt.ParseWithClaims(token, claims, jwtHandler.keyFunc)
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, nil, claims.EmailVerified)
+	assert.Equal(t, false, *claims.EmailVerified)
+}
+
+func TestGenerateIDToken_SharedSecret(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	jwtHandler := NewJWTHandler(config, newMockDAL())
+	assert.Equal(t, false, jwtHandler.SupportsIDTokens())
+
+	_, err = jwtHandler.GenerateIDToken(context.Background(), credentials.Identity{Subject: 42, ClientID: 7})
+	assert.Equal(t, true, errors.Is(err, credentials.ErrSharedSecret))
+}
+
+// setUpKeyFile writes a PKCS #8 PEM encoded private key to a temporary file and points the
+// JWT configuration at it.
+func setUpKeyFile(t *testing.T, algorithm string, key crypto.Signer) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %s", err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "signing.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write private key: %s", err)
+	}
+
+	t.Setenv("OAUTH_JWT_ALGORITHM", algorithm)
+	t.Setenv("OAUTH_JWT_PRIVATE_KEY_FILE", keyFile)
+}
+
+func TestGenerate_AsymmetricAlgorithms(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(crand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(crand.Reader)
+
+	tests := []struct {
+		algorithm string
+		key       crypto.Signer
+	}{
+		{"RS256", rsaKey},
+		{"ES256", ecKey},
+		{"EdDSA", edKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			integration.SetUpLocalTestEnvs()
+			defer integration.UnSetLocalTestEnvs()
+			setUpKeyFile(t, tt.algorithm, tt.key)
+
+			config, err := config.NewJWTConfig()
+			if err != nil {
+				t.Fatalf("invalid jwt config: %s", err)
+			}
+
+			jwtHandler := NewJWTHandler(config, newMockDAL())
+			subject := rand.Int63()
+			token, err := jwtHandler.Generate(context.Background(), subject, credentials.AccessToken)
+			assert.Equal(t, nil, err)
+
+			parsed, _, err := new(gojwt.Parser).ParseUnverified(token, &JWTClaims{})
+			assert.Equal(t, nil, err)
+			assert.Equal(t, tt.algorithm, parsed.Header["alg"])
+			assert.Equal(t, config.GetKeyID(), parsed.Header["kid"])
+
+			claims, err := jwtHandler.Validate(context.Background(), token)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, subject, claims.Subject)
+		})
+	}
+}
+
+func TestValidate_UnknownKeyID(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, JWTClaims{
+		ID:          "jti",
+		Subject:     "1",
+		SubjectType: string(credentials.UserSubject),
+		TokenType:   string(credentials.AccessToken),
+		ExpiresAt:   time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "unknown"
+	signedToken, _ := token.SignedString([]byte(config.GetSecret()))
+
+	jwtHandler := NewJWTHandler(config, newMockDAL())
+	_, err = jwtHandler.Validate(context.Background(), signedToken)
+	assert.Equal(t, credentials.ErrInvalidToken, err)
+}
+
+func TestValidate_AlgorithmConfusion(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+	rsaKey, _ := rsa.GenerateKey(crand.Reader, 2048)
+	setUpKeyFile(t, "RS256", rsaKey)
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	// A token signed with HS256 using the public key as shared secret must not verify.
+	publicDER, _ := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, JWTClaims{
+		ID:          "jti",
+		Subject:     "1",
+		SubjectType: string(credentials.UserSubject),
+		TokenType:   string(credentials.AccessToken),
+		ExpiresAt:   time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = config.GetKeyID()
+	signedToken, _ := token.SignedString(publicDER)
+
+	jwtHandler := NewJWTHandler(config, newMockDAL())
+	_, err = jwtHandler.Validate(context.Background(), signedToken)
+	assert.NotEqual(t, nil, err)
+}
+
+// storedKey generates a signing key with the given status as the data layer would return it.
+func storedKey(t *testing.T, keyConfig *config.KeyConfig, status data.SigningKeyStatus) *data.SigningKey {
+	privateKey, err := keys.GenerateKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	publicDER, _ := x509.MarshalPKIXPublicKey(privateKey.Public())
+	keyID, _ := config.KeyThumbprint(privateKey.Public())
+
+	encrypted, err := keys.EncryptPrivateKey(keyConfig, keyID, privateDER)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %s", err)
+	}
+
+	return &data.SigningKey{
+		ID:         keyID,
+		Algorithm:  "ES256",
+		PrivateKey: encrypted,
+		PublicKey:  publicDER,
+		Status:     status,
+	}
+}
+
+func TestKeyStore_RotatedKeysKeepVerifying(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+	t.Setenv("OAUTH_JWT_ALGORITHM", "ES256")
+
+	jwtConfig, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+	keyConfig, err := config.NewKeyConfig()
+	if err != nil {
+		t.Fatalf("invalid key config: %s", err)
+	}
+
+	first := storedKey(t, keyConfig, data.SigningKeyActive)
+	second := storedKey(t, keyConfig, data.SigningKeyNext)
+
+	mockDAL := newMockDAL()
+	mockDAL.On("GetSigningKeys", mock.Anything).Return([]*data.SigningKey{first, second}, nil).Once()
+
+	keyManager := keys.NewKeyManager(keyConfig, jwtConfig, mockDAL)
+	if err := keyManager.Refresh(context.Background()); err != nil {
+		t.Fatalf("failed to load keys: %s", err)
+	}
+
+	jwtHandler := NewJWTHandlerWithKeyStore(jwtConfig, mockDAL, keyManager)
+	token, err := jwtHandler.Generate(context.Background(), rand.Int63(), credentials.AccessToken)
+	assert.Equal(t, nil, err)
+
+	parsed, _, _ := new(gojwt.Parser).ParseUnverified(token, &JWTClaims{})
+	assert.Equal(t, first.ID, parsed.Header["kid"])
+
+	keySet, err := jwtHandler.KeySet(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, len(keySet.Keys))
+
+	// Rotate: the first key is retired and the second one becomes active.
+	retired, active := *first, *second
+	retired.Status, active.Status = data.SigningKeyRetired, data.SigningKeyActive
+	mockDAL.On("GetSigningKeys", mock.Anything).Return([]*data.SigningKey{&active, &retired}, nil)
+	if err := keyManager.Refresh(context.Background()); err != nil {
+		t.Fatalf("failed to reload keys: %s", err)
+	}
+
+	_, err = jwtHandler.Validate(context.Background(), token)
+	assert.Equal(t, nil, err)
+
+	rotatedToken, err := jwtHandler.Generate(context.Background(), rand.Int63(), credentials.AccessToken)
+	assert.Equal(t, nil, err)
+	parsed, _, _ = new(gojwt.Parser).ParseUnverified(rotatedToken, &JWTClaims{})
+	assert.Equal(t, second.ID, parsed.Header["kid"])
+}
+
+func TestKeySet_SharedSecretIsNotPublished(t *testing.T) {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	config, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	jwtHandler := NewJWTHandler(config, newMockDAL())
+	keySet, err := jwtHandler.KeySet(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(keySet.Keys))
+}
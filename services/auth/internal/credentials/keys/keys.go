@@ -0,0 +1,328 @@
+// Package keys provides a signing key store that rotates asymmetric JWT signing keys.
+//
+// Keys move through three states: a next key is published ahead of time so verifiers can
+// cache it, becomes the active key on rotation, and is retired on the following rotation.
+// Retired keys stay published until every token they signed has expired. Private keys are
+// encrypted with AES-GCM before they are stored, bound to their key id.
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/data"
+	"github.com/sirupsen/logrus"
+)
+
+// Ensure KeyManager implements the KeyStore interface from the credentials package.
+var _ credentials.KeyStore = new(KeyManager)
+
+// KeyManager persists signing keys through the data layer and keeps an in-memory copy of the
+// keys that are currently published.
+type KeyManager struct {
+	config    *config.KeyConfig
+	algorithm string
+	retention time.Duration // how long a retired key keeps verifying tokens
+	dal       data.DataProvider
+
+	mu     sync.RWMutex
+	active *credentials.SigningKey
+	keys   map[string]*credentials.SigningKey
+}
+
+// NewKeyManager creates a KeyManager for keys of the JWT configuration's algorithm.
+func NewKeyManager(cnfg *config.KeyConfig, jwtConfig *config.JWTConfig, dataProvider data.DataProvider) *KeyManager {
+	// Tokens live at most as long as refresh tokens; instances may keep signing with a retired key
+	// until their next refresh.
+	retention := jwtConfig.GetRefreshExpirationTime()
+	if jwtConfig.GetExpirationTime() > retention {
+		retention = jwtConfig.GetExpirationTime()
+	}
+
+	return &KeyManager{
+		config:    cnfg,
+		algorithm: jwtConfig.GetAlgorithm(),
+		retention: retention + cnfg.GetRefreshInterval(),
+		dal:       dataProvider,
+		keys:      make(map[string]*credentials.SigningKey),
+	}
+}
+
+// Load makes sure an active and a next key exist and loads the published keys.
+func (k *KeyManager) Load(ctx context.Context) error {
+	keys, err := k.dal.GetSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	for _, status := range []data.SigningKeyStatus{data.SigningKeyActive, data.SigningKeyNext} {
+		if hasStatus(keys, status) {
+			continue
+		}
+
+		params, err := k.generate(status)
+		if err != nil {
+			return err
+		}
+
+		// Another instance may have created the key in the meantime, which is fine.
+		if _, err := k.dal.CreateSigningKey(ctx, params); err != nil && err != data.ErrSigningKeyExists {
+			return fmt.Errorf("failed to store signing key: %w", err)
+		}
+	}
+
+	return k.Refresh(ctx)
+}
+
+// Refresh reloads the published keys from the data layer.
+func (k *KeyManager) Refresh(ctx context.Context) error {
+	stored, err := k.dal.GetSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	var active *credentials.SigningKey
+	keys := make(map[string]*credentials.SigningKey, len(stored))
+	for _, s := range stored {
+		key, err := k.decode(s)
+		if err != nil {
+			return err
+		}
+
+		// Only the active key signs; next and retired keys are published for verification.
+		if s.Status != data.SigningKeyActive {
+			key.PrivateKey = nil
+		} else {
+			active = key
+		}
+		keys[key.ID] = key
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.active = active
+	k.keys = keys
+	return nil
+}
+
+// Rotate retires the active key, activates the next key and generates a new next key, provided the
+// active key is older than the rotation interval.
+func (k *KeyManager) Rotate(ctx context.Context) error {
+	next, err := k.generate(data.SigningKeyNext)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rotated, err := k.dal.RotateSigningKeys(ctx, data.RotateSigningKeysParams{
+		ActivatedBefore:  now.Add(-k.config.GetRotationInterval()),
+		RetiredExpiresAt: now.Add(k.retention),
+		Next:             next,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rotate signing keys: %w", err)
+	}
+
+	if rotated {
+		logrus.WithContext(ctx).WithField("nextKeyID", next.ID).Info("signing keys rotated")
+	}
+	return k.Refresh(ctx)
+}
+
+// Run rotates keys when they are due, reloads the published keys and removes expired ones
+// every refresh interval until ctx is done.
+func (k *KeyManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(k.config.GetRefreshInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			logger := logrus.WithContext(ctx)
+			if err := k.Rotate(ctx); err != nil {
+				logger.Error("error rotating signing keys: %w", err)
+			}
+			if err := k.dal.DeleteExpiredSigningKeys(ctx); err != nil {
+				logger.Error("error cleaning up signing keys: %w", err)
+			}
+		}
+	}
+}
+
+// SigningKey returns the active key.
+func (k *KeyManager) SigningKey() (*credentials.SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.active == nil {
+		return nil, credentials.ErrNoSigningKey
+	}
+	return k.active, nil
+}
+
+// VerificationKey returns a published key by its id.
+func (k *KeyManager) VerificationKey(keyID string) (*credentials.SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, credentials.ErrUnknownKey
+	}
+	return key, nil
+}
+
+// PublicKeys returns every published key.
+func (k *KeyManager) PublicKeys() []*credentials.SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]*credentials.SigningKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// generate creates a new key pair for the configured algorithm.
+func (k *KeyManager) generate(status data.SigningKeyStatus) (data.CreateSigningKeyParams, error) {
+	privateKey, err := GenerateKey(k.algorithm)
+	if err != nil {
+		return data.CreateSigningKeyParams{}, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return data.CreateSigningKeyParams{}, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return data.CreateSigningKeyParams{}, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	keyID, err := config.KeyThumbprint(privateKey.Public())
+	if err != nil {
+		return data.CreateSigningKeyParams{}, err
+	}
+
+	encrypted, err := EncryptPrivateKey(k.config, keyID, privateDER)
+	if err != nil {
+		return data.CreateSigningKeyParams{}, err
+	}
+
+	return data.CreateSigningKeyParams{
+		ID:         keyID,
+		Algorithm:  k.algorithm,
+		PrivateKey: encrypted,
+		PublicKey:  publicDER,
+		Status:     status,
+	}, nil
+}
+
+// GenerateKey generates a private key that can sign with the given algorithm.
+func GenerateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ES512":
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case "EdDSA":
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	default:
+		return nil, fmt.Errorf("signing keys can't be generated for %s", algorithm)
+	}
+}
+
+// EncryptPrivateKey encrypts a PKCS #8 encoded private key for storage. The key id is used as
+// additional data, so the result only decrypts for the key it was stored with.
+func EncryptPrivateKey(cnfg *config.KeyConfig, keyID string, privateDER []byte) ([]byte, error) {
+	gcm, err := newCipher(cnfg)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, privateDER, []byte(keyID)), nil
+}
+
+// decryptPrivateKey reverses EncryptPrivateKey.
+func decryptPrivateKey(cnfg *config.KeyConfig, keyID string, encrypted []byte) ([]byte, error) {
+	gcm, err := newCipher(cnfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encrypted) < gcm.NonceSize() {
+		return nil, errors.New("encrypted signing key is too short")
+	}
+	nonce, sealed := encrypted[:gcm.NonceSize()], encrypted[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, sealed, []byte(keyID))
+}
+
+func newCipher(cnfg *config.KeyConfig) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(cnfg.GetEncryptionKey())
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// decode decrypts a stored key and turns it into a credentials.SigningKey.
+func (k *KeyManager) decode(stored *data.SigningKey) (*credentials.SigningKey, error) {
+	privateDER, err := decryptPrivateKey(k.config, stored.ID, stored.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing key %s: %w", stored.ID, err)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(privateDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signing key %s: %w", stored.ID, err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s has unsupported type %T", stored.ID, privateKey)
+	}
+
+	return &credentials.SigningKey{
+		ID:         stored.ID,
+		Algorithm:  stored.Algorithm,
+		PrivateKey: signer,
+		PublicKey:  signer.Public(),
+	}, nil
+}
+
+func hasStatus(keys []*data.SigningKey, status data.SigningKeyStatus) bool {
+	for _, key := range keys {
+		if key.Status == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package keys
+
+import (
+	"context"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/integration"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/data"
+	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
+	"github.com/stretchr/testify/mock"
+)
+
+// newKeyManager returns a KeyManager for ES256 keys backed by mockDAL.
+func newKeyManager(t *testing.T, mockDAL *dalMock.DataProvider) *KeyManager {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+	t.Setenv("OAUTH_JWT_ALGORITHM", "ES256")
+
+	jwtConfig, err := config.NewJWTConfig()
+	if err != nil {
+		t.Fatalf("invalid jwt config: %s", err)
+	}
+
+	keyConfig, err := config.NewKeyConfig()
+	if err != nil {
+		t.Fatalf("invalid key config: %s", err)
+	}
+
+	return NewKeyManager(keyConfig, jwtConfig, mockDAL)
+}
+
+// newKeyConfig returns the key configuration of the test environment.
+func newKeyConfig(t *testing.T) *config.KeyConfig {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	keyConfig, err := config.NewKeyConfig()
+	if err != nil {
+		t.Fatalf("invalid key config: %s", err)
+	}
+	return keyConfig
+}
+
+// storedKey generates a key with the given status as the data layer would return it.
+func storedKey(t *testing.T, status data.SigningKeyStatus) *data.SigningKey {
+	privateKey, err := GenerateKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	publicDER, _ := x509.MarshalPKIXPublicKey(privateKey.Public())
+	keyID, _ := config.KeyThumbprint(privateKey.Public())
+
+	encrypted, err := EncryptPrivateKey(newKeyConfig(t), keyID, privateDER)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %s", err)
+	}
+
+	return &data.SigningKey{
+		ID:         keyID,
+		Algorithm:  "ES256",
+		PrivateKey: encrypted,
+		PublicKey:  publicDER,
+		Status:     status,
+	}
+}
+
+func TestLoad_BootstrapsActiveAndNextKeys(t *testing.T) {
+	active := storedKey(t, data.SigningKeyActive)
+	next := storedKey(t, data.SigningKeyNext)
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetSigningKeys", mock.Anything).Return([]*data.SigningKey{}, nil).Once()
+	mockDAL.On("CreateSigningKey", mock.Anything, mock.MatchedBy(func(params data.CreateSigningKeyParams) bool {
+		return params.Status == data.SigningKeyActive && params.Algorithm == "ES256"
+	})).Return(active, nil).Once()
+	mockDAL.On("CreateSigningKey", mock.Anything, mock.MatchedBy(func(params data.CreateSigningKeyParams) bool {
+		return params.Status == data.SigningKeyNext && params.Algorithm == "ES256"
+	})).Return(next, nil).Once()
+	mockDAL.On("GetSigningKeys", mock.Anything).Return([]*data.SigningKey{active, next}, nil)
+
+	keyManager := newKeyManager(t, mockDAL)
+	err := keyManager.Load(context.Background())
+	assert.Equal(t, nil, err)
+
+	signingKey, err := keyManager.SigningKey()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, active.ID, signingKey.ID)
+	assert.Equal(t, 2, len(keyManager.PublicKeys()))
+	mockDAL.AssertNumberOfCalls(t, "CreateSigningKey", 2)
+}
+
+func TestLoad_KeepsExistingKeys(t *testing.T) {
+	active := storedKey(t, data.SigningKeyActive)
+	next := storedKey(t, data.SigningKeyNext)
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetSigningKeys", mock.Anything).Return([]*data.SigningKey{active, next}, nil)
+
+	keyManager := newKeyManager(t, mockDAL)
+	err := keyManager.Load(context.Background())
+
+	assert.Equal(t, nil, err)
+	mockDAL.AssertNotCalled(t, "CreateSigningKey", mock.Anything, mock.Anything)
+}
+
+func TestRefresh_OnlyActiveKeySigns(t *testing.T) {
+	active := storedKey(t, data.SigningKeyActive)
+	next := storedKey(t, data.SigningKeyNext)
+	retired := storedKey(t, data.SigningKeyRetired)
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetSigningKeys", mock.Anything).Return([]*data.SigningKey{active, next, retired}, nil)
+
+	keyManager := newKeyManager(t, mockDAL)
+	err := keyManager.Refresh(context.Background())
+	assert.Equal(t, nil, err)
+
+	for _, stored := range []*data.SigningKey{next, retired} {
+		key, err := keyManager.VerificationKey(stored.ID)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, nil, key.PrivateKey)
+		assert.NotEqual(t, nil, key.PublicKey)
+	}
+
+	_, err = keyManager.VerificationKey("unknown")
+	assert.Equal(t, credentials.ErrUnknownKey, err)
+}
+
+func TestLoad_EncryptsPrivateKeys(t *testing.T) {
+	var created []data.CreateSigningKeyParams
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetSigningKeys", mock.Anything).Return([]*data.SigningKey{}, nil)
+	mockDAL.On("CreateSigningKey", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		created = append(created, args.Get(1).(data.CreateSigningKeyParams))
+	}).Return(&data.SigningKey{}, nil)
+
+	keyManager := newKeyManager(t, mockDAL)
+	_ = keyManager.Load(context.Background())
+
+	assert.Equal(t, 2, len(created))
+	for _, params := range created {
+		_, err := x509.ParsePKCS8PrivateKey(params.PrivateKey)
+		assert.NotEqual(t, nil, err)
+
+		privateDER, err := decryptPrivateKey(keyManager.config, params.ID, params.PrivateKey)
+		assert.Equal(t, nil, err)
+		_, err = x509.ParsePKCS8PrivateKey(privateDER)
+		assert.Equal(t, nil, err)
+	}
+}
+
+func TestRefresh_PrivateKeyBoundToKeyID(t *testing.T) {
+	active := storedKey(t, data.SigningKeyActive)
+	next := storedKey(t, data.SigningKeyNext)
+	// A private key copied onto another key id must not decrypt.
+	next.PrivateKey = active.PrivateKey
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetSigningKeys", mock.Anything).Return([]*data.SigningKey{active, next}, nil)
+
+	keyManager := newKeyManager(t, mockDAL)
+	err := keyManager.Refresh(context.Background())
+
+	assert.NotEqual(t, nil, err)
+	_, err = keyManager.SigningKey()
+	assert.Equal(t, credentials.ErrNoSigningKey, err)
+}
+
+func TestRotate_RetiresKeysForTokenLifetime(t *testing.T) {
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("RotateSigningKeys", mock.Anything, mock.MatchedBy(func(params data.RotateSigningKeysParams) bool {
+		// refresh tokens live 7200 seconds in the test environment
+		retention := time.Until(params.RetiredExpiresAt)
+		return params.Next.Status == data.SigningKeyNext &&
+			retention > 7200*time.Second &&
+			time.Until(params.ActivatedBefore) < -config.DefaultKeyRotationInterval+time.Minute
+	})).Return(true, nil)
+	mockDAL.On("GetSigningKeys", mock.Anything).Return([]*data.SigningKey{storedKey(t, data.SigningKeyActive)}, nil)
+
+	keyManager := newKeyManager(t, mockDAL)
+	err := keyManager.Rotate(context.Background())
+
+	assert.Equal(t, nil, err)
+	mockDAL.AssertNumberOfCalls(t, "RotateSigningKeys", 1)
+}
+
+func TestSigningKey_NotLoaded(t *testing.T) {
+	keyManager := newKeyManager(t, &dalMock.DataProvider{})
+	_, err := keyManager.SigningKey()
+	assert.Equal(t, credentials.ErrNoSigningKey, err)
+}
@@ -29,6 +29,21 @@ func (t *TokenHandler) Invalidate(ctx context.Context, token string) error {
 	return args.Error(0)
 }
 
+func (t *TokenHandler) KeySet(ctx context.Context) (*credentials.JWKSet, error) {
+	args := t.Called(ctx)
+	return args.Get(0).(*credentials.JWKSet), args.Error(1)
+}
+
+func (t *TokenHandler) GenerateIDToken(ctx context.Context, identity credentials.Identity) (string, error) {
+	args := t.Called(ctx, identity)
+	return args.String(0), args.Error(1)
+}
+
+func (t *TokenHandler) SupportsIDTokens() bool {
+	args := t.Called()
+	return args.Bool(0)
+}
+
 type SessionManager struct {
 	mock.Mock
 }
@@ -45,6 +60,16 @@ func (s *SessionManager) End(ctx context.Context, sessionID string) error {
 	return args.Error(0)
 }
 
+func (s *SessionManager) EndAll(ctx context.Context, subject interface{}) error {
+	args := s.Called(ctx, subject)
+	return args.Error(0)
+}
+
+func (s *SessionManager) EndOthers(ctx context.Context, sessionID string) error {
+	args := s.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
 func (s *SessionManager) Get(ctx context.Context, sessionID string) (*credentials.Session, error) {
 	args := s.Called(ctx, sessionID)
 	return args.Get(0).(*credentials.Session), args.Error(1)
@@ -54,3 +79,60 @@ func (s *SessionManager) Refresh(ctx context.Context, sessionID string) (string,
 	args := s.Called(ctx, sessionID)
 	return args.String(0), args.Error(1)
 }
+
+type MFAManager struct {
+	mock.Mock
+}
+
+// Compile-time check to ensure mock MFAManager satisfies the credentials.MFAManager interface.
+var _ credentials.MFAManager = new(MFAManager)
+
+func (m *MFAManager) Enroll(ctx context.Context, userID int64, accountName string) (*credentials.MFAEnrollment, error) {
+	args := m.Called(ctx, userID, accountName)
+	return args.Get(0).(*credentials.MFAEnrollment), args.Error(1)
+}
+
+func (m *MFAManager) Confirm(ctx context.Context, userID int64, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MFAManager) Disable(ctx context.Context, userID int64, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MFAManager) Verify(ctx context.Context, userID int64, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MFAManager) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MFAManager) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MFAManager) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MFAManager) StartChallenge(ctx context.Context, userID int64) (*credentials.MFAChallenge, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*credentials.MFAChallenge), args.Error(1)
+}
+
+func (m *MFAManager) GetChallenge(ctx context.Context, challengeID string) (*credentials.MFAChallenge, error) {
+	args := m.Called(ctx, challengeID)
+	return args.Get(0).(*credentials.MFAChallenge), args.Error(1)
+}
+
+func (m *MFAManager) EndChallenge(ctx context.Context, challengeID string) error {
+	args := m.Called(ctx, challengeID)
+	return args.Error(0)
+}
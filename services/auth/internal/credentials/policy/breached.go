@@ -0,0 +1,50 @@
+package policy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedList looks passwords up in a local copy of a breached password list in the k-anonymity
+// range format of Have I Been Pwned: a file per upper case hex prefix of five characters of the SHA-1
+// hash, named <prefix>.txt, with a <remaining 35 characters>:<count> line per breached password.
+type BreachedList struct {
+	dir string
+}
+
+// NewBreachedList creates a new BreachedList of the prefix files in dir.
+func NewBreachedList(dir string) *BreachedList {
+	return &BreachedList{
+		dir: dir,
+	}
+}
+
+// Contains reports whether password appears in the list. Missing prefix files count as empty.
+func (l *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(l.dir, prefix+".txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		// Padding entries, added to hide the number of actual matches, have a count of 0
+		if strings.EqualFold(lineSuffix, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,167 @@
+// Package policy checks new passwords against the configured password policy.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/data"
+)
+
+// Rules of the policy a password can violate.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleLower     = "lowercase"
+	RuleUpper     = "uppercase"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RulePersonal  = "personal_info"
+	RuleHistory   = "history"
+	RuleBreached  = "breached"
+)
+
+// Violation is a rule of the policy a password breaks.
+type Violation struct {
+	Rule    string // one of the Rule constants
+	Message string // explanation for the user
+}
+
+// PasswordPolicy checks new passwords and keeps the history of the passwords users have set.
+type PasswordPolicy struct {
+	config   *config.PasswordPolicyConfig
+	dal      data.DataProvider
+	hasher   credentials.SecureHasher // verifies new passwords against the history
+	breached *BreachedList            // nil if no breached password list is configured
+}
+
+// NewPasswordPolicy creates a new PasswordPolicy enforcing the configured rules.
+func NewPasswordPolicy(cnfg *config.PasswordPolicyConfig, dal data.DataProvider, hasher credentials.SecureHasher) *PasswordPolicy {
+	policy := &PasswordPolicy{
+		config: cnfg,
+		dal:    dal,
+		hasher: hasher,
+	}
+	if dir := cnfg.GetBreachedDir(); len(dir) > 0 {
+		policy.breached = NewBreachedList(dir)
+	}
+	return policy
+}
+
+// Check returns the rules password violates as the new password of user, none if it's acceptable.
+// Users that aren't created yet only need a username and email, and have no history to check.
+func (p *PasswordPolicy) Check(ctx context.Context, password string, user *data.User) ([]Violation, error) {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.GetMinLength() {
+		violations = append(violations, Violation{RuleMinLength, fmt.Sprintf("password must be at least %d characters long", p.config.GetMinLength())})
+	}
+	if length > p.config.GetMaxLength() {
+		violations = append(violations, Violation{RuleMaxLength, fmt.Sprintf("password must be at most %d characters long", p.config.GetMaxLength())})
+	}
+
+	classes := []struct {
+		required bool
+		rule     string
+		message  string
+		is       func(r rune) bool
+	}{
+		{p.config.IsLowerRequired(), RuleLower, "password must contain a lowercase letter", unicode.IsLower},
+		{p.config.IsUpperRequired(), RuleUpper, "password must contain an uppercase letter", unicode.IsUpper},
+		{p.config.IsDigitRequired(), RuleDigit, "password must contain a digit", unicode.IsDigit},
+		{p.config.IsSymbolRequired(), RuleSymbol, "password must contain a symbol", isSymbol},
+	}
+	for _, class := range classes {
+		if class.required && !strings.ContainsFunc(password, class.is) {
+			violations = append(violations, Violation{class.rule, class.message})
+		}
+	}
+
+	if containsPersonalInfo(password, user) {
+		violations = append(violations, Violation{RulePersonal, "password must not contain the username or email address"})
+	}
+
+	reused, err := p.isReused(ctx, password, user)
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		violations = append(violations, Violation{RuleHistory, fmt.Sprintf("password must differ from the last %d passwords", p.config.GetHistory())})
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			violations = append(violations, Violation{RuleBreached, "password appears in a data breach"})
+		}
+	}
+
+	return violations, nil
+}
+
+// Record adds a password the user has set to the user's history, forgetting the ones beyond the
+// configured history.
+func (p *PasswordPolicy) Record(ctx context.Context, userID int64, hashedPassword string) error {
+	if p.config.GetHistory() == 0 {
+		return nil
+	}
+	return p.dal.AddPasswordHistory(ctx, userID, []byte(hashedPassword), p.config.GetHistory())
+}
+
+// isReused reports whether password is the current password of the user or one of the recent ones.
+func (p *PasswordPolicy) isReused(ctx context.Context, password string, user *data.User) (bool, error) {
+	if p.config.GetHistory() == 0 || user.ID == 0 {
+		return false, nil
+	}
+
+	// The current password is checked too, since it predates the history for existing users
+	hashes := []string{string(user.HashedPassword)}
+	history, err := p.dal.GetPasswordHistory(ctx, user.ID, p.config.GetHistory())
+	if err != nil {
+		return false, err
+	}
+	for _, h := range history {
+		if string(h.HashedPassword) != hashes[0] {
+			hashes = append(hashes, string(h.HashedPassword))
+		}
+	}
+
+	// Hashes that can't be verified, such as of formats no longer configured, can't match either
+	for _, hashed := range hashes {
+		if len(hashed) > 0 && p.hasher.Compare(hashed, password) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// containsPersonalInfo reports whether password contains the username, email address or the local
+// part of the email address of the user, ignoring case.
+func containsPersonalInfo(password string, user *data.User) bool {
+	password = strings.ToLower(password)
+	candidates := []string{user.Username, user.Email}
+	if local, _, ok := strings.Cut(user.Email, "@"); ok {
+		candidates = append(candidates, local)
+	}
+
+	for _, candidate := range candidates {
+		// Very short values would match too many passwords by chance
+		if utf8.RuneCountInString(candidate) >= 3 && strings.Contains(password, strings.ToLower(candidate)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
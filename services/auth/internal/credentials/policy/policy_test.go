@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials/hasher"
+	"github.com/ramyadmz/goauth/internal/data"
+	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newPolicy(t *testing.T, dal data.DataProvider) *PasswordPolicy {
+	cnfg, err := config.NewPasswordPolicyConfig()
+	if err != nil {
+		t.Fatalf("invalid password policy config: %s", err)
+	}
+	return NewPasswordPolicy(cnfg, dal, hasher.NewBcryptHasher(bcrypt.MinCost))
+}
+
+func rules(violations []Violation) []string {
+	rules := []string{}
+	for _, violation := range violations {
+		rules = append(rules, violation.Rule)
+	}
+	return rules
+}
+
+func TestCheck_Rules(t *testing.T) {
+	t.Setenv("OAUTH_PASSWORD_MAX_LENGTH", "16")
+	t.Setenv("OAUTH_PASSWORD_REQUIRE_LOWER", "true")
+	t.Setenv("OAUTH_PASSWORD_REQUIRE_UPPER", "true")
+	t.Setenv("OAUTH_PASSWORD_REQUIRE_DIGIT", "true")
+	t.Setenv("OAUTH_PASSWORD_REQUIRE_SYMBOL", "true")
+	policy := newPolicy(t, &dalMock.DataProvider{})
+	user := &data.User{Username: "alice", Email: "wonderland@test.com"}
+
+	tests := map[string][]string{
+		"Sh0rt!":                 {RuleMinLength},
+		"Much-too-l0ng-password": {RuleMaxLength},
+		"NO-L0WERCASE":           {RuleLower},
+		"no-upp3rcase":           {RuleUpper},
+		"No-Digits-Here":         {RuleDigit},
+		"NoSymbols1nIt":          {RuleSymbol},
+		"Hi-ALICE-123":           {RulePersonal},
+		"My-Wonderland-1":        {RulePersonal},
+		"Acceptable-1":           {},
+	}
+	for password, expected := range tests {
+		violations, err := policy.Check(context.Background(), password, user)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, rules(violations), expected)
+	}
+}
+
+func TestCheck_History(t *testing.T) {
+	current, _ := bcrypt.GenerateFromPassword([]byte("current password"), bcrypt.MinCost)
+	previous, _ := bcrypt.GenerateFromPassword([]byte("previous password"), bcrypt.MinCost)
+	user := &data.User{ID: 1, Username: "alice", HashedPassword: current}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetPasswordHistory", mock.Anything, user.ID, config.DefaultPasswordHistory).Return([]*data.PasswordHistory{
+		{UserID: user.ID, HashedPassword: current},
+		{UserID: user.ID, HashedPassword: previous},
+	}, nil)
+	policy := newPolicy(t, mockDAL)
+
+	for _, password := range []string{"current password", "previous password"} {
+		violations, err := policy.Check(context.Background(), password, user)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, rules(violations), []string{RuleHistory})
+	}
+
+	violations, err := policy.Check(context.Background(), "another password", user)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(violations), 0)
+
+	// New users have no history yet
+	violations, err = policy.Check(context.Background(), "current password", &data.User{Username: "bob"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(violations), 0)
+}
+
+func TestRecord_HistoryDisabled(t *testing.T) {
+	t.Setenv("OAUTH_PASSWORD_HISTORY", "0")
+	mockDAL := &dalMock.DataProvider{}
+	policy := newPolicy(t, mockDAL)
+
+	err := policy.Record(context.Background(), 1, "hash")
+	assert.Equal(t, err, nil)
+	mockDAL.AssertNotCalled(t, "AddPasswordHistory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = policy.Check(context.Background(), "current password", &data.User{ID: 1})
+	assert.Equal(t, err, nil)
+	mockDAL.AssertNotCalled(t, "GetPasswordHistory", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCheck_Breached(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(
+		"003D68EB55068C33ACE09247EE4C639306B:3\r\n"+
+			"1e4c9b93f3f0682250b6cf8331b7ee68fd8:9545824\r\n"+
+			"1E4C9B93F3F0682250B6CF8331B7EE68FD9:0\r\n"), 0o600)
+	assert.Equal(t, err, nil)
+
+	t.Setenv("OAUTH_PASSWORD_BREACHED_DIR", dir)
+	policy := newPolicy(t, &dalMock.DataProvider{})
+
+	violations, err := policy.Check(context.Background(), "password", &data.User{})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rules(violations), []string{RuleBreached})
+
+	// Passwords with other prefixes have no file to look in
+	violations, err = policy.Check(context.Background(), "correct horse battery", &data.User{})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(violations), 0)
+}
@@ -2,6 +2,7 @@ package credentials
 
 import (
 	"context"
+	"crypto"
 	"errors"
 	"time"
 )
@@ -17,6 +18,10 @@ var (
 	ErrValidateToken = errors.New("failed to validate token")
 	ErrInvalidToken  = errors.New("token is invalid or expired")
 	ErrSigningMethod = errors.New("unexpected signing method")
+	ErrRevokeToken   = errors.New("failed to revoke token")
+	ErrUnknownKey    = errors.New("unknown signing key")
+	ErrNoSigningKey  = errors.New("no active signing key")
+	ErrSharedSecret  = errors.New("id tokens can't be signed with a shared secret")
 
 	// Session-related errors
 	ErrStartSession   = errors.New("failed to start session")
@@ -25,6 +30,12 @@ var (
 	ErrRefreshSession = errors.New("failed to refresh session")
 	ErrInvalidSession = errors.New("session is invalid or expired")
 
+	// MFA-related errors
+	ErrMFANotEnabled       = errors.New("mfa is not enabled")
+	ErrMFAAlreadyEnabled   = errors.New("mfa is already enabled")
+	ErrInvalidMFACode      = errors.New("mfa code is invalid or already used")
+	ErrInvalidMFAChallenge = errors.New("mfa challenge is invalid or expired")
+
 	// Password-related errors
 	ErrHashPassword   = errors.New("failed to hash password")
 	ErrVerifyPassword = errors.New("failed to verify password")
@@ -39,16 +50,56 @@ const (
 	RefreshToken TokenType = "refresh"
 )
 
+// SubjectType defines the kind of principal a token is issued for
+type SubjectType string
+
+const (
+	UserSubject   SubjectType = "user"   // token acts on behalf of a user
+	ClientSubject SubjectType = "client" // token acts on behalf of the client itself
+)
+
+// Subject describes the principal a token is issued for and the client it is issued to
+type Subject struct {
+	Type     SubjectType
+	ID       int64 // user id or client id, depending on Type
+	ClientID int64
+	Scope    string
+	GrantID  string   // groups tokens issued from the same authorization grant
+	Roles    []string // roles of the user, empty for client subjects
+}
+
 // Claims holds JWT token claims
 type Claims struct {
-	Subject   interface{} `json:"sub"`
-	ExpiresAt time.Time   `json:"exp"`
+	ID          string      `json:"jti"`
+	GrantID     string      `json:"gid"`
+	Subject     interface{} `json:"sub"`
+	SubjectType SubjectType `json:"sub_type"`
+	ClientID    int64       `json:"client_id"`
+	Scope       string      `json:"scope"`
+	Roles       []string    `json:"roles"`
+	TokenType   TokenType   `json:"token_type"`
+	Issuer      string      `json:"iss"`
+	Audience    string      `json:"aud"`
+	IssuedAt    time.Time   `json:"iat"`
+	ExpiresAt   time.Time   `json:"exp"`
+}
+
+// Identity holds the OpenID Connect claims about an authenticated user that go into an ID token
+type Identity struct {
+	Subject           int64 // user id
+	ClientID          int64 // client the ID token is issued to, used as audience
+	Nonce             string
+	AuthTime          time.Time
+	PreferredUsername string // only set when the profile scope was granted
+	Email             string // only set when the email scope was granted
+	EmailVerified     *bool  // only set when the email scope was granted
 }
 
 // Session holds session data
 type Session struct {
 	SessionID string      `json:"session_id"`
 	Subject   interface{} `json:"subject"`
+	CreatedAt time.Time   `json:"created_at"` // time the user authenticated
 	ExpiresAt time.Time   `json:"expires_at"`
 }
 
@@ -56,20 +107,70 @@ type Session struct {
 type TokenHandler interface {
 	Generate(ctx context.Context, subject interface{}, tokenType TokenType) (string, error) // Generates token
 	Validate(ctx context.Context, token string) (*Claims, error)                            // Validates token
-	Invalidate(ctx context.Context, token string) error                                     // Invalidates token
+	Invalidate(ctx context.Context, token string) error                                     // Invalidates token, and its whole token family for refresh tokens
+	GenerateIDToken(ctx context.Context, identity Identity) (string, error)                 // Generates OpenID Connect ID token
+	KeySet(ctx context.Context) (*JWKSet, error)                                            // Returns the public keys tokens can be verified with
+	SupportsIDTokens() bool                                                                 // Reports whether ID tokens can be issued
+}
+
+// SigningKey is an asymmetric key tokens are signed or verified with
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// KeyStore holds the signing keys of a JWT issuer
+type KeyStore interface {
+	SigningKey() (*SigningKey, error)                  // Returns the key new tokens are signed with
+	VerificationKey(keyID string) (*SigningKey, error) // Returns a published key by its id
+	PublicKeys() []*SigningKey                         // Returns every published key
 }
 
 // SessionManager manages session operations
 type SessionManager interface {
 	Start(ctx context.Context, subject interface{}) (Session, error) // Starts session
 	End(ctx context.Context, sessionID string) error                 // Ends session
+	EndAll(ctx context.Context, subject interface{}) error           // Ends every session of a subject
+	EndOthers(ctx context.Context, sessionID string) error           // Ends every other session of the session's subject
 	Get(ctx context.Context, sessionID string) (*Session, error)     // Retrieves session
 	Refresh(ctx context.Context, sessionID string) (string, error)   // Refresh session
 
 }
 
+// MFAEnrollment holds what a user needs to set up a TOTP authenticator app
+type MFAEnrollment struct {
+	Secret        string   // base32 encoded shared secret, for manual entry
+	URI           string   // otpauth:// URI, usually shown as a QR code
+	RecoveryCodes []string // single-use codes to use when the authenticator is lost, only shown once
+}
+
+// MFAChallenge is a pending login of a user that passed password authentication but still has to
+// present a TOTP code
+type MFAChallenge struct {
+	ID        string
+	UserID    int64
+	ExpiresAt time.Time
+}
+
+// MFAManager manages TOTP multi-factor authentication
+type MFAManager interface {
+	Enroll(ctx context.Context, userID int64, accountName string) (*MFAEnrollment, error)     // Starts enrolling a new authenticator
+	Confirm(ctx context.Context, userID int64, code string) error                             // Enables MFA with a first code of the enrolled authenticator
+	Disable(ctx context.Context, userID int64, code string) error                             // Disables MFA after verifying a code
+	Verify(ctx context.Context, userID int64, code string) error                              // Verifies a TOTP or recovery code, which can't be used again
+	IsEnabled(ctx context.Context, userID int64) (bool, error)                                // Reports whether logins require a code
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) // Replaces the recovery codes after verifying a code
+	CountRecoveryCodes(ctx context.Context, userID int64) (int, error)                        // Returns the number of unused recovery codes
+	StartChallenge(ctx context.Context, userID int64) (*MFAChallenge, error)                  // Starts a pending login
+	GetChallenge(ctx context.Context, challengeID string) (*MFAChallenge, error)              // Retrieves an unexpired pending login
+	EndChallenge(ctx context.Context, challengeID string) error                               // Completes a pending login, at most once
+}
+
 // SecureHasher manages secure hashing operations
 type SecureHasher interface {
 	Hash(data string) (string, error)  // Hashes data
 	Compare(hashed, data string) error // Compares hash and data
+	NeedsRehash(hashed string) bool    // Reports whether the hash was made with an outdated algorithm or parameters
 }
@@ -41,6 +41,7 @@ func (s *SessionManager) Start(ctx context.Context, subject interface{}) (creden
 	return credentials.Session{
 		SessionID: session.ID,
 		Subject:   session.UserID,
+		CreatedAt: session.CreatedAt,
 		ExpiresAt: session.ExpiresAt,
 	}, nil
 }
@@ -65,6 +66,7 @@ func (s *SessionManager) Get(ctx context.Context, sessionID string) (*credential
 	return &credentials.Session{
 		SessionID: session.ID,
 		Subject:   session.UserID,
+		CreatedAt: session.CreatedAt,
 		ExpiresAt: session.ExpiresAt,
 	}, nil
 }
@@ -79,6 +81,33 @@ func (s *SessionManager) End(ctx context.Context, sessionID string) error {
 	return nil
 }
 
+// EndAll terminates every session of a subject (user), such as after a password reset.
+func (s *SessionManager) EndAll(ctx context.Context, subject interface{}) error {
+	err := s.dal.DeleteSessionsByUserID(ctx, subject.(int64))
+	if err != nil {
+		return credentials.ErrEndSession
+	}
+	return nil
+}
+
+// EndOthers terminates every session of the subject (user) of a session but the session itself, such
+// as after a password change.
+func (s *SessionManager) EndOthers(ctx context.Context, sessionID string) error {
+	session, err := s.dal.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		if err == data.ErrSessionNotFound {
+			return credentials.ErrInvalidSession
+		}
+		return credentials.ErrFetchSession
+	}
+
+	err = s.dal.DeleteOtherSessionsByUserID(ctx, session.UserID, session.ID)
+	if err != nil {
+		return credentials.ErrEndSession
+	}
+	return nil
+}
+
 // Refresh extends the expiration time of a session.
 func (s *SessionManager) Refresh(ctx context.Context, sessionID string) (string, error) {
 	// Fetch the existing session
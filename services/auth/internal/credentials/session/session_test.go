@@ -61,6 +61,30 @@ func TestEndSession_Success(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestEndAllSessions_Success(t *testing.T) {
+	mockDal := new(dalMock.DataProvider)
+	sessMgr := NewSessionManager(mockDal)
+	userID := rand.Int63()
+
+	mockDal.On("DeleteSessionsByUserID", context.Background(), userID).Return(nil)
+
+	err := sessMgr.EndAll(context.Background(), userID)
+	assert.NoError(t, err)
+}
+
+func TestEndOtherSessions_Success(t *testing.T) {
+	mockDal := new(dalMock.DataProvider)
+	sessMgr := NewSessionManager(mockDal)
+	userID := rand.Int63()
+
+	mockDal.On("GetSessionByID", mock.Anything, "123").Return(&data.Session{ID: "123", UserID: userID, ExpiresAt: time.Now().Add(1 * time.Hour)}, nil)
+	mockDal.On("DeleteOtherSessionsByUserID", mock.Anything, userID, "123").Return(nil)
+
+	err := sessMgr.EndOthers(context.Background(), "123")
+	assert.NoError(t, err)
+	mockDal.AssertExpectations(t)
+}
+
 func TestRefreshSession_Success(t *testing.T) {
 	mockDal := new(dalMock.DataProvider)
 	sessionManager := NewSessionManager(mockDal)
@@ -0,0 +1,82 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ramyadmz/goauth/internal/data"
+)
+
+// Ensure the data layer can be used as a Store.
+var _ Store = data.DataProvider(nil)
+
+// MemoryStore keeps the failure counters in memory. Counters aren't shared between replicas, so it
+// is meant for single instance deployments and tests.
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]data.LoginAttempts
+	now      func() time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		attempts: make(map[string]data.LoginAttempts),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) RecordLoginAttempt(ctx context.Context, key string, resetBefore time.Time, allow func(*data.LoginAttempts) bool) (*data.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.attempts[key]
+	if !ok || a.LastFailureAt.Before(resetBefore) {
+		a = data.LoginAttempts{Key: key}
+	}
+	previous := a
+	if !allow(&previous) {
+		return &a, nil
+	}
+	a.Failures++
+	a.LastFailureAt = s.now()
+	s.attempts[key] = a
+
+	return &a, nil
+}
+
+func (s *MemoryStore) ReleaseLoginAttempts(ctx context.Context, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		if a, ok := s.attempts[key]; ok && a.Failures > 0 {
+			a.Failures--
+			s.attempts[key] = a
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) ResetLoginAttempts(ctx context.Context, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.attempts, key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, a := range s.attempts {
+		if a.LastFailureAt.Before(before) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package throttle slows down brute-force attacks on passwords and client secrets.
+//
+// Failed attempts are counted per key, such as a username, a client id or a source IP address. An
+// attempt is counted as failed before the credentials are compared and forgotten once they turn out
+// to be valid, so that concurrent attempts can't slip past the limits. Once a key has used up its
+// free attempts, every further attempt has to wait for an exponentially growing delay after the last
+// failure, and after too many failures the key is locked out for a while. Counters are forgotten
+// when no failure happened for the configured window.
+package throttle
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/data"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RetryAfterHeader is the response metadata key telling a throttled caller how many seconds to wait.
+const RetryAfterHeader = "retry-after"
+
+const ipKeyPrefix = "ip:"
+
+// Store keeps the failure counters. data.DataProvider implements it, so that replicas sharing a
+// database share their counters.
+type Store interface {
+	RecordLoginAttempt(ctx context.Context, key string, resetBefore time.Time, allow func(*data.LoginAttempts) bool) (*data.LoginAttempts, error)
+	ReleaseLoginAttempts(ctx context.Context, keys []string) error
+	ResetLoginAttempts(ctx context.Context, keys []string) error
+	DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error
+}
+
+// Throttler decides whether an authentication attempt may proceed based on the failures recorded
+// for its keys.
+type Throttler struct {
+	config *config.ThrottleConfig
+	store  Store
+	now    func() time.Time
+}
+
+// NewThrottler creates a new Throttler keeping its counters in store.
+func NewThrottler(cnfg *config.ThrottleConfig, store Store) *Throttler {
+	return &Throttler{
+		config: cnfg,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+// UserKey returns the throttling key of a username.
+func UserKey(username string) string {
+	return "user:" + username
+}
+
+// ClientKey returns the throttling key of a client id.
+func ClientKey(clientID int64) string {
+	return "client:" + strconv.FormatInt(clientID, 10)
+}
+
+// MFAKey returns the throttling key of the MFA codes of a user.
+func MFAKey(userID int64) string {
+	return "mfa:" + strconv.FormatInt(userID, 10)
+}
+
+// IPKey returns the throttling key of the address the call came from, if it is known.
+func IPKey(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	addr, ok := p.Addr.(*net.TCPAddr)
+	if !ok {
+		return "", false
+	}
+	return ipKeyPrefix + addr.IP.String(), true
+}
+
+// WithIP returns keys followed by the throttling key of the address the call came from, if it is known.
+func WithIP(ctx context.Context, keys ...string) []string {
+	if ipKey, ok := IPKey(ctx); ok {
+		return append(keys, ipKey)
+	}
+	return keys
+}
+
+// Attempt counts an authentication attempt as failed for each of the keys, unless any of them has to
+// wait before its next attempt. Waiting and counting are decided in one step per key, so callers must
+// only compare credentials after Attempt returned nil, and call Succeed if they were valid.
+//
+// A throttled attempt isn't counted and fails with a ResourceExhausted error. The error carries the
+// delay as RetryInfo detail, and it is also sent in the retry-after header.
+func (t *Throttler) Attempt(ctx context.Context, keys ...string) error {
+	logger := logrus.WithContext(ctx).WithField("keys", keys)
+
+	now := t.now()
+	resetBefore := now.Add(-t.config.GetWindow())
+	var wait time.Duration
+	counted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		allowed := false
+		attempts, err := t.store.RecordLoginAttempt(ctx, key, resetBefore, func(previous *data.LoginAttempts) bool {
+			d := t.retryAfter(previous, now)
+			if d > wait {
+				wait = d
+			}
+			allowed = d <= 0
+			return allowed
+		})
+		if err != nil {
+			logger.WithField("key", key).Error("error recording login attempt: %w", err)
+			t.Release(ctx, counted...)
+			return status.Errorf(codes.Internal, "Internal server error")
+		}
+		if !allowed {
+			continue
+		}
+		counted = append(counted, key)
+
+		if attempts.Failures == t.config.GetLockoutAttempts() {
+			logger.WithField("key", key).Warn("locked out after too many failed attempts")
+		}
+	}
+	if wait <= 0 {
+		return nil
+	}
+
+	// The attempt doesn't happen, so it mustn't count against the keys that would have allowed it.
+	t.Release(ctx, counted...)
+
+	// Round up, so that a caller waiting for the advertised time isn't rejected again.
+	seconds := int64(math.Ceil(wait.Seconds()))
+	logger.WithField("retryAfter", seconds).Warn("attempt throttled")
+
+	// Callers outside of a gRPC call, such as the HTTP front end, have no header to set.
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, strconv.FormatInt(seconds, 10)))
+
+	st := status.New(codes.ResourceExhausted, "too many failed attempts, try again later")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(seconds) * time.Second)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// Succeed forgets the failures of the keys of an attempt that turned out to be valid. Source addresses
+// only get the attempt itself back, so that an attacker can't clear its counter by logging into an
+// account of its own.
+func (t *Throttler) Succeed(ctx context.Context, keys ...string) {
+	var reset, released []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, ipKeyPrefix) {
+			released = append(released, key)
+		} else {
+			reset = append(reset, key)
+		}
+	}
+
+	if len(reset) > 0 {
+		if err := t.store.ResetLoginAttempts(ctx, reset); err != nil {
+			logrus.WithContext(ctx).WithField("keys", reset).Error("error resetting login attempts: %w", err)
+		}
+	}
+	t.Release(ctx, released...)
+}
+
+// Cleanup periodically removes the counters that outlived the window until ctx is canceled.
+func (t *Throttler) Cleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.store.DeleteExpiredLoginAttempts(ctx, t.now().Add(-t.config.GetWindow())); err != nil {
+				logrus.WithContext(ctx).Error("error cleaning up login attempts: %w", err)
+			}
+		}
+	}
+}
+
+// Release takes back the attempt counted for the keys when it couldn't be decided, for example because
+// of an internal error. Errors are only logged, since the worst outcome is a failure counted too many.
+func (t *Throttler) Release(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	if err := t.store.ReleaseLoginAttempts(ctx, keys); err != nil {
+		logrus.WithContext(ctx).WithField("keys", keys).Error("error releasing login attempts: %w", err)
+	}
+}
+
+// retryAfter returns how long the key of a has to wait before its next attempt.
+func (t *Throttler) retryAfter(a *data.LoginAttempts, now time.Time) time.Duration {
+	if a.LastFailureAt.Before(now.Add(-t.config.GetWindow())) {
+		return 0
+	}
+
+	var delay time.Duration
+	switch {
+	case a.Failures >= t.config.GetLockoutAttempts():
+		delay = t.config.GetLockoutDuration()
+	case a.Failures > t.config.GetFreeAttempts():
+		delay = t.config.GetMaxDelay()
+		// Doubling more than 62 times overflows, and is past any sensible max delay anyway.
+		if exp := a.Failures - t.config.GetFreeAttempts() - 1; exp < 62 {
+			if d := t.config.GetBaseDelay() << exp; d > 0 && d < delay {
+				delay = d
+			}
+		}
+	}
+
+	return a.LastFailureAt.Add(delay).Sub(now)
+}
@@ -0,0 +1,180 @@
+package throttle
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/data"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const testKey = "user:alice"
+
+// clock is a fake time source shared by a throttler and its store.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time          { return c.now }
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newThrottler(t *testing.T) (*Throttler, *clock) {
+	cnfg, err := config.NewThrottleConfig()
+	if err != nil {
+		t.Fatalf("invalid throttle config: %s", err)
+	}
+
+	c := &clock{now: time.Now()}
+	store := NewMemoryStore()
+	store.now = c.Now
+
+	throttler := NewThrottler(cnfg, store)
+	throttler.now = c.Now
+	return throttler, c
+}
+
+// fail records failed attempts for the keys without asking whether they may happen.
+func fail(throttler *Throttler, times int, keys ...string) {
+	for i := 0; i < times; i++ {
+		for _, key := range keys {
+			throttler.store.RecordLoginAttempt(context.Background(), key, time.Time{}, func(*data.LoginAttempts) bool { return true })
+		}
+	}
+}
+
+// failures returns the number of failures counted for key.
+func failures(throttler *Throttler, key string) int {
+	return throttler.store.(*MemoryStore).attempts[key].Failures
+}
+
+// retryDelay returns the delay advertised by a throttling error.
+func retryDelay(t *testing.T, err error) time.Duration {
+	st := status.Convert(err)
+	assert.Equal(t, st.Code(), codes.ResourceExhausted)
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			return info.RetryDelay.AsDuration()
+		}
+	}
+	t.Fatal("throttling error without retry info")
+	return 0
+}
+
+func TestAttempt_FreeAttempts(t *testing.T) {
+	throttler, _ := newThrottler(t)
+
+	fail(throttler, config.DefaultThrottleFreeAttempts, testKey)
+	assert.Equal(t, throttler.Attempt(context.Background(), testKey), nil)
+	assert.Equal(t, failures(throttler, testKey), config.DefaultThrottleFreeAttempts+1)
+
+	assert.Equal(t, retryDelay(t, throttler.Attempt(context.Background(), testKey)), config.DefaultThrottleBaseDelay)
+}
+
+func TestAttempt_ExponentialBackoff(t *testing.T) {
+	throttler, clock := newThrottler(t)
+
+	fail(throttler, config.DefaultThrottleFreeAttempts+3, testKey)
+	assert.Equal(t, retryDelay(t, throttler.Attempt(context.Background(), testKey)), 4*config.DefaultThrottleBaseDelay)
+
+	clock.Advance(4 * config.DefaultThrottleBaseDelay)
+	assert.Equal(t, throttler.Attempt(context.Background(), testKey), nil)
+}
+
+func TestAttempt_MaxDelay(t *testing.T) {
+	t.Setenv("OAUTH_THROTTLE_MAX_DELAY", "10")
+	throttler, _ := newThrottler(t)
+
+	fail(throttler, config.DefaultThrottleLockoutAttempts-1, testKey)
+	assert.Equal(t, retryDelay(t, throttler.Attempt(context.Background(), testKey)), 10*time.Second)
+}
+
+func TestAttempt_Lockout(t *testing.T) {
+	throttler, clock := newThrottler(t)
+
+	fail(throttler, config.DefaultThrottleLockoutAttempts, testKey)
+	assert.Equal(t, retryDelay(t, throttler.Attempt(context.Background(), testKey)), config.DefaultThrottleLockoutDuration)
+
+	clock.Advance(config.DefaultThrottleLockoutDuration - time.Minute)
+	assert.Equal(t, retryDelay(t, throttler.Attempt(context.Background(), testKey)), time.Minute)
+}
+
+func TestAttempt_LongestDelayWins(t *testing.T) {
+	throttler, _ := newThrottler(t)
+
+	fail(throttler, config.DefaultThrottleFreeAttempts+1, testKey)
+	fail(throttler, config.DefaultThrottleLockoutAttempts, "ip:192.0.2.1")
+
+	assert.Equal(t, retryDelay(t, throttler.Attempt(context.Background(), testKey, "ip:192.0.2.1")), config.DefaultThrottleLockoutDuration)
+	assert.Equal(t, throttler.Attempt(context.Background(), "user:bob"), nil)
+}
+
+func TestAttempt_WindowExpired(t *testing.T) {
+	throttler, clock := newThrottler(t)
+
+	fail(throttler, config.DefaultThrottleLockoutAttempts, testKey)
+	clock.Advance(config.DefaultThrottleWindow + time.Second)
+	assert.Equal(t, throttler.Attempt(context.Background(), testKey), nil)
+
+	// The count starts over instead of continuing from the lockout.
+	assert.Equal(t, failures(throttler, testKey), 1)
+}
+
+func TestAttempt_ThrottledIsNotCounted(t *testing.T) {
+	throttler, _ := newThrottler(t)
+
+	fail(throttler, config.DefaultThrottleLockoutAttempts, "ip:192.0.2.1")
+	assert.Equal(t, status.Code(throttler.Attempt(context.Background(), testKey, "ip:192.0.2.1")), codes.ResourceExhausted)
+
+	assert.Equal(t, failures(throttler, testKey), 0)
+	assert.Equal(t, failures(throttler, "ip:192.0.2.1"), config.DefaultThrottleLockoutAttempts)
+}
+
+func TestAttempt_Concurrent(t *testing.T) {
+	throttler, _ := newThrottler(t)
+
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if throttler.Attempt(context.Background(), testKey) == nil {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Attempts are allowed until the free ones are used up, however many run at the same time.
+	assert.Equal(t, int(allowed.Load()), config.DefaultThrottleFreeAttempts+1)
+}
+
+func TestSucceed(t *testing.T) {
+	throttler, _ := newThrottler(t)
+
+	fail(throttler, config.DefaultThrottleLockoutAttempts, testKey, "ip:192.0.2.1")
+	throttler.Succeed(context.Background(), testKey, "ip:192.0.2.1")
+
+	// The source address only gets the successful attempt back.
+	assert.Equal(t, failures(throttler, testKey), 0)
+	assert.Equal(t, failures(throttler, "ip:192.0.2.1"), config.DefaultThrottleLockoutAttempts-1)
+	assert.Equal(t, throttler.Attempt(context.Background(), testKey), nil)
+	assert.Equal(t, status.Code(throttler.Attempt(context.Background(), "ip:192.0.2.1")), codes.ResourceExhausted)
+}
+
+func TestWithIP(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 4242}})
+	assert.Equal(t, WithIP(ctx, testKey), []string{testKey, "ip:192.0.2.1"})
+
+	assert.Equal(t, WithIP(context.Background(), testKey), []string{testKey})
+}
@@ -0,0 +1,135 @@
+package totp
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/data"
+)
+
+const (
+	recoveryCodeLength   = 10 // characters, 50 bits of entropy
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+// RegenerateRecoveryCodes replaces every recovery code of the user with new ones after verifying a
+// code, so that a stolen session alone can't be used to obtain them.
+func (m *TOTPManager) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := m.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	return m.replaceRecoveryCodes(ctx, userID)
+}
+
+// CountRecoveryCodes returns how many recovery codes of the user haven't been used yet.
+func (m *TOTPManager) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	if _, err := m.getEnabledMFA(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	recoveryCodes, err := m.dal.GetUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch recovery codes: %w", err)
+	}
+	return len(recoveryCodes), nil
+}
+
+// replaceRecoveryCodes generates and stores a new set of recovery codes, invalidating the old ones.
+func (m *TOTPManager) replaceRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	recoveryCodes := make([]string, 0, m.config.GetRecoveryCodes())
+	hashedCodes := make([]string, 0, m.config.GetRecoveryCodes())
+	for i := 0; i < m.config.GetRecoveryCodes(); i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		hashed, err := m.hasher.Hash(normalizeRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+
+		recoveryCodes = append(recoveryCodes, code)
+		hashedCodes = append(hashedCodes, hashed)
+	}
+
+	if err := m.dal.ReplaceRecoveryCodes(ctx, userID, hashedCodes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return recoveryCodes, nil
+}
+
+// verifyRecoveryCode checks code against the unused recovery codes of the user and marks the matching
+// one as used.
+func (m *TOTPManager) verifyRecoveryCode(ctx context.Context, userID int64, code string) error {
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeLength {
+		return credentials.ErrInvalidMFACode
+	}
+
+	recoveryCodes, err := m.dal.GetUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch recovery codes: %w", err)
+	}
+
+	for _, recoveryCode := range recoveryCodes {
+		err := m.hasher.Compare(recoveryCode.HashedCode, code)
+		if err == credentials.ErrInvalidPass {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := m.dal.UseRecoveryCode(ctx, recoveryCode.ID); err != nil {
+			if err == data.ErrRecoveryCodeUsed {
+				return credentials.ErrInvalidMFACode
+			}
+			return fmt.Errorf("failed to record recovery code: %w", err)
+		}
+		return nil
+	}
+
+	return credentials.ErrInvalidMFACode
+}
+
+// generateRecoveryCode returns a random code formatted as two dash separated groups.
+func generateRecoveryCode() (string, error) {
+	random := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", credentials.ErrGenerateSecret
+	}
+
+	code := make([]byte, 0, recoveryCodeLength+1)
+	for i, b := range random {
+		if i == recoveryCodeLength/2 {
+			code = append(code, '-')
+		}
+		// The alphabet has 32 characters, so every byte maps to one without bias
+		code = append(code, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+// normalizeRecoveryCode strips the formatting a user may or may not have typed along with a code.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// isTOTPCode reports whether code has the format of a TOTP code rather than of a recovery code.
+func isTOTPCode(code string) bool {
+	if len(code) != Digits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
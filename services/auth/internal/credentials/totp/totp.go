@@ -0,0 +1,355 @@
+// Package totp provides multi-factor authentication with time-based one-time passwords (RFC 6238),
+// as generated by common authenticator apps.
+//
+// Secrets are encrypted with AES-GCM before they are stored, bound to the user they belong to. Every
+// accepted code records its time step, so that neither it nor an older code can be used again.
+// Single-use recovery codes, stored hashed, can be used in place of a code when the authenticator is lost.
+package totp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/data"
+	"github.com/sirupsen/logrus"
+)
+
+// Ensure TOTPManager implements the MFAManager interface from the credentials package.
+var _ credentials.MFAManager = new(TOTPManager)
+
+// Parameters of the generated codes. These are the defaults of RFC 6238, the only ones every
+// authenticator app supports.
+const (
+	Digits = 6
+	Period = 30 * time.Second
+
+	secretSize = 20 // bytes, the size of an HMAC-SHA1 key
+	skew       = 1  // steps a code may be off, to allow for clock drift and typing time
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPManager manages TOTP authenticators and pending MFA logins through the data layer.
+type TOTPManager struct {
+	config *config.MFAConfig
+	dal    data.DataProvider
+	hasher credentials.SecureHasher // hashes recovery codes
+	now    func() time.Time
+}
+
+// NewTOTPManager creates a new TOTPManager.
+func NewTOTPManager(cnfg *config.MFAConfig, dataProvider data.DataProvider, hasher credentials.SecureHasher) *TOTPManager {
+	return &TOTPManager{
+		config: cnfg,
+		dal:    dataProvider,
+		hasher: hasher,
+		now:    time.Now,
+	}
+}
+
+// Enroll generates a new secret and recovery codes for the user. It replaces an earlier enrollment
+// that was never confirmed, and fails with credentials.ErrMFAAlreadyEnabled if MFA is enabled.
+func (m *TOTPManager) Enroll(ctx context.Context, userID int64, accountName string) (*credentials.MFAEnrollment, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, credentials.ErrGenerateSecret
+	}
+
+	encrypted, err := m.encrypt(userID, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = m.dal.CreateUserMFA(ctx, data.CreateUserMFAParams{
+		UserID:          userID,
+		EncryptedSecret: encrypted,
+	})
+	if err != nil {
+		if err == data.ErrMFAAlreadyEnabled {
+			return nil, credentials.ErrMFAAlreadyEnabled
+		}
+		return nil, fmt.Errorf("failed to store mfa secret: %w", err)
+	}
+
+	recoveryCodes, err := m.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := secretEncoding.EncodeToString(secret)
+	return &credentials.MFAEnrollment{
+		Secret:        encoded,
+		URI:           m.uri(accountName, encoded),
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// Confirm enables MFA once the user proves the authenticator works with a first code.
+func (m *TOTPManager) Confirm(ctx context.Context, userID int64, code string) error {
+	userMFA, err := m.getUserMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if userMFA.ConfirmedAt != nil {
+		return credentials.ErrMFAAlreadyEnabled
+	}
+
+	if err := m.verify(ctx, userMFA, code); err != nil {
+		return err
+	}
+
+	if err := m.dal.ConfirmUserMFA(ctx, userID); err != nil {
+		if err == data.ErrUserMFANotFound {
+			return credentials.ErrMFANotEnabled
+		}
+		return fmt.Errorf("failed to confirm mfa: %w", err)
+	}
+	return nil
+}
+
+// Disable turns MFA off after verifying a code, so that a stolen session alone can't remove it.
+func (m *TOTPManager) Disable(ctx context.Context, userID int64, code string) error {
+	if err := m.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := m.dal.DeleteUserMFA(ctx, userID); err != nil {
+		if err == data.ErrUserMFANotFound {
+			return credentials.ErrMFANotEnabled
+		}
+		return fmt.Errorf("failed to delete mfa: %w", err)
+	}
+	return nil
+}
+
+// Verify checks a code of the user's confirmed authenticator, or one of the user's recovery codes.
+func (m *TOTPManager) Verify(ctx context.Context, userID int64, code string) error {
+	userMFA, err := m.getEnabledMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !isTOTPCode(code) {
+		return m.verifyRecoveryCode(ctx, userID, code)
+	}
+	return m.verify(ctx, userMFA, code)
+}
+
+// IsEnabled reports whether the user has a confirmed authenticator.
+func (m *TOTPManager) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	userMFA, err := m.dal.GetUserMFA(ctx, userID)
+	if err != nil {
+		if err == data.ErrUserMFANotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to fetch mfa: %w", err)
+	}
+	return userMFA.ConfirmedAt != nil, nil
+}
+
+// StartChallenge creates a pending login that expires after the configured challenge expiration.
+func (m *TOTPManager) StartChallenge(ctx context.Context, userID int64) (*credentials.MFAChallenge, error) {
+	challenge, err := m.dal.CreateMFAChallenge(ctx, data.CreateMFAChallengeParams{
+		UserID:    userID,
+		ExpiresAt: m.now().Add(m.config.GetChallengeExpiration()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+
+	return &credentials.MFAChallenge{
+		ID:        challenge.ID,
+		UserID:    challenge.UserID,
+		ExpiresAt: challenge.ExpiresAt,
+	}, nil
+}
+
+// GetChallenge retrieves a pending login, returning credentials.ErrInvalidMFAChallenge if it doesn't
+// exist or has expired.
+func (m *TOTPManager) GetChallenge(ctx context.Context, challengeID string) (*credentials.MFAChallenge, error) {
+	challenge, err := m.dal.GetMFAChallengeByID(ctx, challengeID)
+	if err != nil {
+		if err == data.ErrMFAChallengeNotFound {
+			return nil, credentials.ErrInvalidMFAChallenge
+		}
+		return nil, fmt.Errorf("failed to fetch mfa challenge: %w", err)
+	}
+
+	if m.now().After(challenge.ExpiresAt) {
+		return nil, credentials.ErrInvalidMFAChallenge
+	}
+
+	return &credentials.MFAChallenge{
+		ID:        challenge.ID,
+		UserID:    challenge.UserID,
+		ExpiresAt: challenge.ExpiresAt,
+	}, nil
+}
+
+// EndChallenge completes a pending login. Only the first of concurrent calls succeeds; the others get
+// credentials.ErrInvalidMFAChallenge.
+func (m *TOTPManager) EndChallenge(ctx context.Context, challengeID string) error {
+	if err := m.dal.DeleteMFAChallenge(ctx, challengeID); err != nil {
+		if err == data.ErrMFAChallengeNotFound {
+			return credentials.ErrInvalidMFAChallenge
+		}
+		return fmt.Errorf("failed to delete mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// CleanupChallenges periodically removes expired pending logins until ctx is canceled.
+func (m *TOTPManager) CleanupChallenges(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.dal.DeleteExpiredMFAChallenges(ctx); err != nil {
+				logrus.WithContext(ctx).Error("error cleaning up mfa challenges: %w", err)
+			}
+		}
+	}
+}
+
+func (m *TOTPManager) getUserMFA(ctx context.Context, userID int64) (*data.UserMFA, error) {
+	userMFA, err := m.dal.GetUserMFA(ctx, userID)
+	if err != nil {
+		if err == data.ErrUserMFANotFound {
+			return nil, credentials.ErrMFANotEnabled
+		}
+		return nil, fmt.Errorf("failed to fetch mfa: %w", err)
+	}
+	return userMFA, nil
+}
+
+// getEnabledMFA fetches the confirmed authenticator of the user.
+func (m *TOTPManager) getEnabledMFA(ctx context.Context, userID int64) (*data.UserMFA, error) {
+	userMFA, err := m.getUserMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userMFA.ConfirmedAt == nil {
+		return nil, credentials.ErrMFANotEnabled
+	}
+	return userMFA, nil
+}
+
+// verify checks code against the steps around the current time and marks the matching step as used.
+func (m *TOTPManager) verify(ctx context.Context, userMFA *data.UserMFA, code string) error {
+	if len(code) != Digits {
+		return credentials.ErrInvalidMFACode
+	}
+
+	secret, err := m.decrypt(userMFA.UserID, userMFA.EncryptedSecret)
+	if err != nil {
+		return err
+	}
+
+	current := m.now().Unix() / int64(Period/time.Second)
+	for step := current - skew; step <= current+skew; step++ {
+		if step <= userMFA.LastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(Code(secret, step)), []byte(code)) != 1 {
+			continue
+		}
+
+		if err := m.dal.UseMFAStep(ctx, userMFA.UserID, step); err != nil {
+			if err == data.ErrMFAStepUsed {
+				return credentials.ErrInvalidMFACode
+			}
+			return fmt.Errorf("failed to record mfa code: %w", err)
+		}
+		return nil
+	}
+
+	return credentials.ErrInvalidMFACode
+}
+
+// uri builds the key URI understood by authenticator apps.
+func (m *TOTPManager) uri(accountName, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", m.config.GetIssuer())
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(Digits))
+	query.Set("period", strconv.Itoa(int(Period/time.Second)))
+
+	label := url.PathEscape(m.config.GetIssuer() + ":" + accountName)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// encrypt seals secret with AES-GCM. The user id is authenticated along with it, so that a secret
+// can't be moved to another user's record.
+func (m *TOTPManager) encrypt(userID int64, secret []byte) ([]byte, error) {
+	gcm, err := m.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, credentials.ErrGenerateSecret
+	}
+	return gcm.Seal(nonce, nonce, secret, additionalData(userID)), nil
+}
+
+func (m *TOTPManager) decrypt(userID int64, encrypted []byte) ([]byte, error) {
+	gcm, err := m.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encrypted) < gcm.NonceSize() {
+		return nil, errors.New("encrypted mfa secret is too short")
+	}
+	nonce, sealed := encrypted[:gcm.NonceSize()], encrypted[gcm.NonceSize():]
+
+	secret, err := gcm.Open(nil, nonce, sealed, additionalData(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt mfa secret: %w", err)
+	}
+	return secret, nil
+}
+
+func (m *TOTPManager) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(m.config.GetEncryptionKey())
+	if err != nil {
+		return nil, fmt.Errorf("invalid mfa encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func additionalData(userID int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
+
+// Code returns the code of secret for a time step (RFC 4226 section 5.3).
+func Code(secret []byte, step int64) string {
+	mac := hmac.New(sha1.New, secret)
+	_ = binary.Write(mac, binary.BigEndian, step)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%1000000)
+}
@@ -0,0 +1,252 @@
+package totp
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/integration"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/hasher"
+	"github.com/ramyadmz/goauth/internal/data"
+	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newManager(t *testing.T, dal data.DataProvider) *TOTPManager {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+
+	cnfg, err := config.NewMFAConfig()
+	if err != nil {
+		t.Fatalf("invalid mfa config: %s", err)
+	}
+	return NewTOTPManager(cnfg, dal, hasher.NewBcryptHasher(bcrypt.MinCost))
+}
+
+// TestCode checks the SHA1 test vectors of RFC 6238 appendix B, truncated to six digits.
+func TestCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, code := range vectors {
+		assert.Equal(t, Code(secret, unix/30), code)
+	}
+}
+
+func TestEnrollConfirmVerify(t *testing.T) {
+	ctx := context.Background()
+	userID := rand.Int63()
+	now := time.Now()
+
+	userMFA := &data.UserMFA{UserID: userID}
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("CreateUserMFA", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		userMFA.EncryptedSecret = args.Get(1).(data.CreateUserMFAParams).EncryptedSecret
+	}).Return(userMFA, nil)
+	mockDAL.On("ReplaceRecoveryCodes", mock.Anything, userID, mock.Anything).Return(nil)
+	mockDAL.On("GetUserMFA", mock.Anything, userID).Return(userMFA, nil)
+	mockDAL.On("UseMFAStep", mock.Anything, userID, mock.Anything).Run(func(args mock.Arguments) {
+		userMFA.LastUsedStep = args.Get(2).(int64)
+	}).Return(nil)
+	mockDAL.On("ConfirmUserMFA", mock.Anything, userID).Run(func(args mock.Arguments) {
+		userMFA.ConfirmedAt = &now
+	}).Return(nil)
+
+	manager := newManager(t, mockDAL)
+	manager.now = func() time.Time { return now }
+
+	enrollment, err := manager.Enroll(ctx, userID, "alice")
+	assert.Equal(t, err, nil)
+
+	uri, err := url.Parse(enrollment.URI)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, uri.Scheme, "otpauth")
+	assert.Equal(t, uri.Host, "totp")
+	assert.Equal(t, uri.Query().Get("secret"), enrollment.Secret)
+	assert.Equal(t, uri.Query().Get("issuer"), config.DefaultMFAIssuer)
+	assert.Equal(t, len(enrollment.RecoveryCodes), config.DefaultMFARecoveryCodes)
+
+	secret, err := secretEncoding.DecodeString(enrollment.Secret)
+	assert.Equal(t, err, nil)
+	step := now.Unix() / 30
+
+	// MFA isn't enabled until the enrollment is confirmed
+	err = manager.Verify(ctx, userID, Code(secret, step))
+	assert.Equal(t, err, credentials.ErrMFANotEnabled)
+
+	err = manager.Confirm(ctx, userID, Code(secret, step))
+	assert.Equal(t, err, nil)
+
+	enabled, err := manager.IsEnabled(ctx, userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, enabled, true)
+
+	// The code used to confirm can't be used again
+	err = manager.Verify(ctx, userID, Code(secret, step))
+	assert.Equal(t, err, credentials.ErrInvalidMFACode)
+
+	// The next code is accepted within the allowed skew
+	err = manager.Verify(ctx, userID, Code(secret, step+1))
+	assert.Equal(t, err, nil)
+
+	err = manager.Verify(ctx, userID, "000000")
+	assert.Equal(t, err, credentials.ErrInvalidMFACode)
+}
+
+func TestVerify_RecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	userID := rand.Int63()
+	now := time.Now()
+
+	var hashedCodes []string
+	userMFA := &data.UserMFA{UserID: userID}
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("CreateUserMFA", mock.Anything, mock.Anything).Return(userMFA, nil)
+	mockDAL.On("ReplaceRecoveryCodes", mock.Anything, userID, mock.Anything).Run(func(args mock.Arguments) {
+		hashedCodes = args.Get(2).([]string)
+	}).Return(nil)
+
+	manager := newManager(t, mockDAL)
+	enrollment, err := manager.Enroll(ctx, userID, "alice")
+	assert.Equal(t, err, nil)
+
+	userMFA.ConfirmedAt = &now
+	unused := []*data.RecoveryCode{
+		{ID: 1, UserID: userID, HashedCode: hashedCodes[0]},
+		{ID: 2, UserID: userID, HashedCode: hashedCodes[1]},
+	}
+	mockDAL.On("GetUserMFA", mock.Anything, userID).Return(userMFA, nil)
+	mockDAL.On("GetUnusedRecoveryCodes", mock.Anything, userID).Return(unused, nil)
+	mockDAL.On("UseRecoveryCode", mock.Anything, int64(2)).Return(nil).Once()
+	mockDAL.On("UseRecoveryCode", mock.Anything, int64(2)).Return(data.ErrRecoveryCodeUsed)
+
+	// Codes are accepted regardless of case and formatting
+	code := strings.ToUpper(strings.ReplaceAll(enrollment.RecoveryCodes[1], "-", " "))
+	err = manager.Verify(ctx, userID, code)
+	assert.Equal(t, err, nil)
+
+	// A code someone else used in the meantime is rejected
+	err = manager.Verify(ctx, userID, enrollment.RecoveryCodes[1])
+	assert.Equal(t, err, credentials.ErrInvalidMFACode)
+
+	// Codes that were used before are no longer returned by the data layer
+	err = manager.Verify(ctx, userID, enrollment.RecoveryCodes[5])
+	assert.Equal(t, err, credentials.ErrInvalidMFACode)
+
+	count, err := manager.CountRecoveryCodes(ctx, userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, len(unused))
+}
+
+func TestRegenerateRecoveryCodes_InvalidCode(t *testing.T) {
+	ctx := context.Background()
+	userID := rand.Int63()
+	now := time.Now()
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetUserMFA", mock.Anything, userID).Return(&data.UserMFA{
+		UserID:      userID,
+		ConfirmedAt: &now,
+	}, nil)
+	mockDAL.On("GetUnusedRecoveryCodes", mock.Anything, userID).Return([]*data.RecoveryCode{}, nil)
+
+	manager := newManager(t, mockDAL)
+	_, err := manager.RegenerateRecoveryCodes(ctx, userID, "abcde-fghij")
+	assert.Equal(t, err, credentials.ErrInvalidMFACode)
+	mockDAL.AssertNotCalled(t, "ReplaceRecoveryCodes", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerify_OutsideSkew(t *testing.T) {
+	ctx := context.Background()
+	userID := rand.Int63()
+	now := time.Now()
+	secret := []byte("12345678901234567890")
+
+	mockDAL := &dalMock.DataProvider{}
+	manager := newManager(t, mockDAL)
+	manager.now = func() time.Time { return now }
+
+	encrypted, err := manager.encrypt(userID, secret)
+	assert.Equal(t, err, nil)
+	mockDAL.On("GetUserMFA", mock.Anything, userID).Return(&data.UserMFA{
+		UserID:          userID,
+		EncryptedSecret: encrypted,
+		ConfirmedAt:     &now,
+	}, nil)
+
+	step := now.Unix() / 30
+	err = manager.Verify(ctx, userID, Code(secret, step-2))
+	assert.Equal(t, err, credentials.ErrInvalidMFACode)
+	err = manager.Verify(ctx, userID, Code(secret, step+2))
+	assert.Equal(t, err, credentials.ErrInvalidMFACode)
+	mockDAL.AssertNotCalled(t, "UseMFAStep", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerify_ConcurrentReplay(t *testing.T) {
+	ctx := context.Background()
+	userID := rand.Int63()
+	now := time.Now()
+	secret := []byte("12345678901234567890")
+
+	mockDAL := &dalMock.DataProvider{}
+	manager := newManager(t, mockDAL)
+	manager.now = func() time.Time { return now }
+
+	encrypted, err := manager.encrypt(userID, secret)
+	assert.Equal(t, err, nil)
+	mockDAL.On("GetUserMFA", mock.Anything, userID).Return(&data.UserMFA{
+		UserID:          userID,
+		EncryptedSecret: encrypted,
+		ConfirmedAt:     &now,
+	}, nil)
+	// Another request used the step between fetching the record and recording the code
+	mockDAL.On("UseMFAStep", mock.Anything, userID, mock.Anything).Return(data.ErrMFAStepUsed)
+
+	err = manager.Verify(ctx, userID, Code(secret, now.Unix()/30))
+	assert.Equal(t, err, credentials.ErrInvalidMFACode)
+}
+
+func TestDecrypt_OtherUser(t *testing.T) {
+	manager := newManager(t, &dalMock.DataProvider{})
+
+	encrypted, err := manager.encrypt(1, []byte("12345678901234567890"))
+	assert.Equal(t, err, nil)
+
+	_, err = manager.decrypt(2, encrypted)
+	assert.NotEqual(t, err, nil)
+
+	secret, err := manager.decrypt(1, encrypted)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(secret), "12345678901234567890")
+}
+
+func TestGetChallenge_Expired(t *testing.T) {
+	now := time.Now()
+	challenge := &data.MFAChallenge{
+		ID:        "challenge",
+		UserID:    rand.Int63(),
+		ExpiresAt: now.Add(-time.Second),
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetMFAChallengeByID", mock.Anything, challenge.ID).Return(challenge, nil)
+
+	manager := newManager(t, mockDAL)
+	manager.now = func() time.Time { return now }
+
+	_, err := manager.GetChallenge(context.Background(), challenge.ID)
+	assert.Equal(t, err, credentials.ErrInvalidMFAChallenge)
+}
@@ -0,0 +1,7 @@
+ALTER TABLE authorizations
+    DROP COLUMN IF EXISTS code_challenge_method,
+    DROP COLUMN IF EXISTS code_challenge;
+
+ALTER TABLE clients
+    DROP COLUMN IF EXISTS is_public,
+    ALTER COLUMN hashed_secret SET NOT NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE clients
+    ALTER COLUMN hashed_secret DROP NOT NULL,
+    ADD COLUMN is_public BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE authorizations
+    ADD COLUMN code_challenge VARCHAR(128),
+    ADD COLUMN code_challenge_method VARCHAR(10);
@@ -0,0 +1,4 @@
+ALTER TABLE authorizations
+    DROP COLUMN IF EXISTS redirect_uri;
+
+DROP TABLE IF EXISTS client_redirect_uris;
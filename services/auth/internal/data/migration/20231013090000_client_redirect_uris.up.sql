@@ -0,0 +1,10 @@
+CREATE TABLE client_redirect_uris (
+    id SERIAL PRIMARY KEY,
+    client_id INT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    redirect_uri VARCHAR(2048) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (client_id, redirect_uri)
+);
+
+ALTER TABLE authorizations
+    ADD COLUMN redirect_uri VARCHAR(2048);
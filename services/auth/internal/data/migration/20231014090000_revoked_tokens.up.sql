@@ -0,0 +1,9 @@
+CREATE TABLE revoked_tokens (
+    token_id VARCHAR(64) PRIMARY KEY,
+    grant_id VARCHAR(64),
+    revoked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX revoked_tokens_grant_id_idx ON revoked_tokens (grant_id);
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
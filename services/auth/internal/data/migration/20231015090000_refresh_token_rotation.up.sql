@@ -0,0 +1,19 @@
+CREATE TABLE token_families (
+    id VARCHAR(64) PRIMARY KEY,
+    auth_code VARCHAR(255) REFERENCES authorizations(auth_code),
+    user_id INT REFERENCES users(id),
+    client_id INT NOT NULL REFERENCES clients(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX token_families_auth_code_idx ON token_families (auth_code);
+
+CREATE TABLE refresh_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    family_id VARCHAR(64) NOT NULL REFERENCES token_families(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
@@ -0,0 +1,2 @@
+ALTER TABLE authorizations
+    DROP COLUMN IF EXISTS used_at;
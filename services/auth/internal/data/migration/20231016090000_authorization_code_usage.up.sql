@@ -0,0 +1,2 @@
+ALTER TABLE authorizations
+    ADD COLUMN used_at TIMESTAMP;
@@ -0,0 +1,16 @@
+CREATE TABLE authorization_requests (
+    id VARCHAR(64) PRIMARY KEY,
+    session_id VARCHAR(128) NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id),
+    client_id INT NOT NULL REFERENCES clients(id),
+    redirect_uri VARCHAR(2048) NOT NULL,
+    scope VARCHAR(255),
+    state VARCHAR(512),
+    code_challenge VARCHAR(128),
+    code_challenge_method VARCHAR(10),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX authorization_requests_expires_at_idx ON authorization_requests (expires_at);
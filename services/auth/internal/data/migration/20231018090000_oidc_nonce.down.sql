@@ -0,0 +1,7 @@
+ALTER TABLE authorizations
+    DROP COLUMN IF EXISTS auth_time,
+    DROP COLUMN IF EXISTS nonce;
+
+ALTER TABLE authorization_requests
+    DROP COLUMN IF EXISTS auth_time,
+    DROP COLUMN IF EXISTS nonce;
@@ -0,0 +1,7 @@
+ALTER TABLE authorization_requests
+    ADD COLUMN nonce VARCHAR(255),
+    ADD COLUMN auth_time TIMESTAMP;
+
+ALTER TABLE authorizations
+    ADD COLUMN nonce VARCHAR(255),
+    ADD COLUMN auth_time TIMESTAMP;
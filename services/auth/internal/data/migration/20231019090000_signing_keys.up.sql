@@ -0,0 +1,14 @@
+CREATE TABLE signing_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL,
+    private_key BYTEA NOT NULL,
+    public_key BYTEA NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    activated_at TIMESTAMP,
+    retired_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+-- At most one active and one next key at any time.
+CREATE UNIQUE INDEX signing_keys_status_idx ON signing_keys (status) WHERE status IN ('active', 'next');
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS login_attempts;
+CREATE TABLE login_attempts (
+    key VARCHAR(255) PRIMARY KEY,
+    failures INT NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX login_attempts_last_failure_at_idx ON login_attempts (last_failure_at);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS password_resets;
+CREATE TABLE password_resets (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX password_resets_user_id_idx ON password_resets (user_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS password_history;
+CREATE TABLE password_history (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hashed_password VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX password_history_user_id_idx ON password_history (user_id);
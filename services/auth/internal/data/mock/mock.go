@@ -2,6 +2,7 @@ package mock
 
 import (
 	"context"
+	"time"
 
 	"github.com/ramyadmz/goauth/internal/data"
 	"github.com/stretchr/testify/mock"
@@ -22,6 +23,16 @@ func (m *DataProvider) GetUserByID(ctx context.Context, userID int64) (*data.Use
 	args := m.Called(ctx, userID)
 	return args.Get(0).(*data.User), args.Error(1)
 }
+func (m *DataProvider) GetUserByEmail(ctx context.Context, email string) (*data.User, error) {
+	args := m.Called(ctx, email)
+	return args.Get(0).(*data.User), args.Error(1)
+}
+
+func (m *DataProvider) UpdateUserPassword(ctx context.Context, userID int64, hashedPassword []byte) error {
+	args := m.Called(ctx, userID, hashedPassword)
+	return args.Error(0)
+}
+
 func (m *DataProvider) GetUserByUsername(ctx context.Context, username string) (*data.User, error) {
 	args := m.Called(ctx, username)
 	return args.Get(0).(*data.User), args.Error(1)
@@ -35,6 +46,17 @@ func (m *DataProvider) DeleteSessionByID(ctx context.Context, sessionID string)
 	args := m.Called(ctx, sessionID)
 	return args.Error(0)
 }
+
+func (m *DataProvider) DeleteSessionsByUserID(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *DataProvider) DeleteOtherSessionsByUserID(ctx context.Context, userID int64, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
 func (m *DataProvider) UpdateSession(ctx context.Context, params data.UpdateSessionParams) (*data.Session, error) {
 	args := m.Called(ctx, params)
 	return args.Get(0).(*data.Session), args.Error(1)
@@ -64,12 +86,239 @@ func (m *DataProvider) GetAuthorizationCodeByAuthCode(ctx context.Context, authC
 	return args.Get(0).(*data.Authorization), args.Error(1)
 }
 
-func (m *DataProvider) GetAuthorizationCodeByUserIDAndClientID(ctx context.Context, clientID, userID int64) (*data.Authorization, error) {
-	args := m.Called(ctx, clientID, userID)
-	return args.Get(0).(*data.Authorization), args.Error(1)
+func (m *DataProvider) RevokeAuthorizationByUserID(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
 }
 
-func (m *DataProvider) RevokeAuthorizationByUserID(ctx context.Context, userID int64) error {
+func (m *DataProvider) ConsumeAuthorizationCode(ctx context.Context, authCode string, check func(*data.Authorization) error) (*data.Authorization, error) {
+	args := m.Called(ctx, authCode)
+	authorization, err := args.Get(0).(*data.Authorization), args.Error(1)
+	if err != nil {
+		return authorization, err
+	}
+	if err := check(authorization); err != nil {
+		return nil, err
+	}
+	return authorization, nil
+}
+
+func (m *DataProvider) RevokeAuthorizationCode(ctx context.Context, authCode string) error {
+	args := m.Called(ctx, authCode)
+	return args.Error(0)
+}
+
+func (m *DataProvider) CreateAuthorizationRequest(ctx context.Context, params data.CreateAuthorizationRequestParams) (*data.AuthorizationRequest, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*data.AuthorizationRequest), args.Error(1)
+}
+
+func (m *DataProvider) GetAuthorizationRequestByID(ctx context.Context, requestID string) (*data.AuthorizationRequest, error) {
+	args := m.Called(ctx, requestID)
+	return args.Get(0).(*data.AuthorizationRequest), args.Error(1)
+}
+
+func (m *DataProvider) ResolveAuthorizationRequest(ctx context.Context, requestID string, status data.AuthorizationRequestStatus) (*data.AuthorizationRequest, error) {
+	args := m.Called(ctx, requestID, status)
+	return args.Get(0).(*data.AuthorizationRequest), args.Error(1)
+}
+
+func (m *DataProvider) DeleteExpiredAuthorizationRequests(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *DataProvider) RevokeToken(ctx context.Context, params data.RevokeTokenParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *DataProvider) IsTokenRevoked(ctx context.Context, tokenID, grantID string) (bool, error) {
+	args := m.Called(ctx, tokenID, grantID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *DataProvider) DeleteExpiredRevokedTokens(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *DataProvider) CreateTokenFamily(ctx context.Context, params data.CreateTokenFamilyParams) (*data.TokenFamily, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*data.TokenFamily), args.Error(1)
+}
+
+func (m *DataProvider) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	args := m.Called(ctx, familyID)
+	return args.Error(0)
+}
+
+func (m *DataProvider) RevokeTokenFamiliesByUserID(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *DataProvider) CreateRefreshToken(ctx context.Context, params data.CreateRefreshTokenParams) (*data.RefreshToken, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*data.RefreshToken), args.Error(1)
+}
+
+func (m *DataProvider) UseRefreshToken(ctx context.Context, tokenHash string) (*data.RefreshToken, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*data.RefreshToken), args.Error(1)
+}
+
+func (m *DataProvider) CreateSigningKey(ctx context.Context, params data.CreateSigningKeyParams) (*data.SigningKey, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*data.SigningKey), args.Error(1)
+}
+
+func (m *DataProvider) GetSigningKeys(ctx context.Context) ([]*data.SigningKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*data.SigningKey), args.Error(1)
+}
+
+func (m *DataProvider) RotateSigningKeys(ctx context.Context, params data.RotateSigningKeysParams) (bool, error) {
+	args := m.Called(ctx, params)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *DataProvider) DeleteExpiredSigningKeys(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *DataProvider) RecordLoginAttempt(ctx context.Context, key string, resetBefore time.Time, allow func(*data.LoginAttempts) bool) (*data.LoginAttempts, error) {
+	args := m.Called(ctx, key, resetBefore)
+	return args.Get(0).(*data.LoginAttempts), args.Error(1)
+}
+
+func (m *DataProvider) ReleaseLoginAttempts(ctx context.Context, keys []string) error {
+	args := m.Called(ctx, keys)
+	return args.Error(0)
+}
+
+func (m *DataProvider) ResetLoginAttempts(ctx context.Context, keys []string) error {
+	args := m.Called(ctx, keys)
+	return args.Error(0)
+}
+
+func (m *DataProvider) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error {
+	args := m.Called(ctx, before)
+	return args.Error(0)
+}
+
+func (m *DataProvider) CreateUserMFA(ctx context.Context, params data.CreateUserMFAParams) (*data.UserMFA, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*data.UserMFA), args.Error(1)
+}
+
+func (m *DataProvider) GetUserMFA(ctx context.Context, userID int64) (*data.UserMFA, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*data.UserMFA), args.Error(1)
+}
+
+func (m *DataProvider) ConfirmUserMFA(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *DataProvider) UseMFAStep(ctx context.Context, userID int64, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *DataProvider) DeleteUserMFA(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *DataProvider) CreateEmailVerification(ctx context.Context, params data.CreateEmailVerificationParams) (*data.EmailVerification, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*data.EmailVerification), args.Error(1)
+}
+
+func (m *DataProvider) GetLatestEmailVerification(ctx context.Context, userID int64) (*data.EmailVerification, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*data.EmailVerification), args.Error(1)
+}
+
+func (m *DataProvider) UseEmailVerification(ctx context.Context, tokenHash string) (*data.EmailVerification, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*data.EmailVerification), args.Error(1)
+}
+
+func (m *DataProvider) DeleteExpiredEmailVerifications(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *DataProvider) CreatePasswordReset(ctx context.Context, params data.CreatePasswordResetParams) (*data.PasswordReset, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*data.PasswordReset), args.Error(1)
+}
+
+func (m *DataProvider) GetPasswordReset(ctx context.Context, tokenHash string) (*data.PasswordReset, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*data.PasswordReset), args.Error(1)
+}
+
+func (m *DataProvider) GetLatestPasswordReset(ctx context.Context, userID int64) (*data.PasswordReset, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*data.PasswordReset), args.Error(1)
+}
+
+func (m *DataProvider) UsePasswordReset(ctx context.Context, tokenHash string, hashedPassword []byte) (*data.PasswordReset, error) {
+	args := m.Called(ctx, tokenHash, hashedPassword)
+	return args.Get(0).(*data.PasswordReset), args.Error(1)
+}
+
+func (m *DataProvider) DeleteExpiredPasswordResets(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *DataProvider) AddPasswordHistory(ctx context.Context, userID int64, hashedPassword []byte, keep int) error {
+	args := m.Called(ctx, userID, hashedPassword, keep)
+	return args.Error(0)
+}
+
+func (m *DataProvider) GetPasswordHistory(ctx context.Context, userID int64, limit int) ([]*data.PasswordHistory, error) {
+	args := m.Called(ctx, userID, limit)
+	return args.Get(0).([]*data.PasswordHistory), args.Error(1)
+}
+
+func (m *DataProvider) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashedCodes []string) error {
+	args := m.Called(ctx, userID, hashedCodes)
+	return args.Error(0)
+}
+
+func (m *DataProvider) GetUnusedRecoveryCodes(ctx context.Context, userID int64) ([]*data.RecoveryCode, error) {
 	args := m.Called(ctx, userID)
+	return args.Get(0).([]*data.RecoveryCode), args.Error(1)
+}
+
+func (m *DataProvider) UseRecoveryCode(ctx context.Context, codeID int64) error {
+	args := m.Called(ctx, codeID)
+	return args.Error(0)
+}
+
+func (m *DataProvider) CreateMFAChallenge(ctx context.Context, params data.CreateMFAChallengeParams) (*data.MFAChallenge, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*data.MFAChallenge), args.Error(1)
+}
+
+func (m *DataProvider) GetMFAChallengeByID(ctx context.Context, challengeID string) (*data.MFAChallenge, error) {
+	args := m.Called(ctx, challengeID)
+	return args.Get(0).(*data.MFAChallenge), args.Error(1)
+}
+
+func (m *DataProvider) DeleteMFAChallenge(ctx context.Context, challengeID string) error {
+	args := m.Called(ctx, challengeID)
+	return args.Error(0)
+}
+
+func (m *DataProvider) DeleteExpiredMFAChallenges(ctx context.Context) error {
+	args := m.Called(ctx)
 	return args.Error(0)
 }
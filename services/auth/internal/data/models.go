@@ -7,6 +7,8 @@ type User struct {
 	Username       string
 	HashedPassword []byte
 	Email          string
+	EmailVerified  bool
+	Roles          []string
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 }
@@ -24,16 +26,158 @@ type Client struct {
 	Name         string
 	Website      string
 	Scope        string
+	IsPublic     bool
+	RedirectURIs []string
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
 
 type Authorization struct {
+	AuthCode            string
+	UserID              int64
+	ClientID            int64
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	RedirectURI         string
+	Nonce               string
+	AuthTime            time.Time
+	CreatedAt           time.Time
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+	IsRevoked           bool
+}
+
+// AuthorizationRequestStatus is the state of a pending authorization request
+type AuthorizationRequestStatus string
+
+const (
+	AuthorizationRequestPending  AuthorizationRequestStatus = "pending"
+	AuthorizationRequestApproved AuthorizationRequestStatus = "approved"
+	AuthorizationRequestDenied   AuthorizationRequestStatus = "denied"
+)
+
+// AuthorizationRequest is an authorization request awaiting the user's consent.
+type AuthorizationRequest struct {
+	ID                  string
+	SessionID           string
+	UserID              int64
+	ClientID            int64
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	AuthTime            time.Time
+	Status              AuthorizationRequestStatus
+	CreatedAt           time.Time
+	ExpiresAt           time.Time
+}
+
+type RevokedToken struct {
+	TokenID   string
+	GrantID   string
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
+
+// TokenFamily groups the refresh tokens rotated from a single authorization grant.
+type TokenFamily struct {
+	ID        string
 	AuthCode  string
 	UserID    int64
 	ClientID  int64
-	Scope     string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+type RefreshToken struct {
+	TokenHash string
+	FamilyID  string
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// SigningKeyStatus is the lifecycle state of a token signing key
+type SigningKeyStatus string
+
+const (
+	SigningKeyNext    SigningKeyStatus = "next"    // published, becomes active on the next rotation
+	SigningKeyActive  SigningKeyStatus = "active"  // new tokens are signed with it
+	SigningKeyRetired SigningKeyStatus = "retired" // only verifies tokens until ExpiresAt
+)
+
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  []byte // PKCS #8 DER, encrypted with AES-GCM
+	PublicKey   []byte // PKIX DER
+	Status      SigningKeyStatus
+	CreatedAt   time.Time
+	ActivatedAt *time.Time
+	RetiredAt   *time.Time
+	ExpiresAt   *time.Time
+}
+
+// LoginAttempts counts the recent failed authentication attempts for a throttling key, such as a
+// username, a client id or a source IP address.
+type LoginAttempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+}
+
+// UserMFA is the TOTP authenticator of a user. It only protects logins once ConfirmedAt is set.
+type UserMFA struct {
+	UserID          int64
+	EncryptedSecret []byte
+	LastUsedStep    int64 // time step of the last accepted code, older codes are replays
+	CreatedAt       time.Time
+	ConfirmedAt     *time.Time
+}
+
+// EmailVerification is a pending verification of a user's email address, identified by the hash of
+// the token sent to it.
+type EmailVerification struct {
+	TokenHash string
+	UserID    int64
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// PasswordReset is a pending reset of a user's password, identified by the hash of the token mailed
+// to the user.
+type PasswordReset struct {
+	TokenHash string
+	UserID    int64
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// PasswordHistory is a password a user has set, kept to prevent its reuse.
+type PasswordHistory struct {
+	ID             int64
+	UserID         int64
+	HashedPassword []byte
+	CreatedAt      time.Time
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the authenticator is lost.
+type RecoveryCode struct {
+	ID         int64
+	UserID     int64
+	HashedCode string
+	CreatedAt  time.Time
+	UsedAt     *time.Time
+}
+
+// MFAChallenge is handed out by a password login of a user with MFA enabled, to be exchanged for a
+// session together with a TOTP code.
+type MFAChallenge struct {
+	ID        string
+	UserID    int64
 	CreatedAt time.Time
 	ExpiresAt time.Time
-	IsRevoked bool
 }
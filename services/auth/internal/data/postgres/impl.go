@@ -62,7 +62,7 @@ func (p *DataProvider) GetUserByID(ctx context.Context, userID int64) (*data.Use
 	logger := logrus.WithContext(ctx).WithField("userID", userID)
 	user := &User{ID: userID}
 
-	err := p.db.Model(user).WherePK().Select(ctx)
+	err := p.db.Model(user).Relation("Roles").WherePK().Select(ctx)
 	if err != nil {
 		if err == pg.ErrNoRows {
 			logger.Error(data.ErrUserNotFound)
@@ -80,7 +80,7 @@ func (p *DataProvider) GetUserByUsername(ctx context.Context, username string) (
 	logger := logrus.WithContext(ctx).WithField("username", username)
 	user := &User{}
 
-	err := p.db.Model(user).Where("username = ?", username).Select(ctx)
+	err := p.db.Model(user).Relation("Roles").Where("username = ?", username).Select(ctx)
 	if err != nil {
 		if err == pg.ErrNoRows {
 			logger.Error(data.ErrUserNotFound)
@@ -94,6 +94,48 @@ func (p *DataProvider) GetUserByUsername(ctx context.Context, username string) (
 	return user.ToData(), nil
 }
 
+// GetUserByEmail retrieves a user by their email address from the database.
+func (p *DataProvider) GetUserByEmail(ctx context.Context, email string) (*data.User, error) {
+	logger := logrus.WithContext(ctx).WithField("email", email)
+	user := &User{}
+
+	err := p.db.Model(user).Relation("Roles").Where("email = ?", email).Select(ctx)
+	if err != nil {
+		if err == pg.ErrNoRows {
+			logger.Warn(data.ErrUserNotFound)
+			return nil, data.ErrUserNotFound
+		}
+		logger.Error("error fetching user by email: %w", err)
+		return nil, err
+	}
+
+	logger.Info("user fetched by email successfully")
+	return user.ToData(), nil
+}
+
+// UpdateUserPassword replaces the hashed password of a user. It returns data.ErrUserNotFound if the
+// user doesn't exist.
+func (p *DataProvider) UpdateUserPassword(ctx context.Context, userID int64, hashedPassword []byte) error {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+
+	res, err := p.db.Model(&User{}).
+		Set("hashed_password = ?", hashedPassword).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", userID).
+		Update(ctx)
+	if err != nil {
+		logger.Error("error updating user password: %w", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		logger.Warn(data.ErrUserNotFound)
+		return data.ErrUserNotFound
+	}
+
+	logger.Info("user password updated successfully")
+	return nil
+}
+
 // CreateSession creates a new session in the database.
 func (p *DataProvider) CreateSession(ctx context.Context, params data.CreateSessionParams) (*data.Session, error) {
 	logger := logrus.WithContext(ctx).WithField("userID", params.UserID)
@@ -103,7 +145,7 @@ func (p *DataProvider) CreateSession(ctx context.Context, params data.CreateSess
 		ExpiresAt: params.ExpiresAt,
 	}
 
-	_, err := p.db.Model(session).Insert(ctx)
+	_, err := p.db.Model(session).Returning("*").Insert(ctx)
 	if err != nil {
 		logger.Errorf("failed to insert new session record: %s", err)
 		return nil, fmt.Errorf("failed to insert new session record: %w", err)
@@ -148,6 +190,34 @@ func (p *DataProvider) DeleteSessionByID(ctx context.Context, sessionID string)
 	return nil
 }
 
+// DeleteSessionsByUserID deletes every session of a user.
+func (p *DataProvider) DeleteSessionsByUserID(ctx context.Context, userID int64) error {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+
+	res, err := p.db.Model(&Session{}).Where("user_id = ?", userID).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting sessions by user id: %w", err)
+		return err
+	}
+
+	logger.WithField("count", res.RowsAffected()).Info("user sessions deleted successfully")
+	return nil
+}
+
+// DeleteOtherSessionsByUserID deletes every session of a user but the given one.
+func (p *DataProvider) DeleteOtherSessionsByUserID(ctx context.Context, userID int64, sessionID string) error {
+	logger := logrus.WithContext(ctx).WithField("userID", userID).WithField("sessionID", sessionID)
+
+	res, err := p.db.Model(&Session{}).Where("user_id = ?", userID).Where("id <> ?", sessionID).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting other sessions by user id: %w", err)
+		return err
+	}
+
+	logger.WithField("count", res.RowsAffected()).Info("other user sessions deleted successfully")
+	return nil
+}
+
 func (p *DataProvider) UpdateSession(ctx context.Context, params data.UpdateSessionParams) (*data.Session, error) {
 	logger := logrus.WithContext(ctx).WithField("sessionID", params.SessionID).WithField("expireDate", params.ExpiresAt)
 	session := &Session{
@@ -173,14 +243,33 @@ func (p *DataProvider) CreateClient(ctx context.Context, params data.CreateClien
 		HashedSecret: []byte(params.HashedSecret),
 		Website:      params.Website,
 		Scope:        params.Scope,
+		IsPublic:     params.IsPublic,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
-	_, err := p.db.Model(client).Returning("id").Insert(ctx)
+	err := p.db.RunInTransaction(ctx, func(ctx context.Context, tx *pg.Tx) error {
+		if _, err := tx.Model(client).Returning("id").Insert(ctx); err != nil {
+			return fmt.Errorf("failed to insert new client record: %w", err)
+		}
+
+		for _, uri := range params.RedirectURIs {
+			client.RedirectURIs = append(client.RedirectURIs, &ClientRedirectURI{
+				ClientID:    client.ID,
+				RedirectURI: uri,
+			})
+		}
+
+		if len(client.RedirectURIs) > 0 {
+			if _, err := tx.Model(&client.RedirectURIs).Insert(ctx); err != nil {
+				return fmt.Errorf("failed to insert client redirect uris: %w", err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		logger.Error("error creating client: %w", err)
-		return nil, fmt.Errorf("failed to insert new client record: %w", err)
+		return nil, err
 	}
 
 	logger.Info("client created successfully")
@@ -193,7 +282,7 @@ func (p *DataProvider) GetClientByID(ctx context.Context, clientID int64) (*data
 		ID: clientID,
 	}
 
-	err := p.db.Model(client).WherePK().Select(ctx)
+	err := p.db.Model(client).Relation("RedirectURIs").WherePK().Select(ctx)
 	if err != nil {
 		if err == pg.ErrNoRows {
 			logger.Error(data.ErrClientNotFound)
@@ -210,12 +299,17 @@ func (p *DataProvider) GetClientByID(ctx context.Context, clientID int64) (*data
 func (p *DataProvider) CreateAuthorization(ctx context.Context, params data.CreateAuthorizationParams) (*data.Authorization, error) {
 	logger := logrus.WithContext(ctx).WithField("clientID", params.ClientID).WithField("userID", params.UserID).WithField("scope", params.Scope)
 	authorization := &Authorization{
-		AuthCode:  uuid.NewString(),
-		ClientID:  params.ClientID,
-		UserID:    params.UserID,
-		Scope:     params.Scope,
-		ExpiresAt: time.Now().Add(10 * time.Minute),
-		IsRevoked: false,
+		AuthCode:            uuid.NewString(),
+		ClientID:            params.ClientID,
+		UserID:              params.UserID,
+		Scope:               params.Scope,
+		CodeChallenge:       params.CodeChallenge,
+		CodeChallengeMethod: params.CodeChallengeMethod,
+		RedirectURI:         params.RedirectURI,
+		Nonce:               params.Nonce,
+		AuthTime:            params.AuthTime,
+		ExpiresAt:           time.Now().Add(10 * time.Minute),
+		IsRevoked:           false,
 	}
 
 	_, err := p.db.Model(authorization).Insert(ctx)
@@ -248,36 +342,1126 @@ func (p *DataProvider) GetAuthorizationCodeByAuthCode(ctx context.Context, authC
 	return authorization.ToData(), nil
 }
 
-func (p *DataProvider) GetAuthorizationCodeByUserIDAndClientID(ctx context.Context, userID, clientID int64) (*data.Authorization, error) {
-	logger := logrus.WithContext(ctx).WithField("userID", userID).WithField("clientID", clientID)
-	authorization := &Authorization{}
+func (p *DataProvider) RevokeAuthorizationByUserID(ctx context.Context, userID int64) error {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
 
-	err := p.db.Model(authorization).Where("client_id = ? AND user_id = ?", clientID, userID).Select(ctx)
+	_, err := p.db.Model(&Authorization{}).
+		Where("user_id = ? ", userID).
+		Set("is_revoked = ?", true).
+		Update(ctx)
 	if err != nil {
-		if err == pg.ErrNoRows {
-			logger.Error(data.ErrAuthorizationNotFound)
+		logger.Error("error updating authorization: %w", err)
+		return err
+	}
+
+	logger.Info("authorization updated successfully")
+	return nil
+}
+
+// ConsumeAuthorizationCode marks an authorization code as used, locking its row so concurrent
+// exchanges of the same code can't both succeed. The code is only marked as used if check accepts
+// it; otherwise the error of check is returned and the code stays usable. If the code had already
+// been used it is returned together with data.ErrAuthorizationCodeUsed.
+func (p *DataProvider) ConsumeAuthorizationCode(ctx context.Context, authCode string, check func(*data.Authorization) error) (*data.Authorization, error) {
+	logger := logrus.WithContext(ctx)
+	authorization := &Authorization{
+		AuthCode: authCode,
+	}
+
+	var checkErr error
+	err := p.db.RunInTransaction(ctx, func(ctx context.Context, tx *pg.Tx) error {
+		if err := tx.Model(authorization).WherePK().For("UPDATE").Select(ctx); err != nil {
+			return err
+		}
+
+		if authorization.UsedAt != nil {
+			return data.ErrAuthorizationCodeUsed
+		}
+
+		if checkErr = check(authorization.ToData()); checkErr != nil {
+			return checkErr
+		}
+
+		usedAt := time.Now()
+		authorization.UsedAt = &usedAt
+		_, err := tx.Model(authorization).Set("used_at = ?", usedAt).WherePK().Update(ctx)
+		return err
+	})
+	if checkErr != nil {
+		logger.Warn("authorization code rejected: %w", checkErr)
+		return nil, checkErr
+	}
+	if err != nil {
+		switch err {
+		case pg.ErrNoRows:
+			logger.Warn("invalid auth code: %w", err)
 			return nil, data.ErrAuthorizationNotFound
+		case data.ErrAuthorizationCodeUsed:
+			logger.Warn(data.ErrAuthorizationCodeUsed)
+			return authorization.ToData(), data.ErrAuthorizationCodeUsed
 		}
-		logger.Error("error fetching authorization: %w", err)
+		logger.Error("error consuming authorization code: %w", err)
 		return nil, err
 	}
 
-	logger.Info("authorization fetched successfully")
+	logger.Info("authorization code consumed successfully")
 	return authorization.ToData(), nil
 }
 
-func (p *DataProvider) RevokeAuthorizationByUserID(ctx context.Context, userID int64) error {
+// RevokeAuthorizationCode revokes an authorization code together with every token family issued from it.
+func (p *DataProvider) RevokeAuthorizationCode(ctx context.Context, authCode string) error {
+	logger := logrus.WithContext(ctx)
+
+	err := p.db.RunInTransaction(ctx, func(ctx context.Context, tx *pg.Tx) error {
+		_, err := tx.Model(&Authorization{}).
+			Set("is_revoked = ?", true).
+			Where("auth_code = ?", authCode).
+			Update(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Model(&TokenFamily{}).
+			Set("revoked_at = ?", time.Now()).
+			Where("auth_code = ?", authCode).
+			Where("revoked_at IS NULL").
+			Update(ctx)
+		return err
+	})
+	if err != nil {
+		logger.Error("error revoking authorization code: %w", err)
+		return err
+	}
+
+	logger.Info("authorization code revoked successfully")
+	return nil
+}
+
+// CreateAuthorizationRequest stores a new pending authorization request.
+func (p *DataProvider) CreateAuthorizationRequest(ctx context.Context, params data.CreateAuthorizationRequestParams) (*data.AuthorizationRequest, error) {
+	logger := logrus.WithContext(ctx).WithField("clientID", params.ClientID).WithField("userID", params.UserID)
+	request := &AuthorizationRequest{
+		ID:                  uuid.NewString(),
+		SessionID:           params.SessionID,
+		UserID:              params.UserID,
+		ClientID:            params.ClientID,
+		RedirectURI:         params.RedirectURI,
+		Scope:               params.Scope,
+		State:               params.State,
+		CodeChallenge:       params.CodeChallenge,
+		CodeChallengeMethod: params.CodeChallengeMethod,
+		Nonce:               params.Nonce,
+		AuthTime:            params.AuthTime,
+		Status:              string(data.AuthorizationRequestPending),
+		ExpiresAt:           params.ExpiresAt,
+	}
+
+	_, err := p.db.Model(request).Returning("*").Insert(ctx)
+	if err != nil {
+		logger.Error("error creating authorization request: %w", err)
+		return nil, fmt.Errorf("failed to insert new authorization request record: %w", err)
+	}
+
+	logger.Info("authorization request created successfully")
+	return request.ToData(), nil
+}
+
+// GetAuthorizationRequestByID retrieves an authorization request by its ID.
+func (p *DataProvider) GetAuthorizationRequestByID(ctx context.Context, requestID string) (*data.AuthorizationRequest, error) {
+	logger := logrus.WithContext(ctx).WithField("requestID", requestID)
+	request := &AuthorizationRequest{
+		ID: requestID,
+	}
+
+	err := p.db.Model(request).WherePK().Select(ctx)
+	if err != nil {
+		if err == pg.ErrNoRows {
+			logger.Warn(data.ErrAuthRequestNotFound)
+			return nil, data.ErrAuthRequestNotFound
+		}
+		logger.Error("error fetching authorization request: %w", err)
+		return nil, err
+	}
+
+	logger.Info("authorization request fetched successfully")
+	return request.ToData(), nil
+}
+
+// ResolveAuthorizationRequest moves a pending, unexpired authorization request to the given status.
+// Requests that were already resolved or have expired are reported as data.ErrAuthRequestNotFound,
+// so each request can be resolved at most once.
+func (p *DataProvider) ResolveAuthorizationRequest(ctx context.Context, requestID string, status data.AuthorizationRequestStatus) (*data.AuthorizationRequest, error) {
+	logger := logrus.WithContext(ctx).WithField("requestID", requestID).WithField("status", status)
+	request := &AuthorizationRequest{}
+
+	res, err := p.db.Model(request).
+		Set("status = ?", string(status)).
+		Where("id = ?", requestID).
+		Where("status = ?", string(data.AuthorizationRequestPending)).
+		Where("expires_at > ?", time.Now()).
+		Returning("*").
+		Update(ctx)
+	if err != nil && err != pg.ErrNoRows {
+		logger.Error("error resolving authorization request: %w", err)
+		return nil, err
+	}
+
+	if err == pg.ErrNoRows || res.RowsAffected() == 0 {
+		logger.Warn(data.ErrAuthRequestNotFound)
+		return nil, data.ErrAuthRequestNotFound
+	}
+
+	logger.Info("authorization request resolved successfully")
+	return request.ToData(), nil
+}
+
+// DeleteExpiredAuthorizationRequests removes authorization requests past their expiry.
+func (p *DataProvider) DeleteExpiredAuthorizationRequests(ctx context.Context) error {
+	logger := logrus.WithContext(ctx)
+
+	res, err := p.db.Model(&AuthorizationRequest{}).Where("expires_at < ?", time.Now()).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting expired authorization requests: %w", err)
+		return err
+	}
+
+	logger.WithField("count", res.RowsAffected()).Info("expired authorization requests deleted successfully")
+	return nil
+}
+
+// RevokeToken adds a token, and optionally its grant, to the revocation denylist.
+func (p *DataProvider) RevokeToken(ctx context.Context, params data.RevokeTokenParams) error {
+	logger := logrus.WithContext(ctx).WithField("tokenID", params.TokenID).WithField("grantID", params.GrantID)
+	revoked := &RevokedToken{
+		TokenID:   params.TokenID,
+		GrantID:   params.GrantID,
+		ExpiresAt: params.ExpiresAt,
+	}
+
+	_, err := p.db.Model(revoked).OnConflict("(token_id) DO NOTHING").Insert(ctx)
+	if err != nil {
+		logger.Error("error revoking token: %w", err)
+		return fmt.Errorf("failed to insert revoked token record: %w", err)
+	}
+
+	logger.Info("token revoked successfully")
+	return nil
+}
+
+// IsTokenRevoked reports whether the token itself or the grant it was issued from has been revoked,
+// either through the denylist or by revoking its token family.
+func (p *DataProvider) IsTokenRevoked(ctx context.Context, tokenID, grantID string) (bool, error) {
+	logger := logrus.WithContext(ctx).WithField("tokenID", tokenID).WithField("grantID", grantID)
+
+	query := p.db.Model(&RevokedToken{}).Where("token_id = ?", tokenID)
+	if len(grantID) > 0 {
+		query = query.WhereOr("grant_id = ?", grantID)
+	}
+
+	exists, err := query.Exists(ctx)
+	if err != nil {
+		logger.Error("error checking revoked token: %w", err)
+		return false, err
+	}
+
+	if exists || len(grantID) == 0 {
+		return exists, nil
+	}
+
+	exists, err = p.db.Model(&TokenFamily{}).Where("id = ?", grantID).Where("revoked_at IS NOT NULL").Exists(ctx)
+	if err != nil {
+		logger.Error("error checking revoked token family: %w", err)
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// DeleteExpiredRevokedTokens removes denylist entries whose tokens have expired on their own.
+func (p *DataProvider) DeleteExpiredRevokedTokens(ctx context.Context) error {
+	logger := logrus.WithContext(ctx)
+
+	res, err := p.db.Model(&RevokedToken{}).Where("expires_at < ?", time.Now()).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting expired revoked tokens: %w", err)
+		return err
+	}
+
+	logger.WithField("count", res.RowsAffected()).Info("expired revoked tokens deleted successfully")
+	return nil
+}
+
+// CreateTokenFamily starts a new refresh token family for an authorization grant.
+func (p *DataProvider) CreateTokenFamily(ctx context.Context, params data.CreateTokenFamilyParams) (*data.TokenFamily, error) {
+	logger := logrus.WithContext(ctx).WithField("clientID", params.ClientID).WithField("userID", params.UserID)
+	family := &TokenFamily{
+		ID:       uuid.NewString(),
+		AuthCode: params.AuthCode,
+		UserID:   params.UserID,
+		ClientID: params.ClientID,
+	}
+
+	_, err := p.db.Model(family).Returning("*").Insert(ctx)
+	if err != nil {
+		logger.Error("error creating token family: %w", err)
+		return nil, fmt.Errorf("failed to insert new token family record: %w", err)
+	}
+
+	logger.Info("token family created successfully")
+	return family.ToData(), nil
+}
+
+// RevokeTokenFamily marks a token family as revoked, which invalidates every token issued from it.
+func (p *DataProvider) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	logger := logrus.WithContext(ctx).WithField("familyID", familyID)
+
+	_, err := p.db.Model(&TokenFamily{}).
+		Set("revoked_at = ?", time.Now()).
+		Where("id = ?", familyID).
+		Where("revoked_at IS NULL").
+		Update(ctx)
+	if err != nil {
+		logger.Error("error revoking token family: %w", err)
+		return err
+	}
+
+	logger.Info("token family revoked successfully")
+	return nil
+}
+
+// RevokeTokenFamiliesByUserID revokes every token family of a user, which invalidates all refresh
+// tokens issued on the user's behalf along with the access tokens of the same grants.
+func (p *DataProvider) RevokeTokenFamiliesByUserID(ctx context.Context, userID int64) error {
 	logger := logrus.WithContext(ctx).WithField("userID", userID)
 
-	_, err := p.db.Model(&Authorization{}).
-		Where("user_id = ? ", userID).
-		Set("is_revoked = ?", true).
+	res, err := p.db.Model(&TokenFamily{}).
+		Set("revoked_at = ?", time.Now()).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
 		Update(ctx)
 	if err != nil {
-		logger.Error("error updating authorization: %w", err)
+		logger.Error("error revoking token families by user id: %w", err)
 		return err
 	}
 
-	logger.Info("authorization updated successfully")
+	logger.WithField("count", res.RowsAffected()).Info("user token families revoked successfully")
+	return nil
+}
+
+// CreateRefreshToken records a refresh token, identified by its hash, as the newest member of its family.
+func (p *DataProvider) CreateRefreshToken(ctx context.Context, params data.CreateRefreshTokenParams) (*data.RefreshToken, error) {
+	logger := logrus.WithContext(ctx).WithField("familyID", params.FamilyID)
+	refreshToken := &RefreshToken{
+		TokenHash: params.TokenHash,
+		FamilyID:  params.FamilyID,
+	}
+
+	_, err := p.db.Model(refreshToken).Returning("*").Insert(ctx)
+	if err != nil {
+		logger.Error("error creating refresh token: %w", err)
+		return nil, fmt.Errorf("failed to insert new refresh token record: %w", err)
+	}
+
+	logger.Info("refresh token created successfully")
+	return refreshToken.ToData(), nil
+}
+
+// UseRefreshToken atomically marks a refresh token as used. It returns data.ErrRefreshTokenUsed
+// if the token had already been used, which indicates that it has been replayed.
+func (p *DataProvider) UseRefreshToken(ctx context.Context, tokenHash string) (*data.RefreshToken, error) {
+	logger := logrus.WithContext(ctx)
+	refreshToken := &RefreshToken{}
+
+	res, err := p.db.Model(refreshToken).
+		Set("used_at = ?", time.Now()).
+		Where("token_hash = ?", tokenHash).
+		Where("used_at IS NULL").
+		Returning("*").
+		Update(ctx)
+	if err != nil && err != pg.ErrNoRows {
+		logger.Error("error marking refresh token as used: %w", err)
+		return nil, err
+	}
+
+	if err == nil && res.RowsAffected() > 0 {
+		logger.Info("refresh token used successfully")
+		return refreshToken.ToData(), nil
+	}
+
+	refreshToken = &RefreshToken{TokenHash: tokenHash}
+	err = p.db.Model(refreshToken).WherePK().Select(ctx)
+	if err != nil {
+		if err == pg.ErrNoRows {
+			logger.Warn(data.ErrRefreshTokenNotFound)
+			return nil, data.ErrRefreshTokenNotFound
+		}
+		logger.Error("error fetching refresh token: %w", err)
+		return nil, err
+	}
+
+	logger.Warn(data.ErrRefreshTokenUsed)
+	return refreshToken.ToData(), data.ErrRefreshTokenUsed
+}
+
+// CreateSigningKey stores a new signing key. Only one active and one next key can exist at a time;
+// creating another one fails with data.ErrSigningKeyExists.
+func (p *DataProvider) CreateSigningKey(ctx context.Context, params data.CreateSigningKeyParams) (*data.SigningKey, error) {
+	logger := logrus.WithContext(ctx).WithField("keyID", params.ID).WithField("status", params.Status)
+
+	signingKey := newSigningKey(params)
+	_, err := p.db.Model(signingKey).Returning("*").Insert(ctx)
+	if err != nil {
+		if pgErr, ok := err.(pg.Error); ok && pgErr.IntegrityViolation() {
+			logger.Warn(data.ErrSigningKeyExists)
+			return nil, data.ErrSigningKeyExists
+		}
+		logger.Error("error creating signing key: %w", err)
+		return nil, fmt.Errorf("failed to insert new signing key record: %w", err)
+	}
+
+	logger.Info("signing key created successfully")
+	return signingKey.ToData(), nil
+}
+
+// GetSigningKeys returns every signing key that hasn't expired yet.
+func (p *DataProvider) GetSigningKeys(ctx context.Context) ([]*data.SigningKey, error) {
+	logger := logrus.WithContext(ctx)
+
+	var signingKeys []*SigningKey
+	err := p.db.Model(&signingKeys).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("created_at DESC").
+		Select(ctx)
+	if err != nil {
+		logger.Error("error fetching signing keys: %w", err)
+		return nil, err
+	}
+
+	keys := make([]*data.SigningKey, 0, len(signingKeys))
+	for _, k := range signingKeys {
+		keys = append(keys, k.ToData())
+	}
+	return keys, nil
+}
+
+// RotateSigningKeys retires the active key, activates the next key and stores a new next key.
+// It reports false without changing anything if the active key was activated after params.ActivatedBefore.
+func (p *DataProvider) RotateSigningKeys(ctx context.Context, params data.RotateSigningKeysParams) (bool, error) {
+	logger := logrus.WithContext(ctx).WithField("nextKeyID", params.Next.ID)
+	rotated := false
+
+	err := p.db.RunInTransaction(ctx, func(ctx context.Context, tx *pg.Tx) error {
+		active := &SigningKey{}
+		err := tx.Model(active).Where("status = ?", string(data.SigningKeyActive)).For("UPDATE").Select(ctx)
+		if err != nil && err != pg.ErrNoRows {
+			return err
+		}
+
+		now := time.Now()
+		if err == nil {
+			if active.ActivatedAt != nil && !active.ActivatedAt.Before(params.ActivatedBefore) {
+				return nil
+			}
+
+			_, err = tx.Model(active).
+				Set("status = ?", string(data.SigningKeyRetired)).
+				Set("retired_at = ?", now).
+				Set("expires_at = ?", params.RetiredExpiresAt).
+				WherePK().
+				Update(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Model(&SigningKey{}).
+			Set("status = ?", string(data.SigningKeyActive)).
+			Set("activated_at = ?", now).
+			Where("status = ?", string(data.SigningKeyNext)).
+			Update(ctx)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Model(newSigningKey(params.Next)).Insert(ctx); err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	})
+	if err != nil {
+		logger.Error("error rotating signing keys: %w", err)
+		return false, err
+	}
+
+	if rotated {
+		logger.Info("signing keys rotated successfully")
+	}
+	return rotated, nil
+}
+
+// DeleteExpiredSigningKeys removes retired keys that no unexpired token can have been signed with.
+func (p *DataProvider) DeleteExpiredSigningKeys(ctx context.Context) error {
+	logger := logrus.WithContext(ctx)
+
+	res, err := p.db.Model(&SigningKey{}).
+		Where("status = ?", string(data.SigningKeyRetired)).
+		Where("expires_at < ?", time.Now()).
+		Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting expired signing keys: %w", err)
+		return err
+	}
+
+	logger.WithField("count", res.RowsAffected()).Info("expired signing keys deleted successfully")
 	return nil
 }
+
+// RecordLoginAttempt counts an attempt for key as failed if allow accepts the counter recorded so far,
+// and returns the counter. Deciding and counting happen with the counter's row locked, so that
+// concurrent attempts on the same key are decided one after the other. The count starts over if the
+// previous failure happened before resetBefore.
+func (p *DataProvider) RecordLoginAttempt(ctx context.Context, key string, resetBefore time.Time, allow func(*data.LoginAttempts) bool) (*data.LoginAttempts, error) {
+	logger := logrus.WithContext(ctx).WithField("key", key)
+	loginAttempts := &LoginAttempts{Key: key}
+
+	err := p.db.RunInTransaction(ctx, func(ctx context.Context, tx *pg.Tx) error {
+		// Create the counter first, so that there is a row to lock even for the first attempt.
+		_, err := tx.Model(&LoginAttempts{Key: key, LastFailureAt: resetBefore}).OnConflict("DO NOTHING").Insert(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to insert login attempts record: %w", err)
+		}
+
+		if err := tx.Model(loginAttempts).WherePK().For("UPDATE").Select(ctx); err != nil {
+			return fmt.Errorf("failed to fetch login attempts record: %w", err)
+		}
+		if loginAttempts.LastFailureAt.Before(resetBefore) {
+			loginAttempts.Failures = 0
+		}
+
+		if !allow(loginAttempts.ToData()) {
+			return nil
+		}
+
+		loginAttempts.Failures++
+		loginAttempts.LastFailureAt = time.Now()
+		if _, err := tx.Model(loginAttempts).WherePK().Update(ctx); err != nil {
+			return fmt.Errorf("failed to update login attempts record: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("error recording login attempt: %w", err)
+		return nil, err
+	}
+
+	return loginAttempts.ToData(), nil
+}
+
+// ReleaseLoginAttempts takes back an attempt counted for each of the given throttling keys.
+func (p *DataProvider) ReleaseLoginAttempts(ctx context.Context, keys []string) error {
+	logger := logrus.WithContext(ctx).WithField("keys", keys)
+
+	_, err := p.db.Model(&LoginAttempts{}).
+		Set("failures = failures - 1").
+		Where("key IN (?)", pg.In(keys)).
+		Where("failures > 0").
+		Update(ctx)
+	if err != nil {
+		logger.Error("error releasing login attempts: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// ResetLoginAttempts forgets the failures recorded for the given throttling keys.
+func (p *DataProvider) ResetLoginAttempts(ctx context.Context, keys []string) error {
+	logger := logrus.WithContext(ctx).WithField("keys", keys)
+
+	_, err := p.db.Model(&LoginAttempts{}).Where("key IN (?)", pg.In(keys)).Delete(ctx)
+	if err != nil {
+		logger.Error("error resetting login attempts: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpiredLoginAttempts removes the counters whose last failure happened before the given time.
+func (p *DataProvider) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error {
+	logger := logrus.WithContext(ctx)
+
+	res, err := p.db.Model(&LoginAttempts{}).Where("last_failure_at < ?", before).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting expired login attempts: %w", err)
+		return err
+	}
+
+	logger.WithField("count", res.RowsAffected()).Info("expired login attempts deleted successfully")
+	return nil
+}
+
+// CreateUserMFA stores a new, unconfirmed TOTP authenticator for a user, replacing an earlier one that
+// was never confirmed. It fails with data.ErrMFAAlreadyEnabled if the user has a confirmed one.
+func (p *DataProvider) CreateUserMFA(ctx context.Context, params data.CreateUserMFAParams) (*data.UserMFA, error) {
+	logger := logrus.WithContext(ctx).WithField("userID", params.UserID)
+	userMFA := &UserMFA{
+		UserID:          params.UserID,
+		EncryptedSecret: params.EncryptedSecret,
+		CreatedAt:       time.Now(),
+	}
+
+	res, err := p.db.Model(userMFA).
+		OnConflict("(user_id) DO UPDATE").
+		Set("encrypted_secret = EXCLUDED.encrypted_secret").
+		Set("last_used_step = 0").
+		Set("created_at = EXCLUDED.created_at").
+		Where("user_mfa.confirmed_at IS NULL").
+		Returning("*").
+		Insert(ctx)
+	if err != nil && err != pg.ErrNoRows {
+		logger.Error("error creating user mfa: %w", err)
+		return nil, fmt.Errorf("failed to upsert user mfa record: %w", err)
+	}
+
+	if err == pg.ErrNoRows || res.RowsAffected() == 0 {
+		logger.Warn(data.ErrMFAAlreadyEnabled)
+		return nil, data.ErrMFAAlreadyEnabled
+	}
+
+	logger.Info("user mfa created successfully")
+	return userMFA.ToData(), nil
+}
+
+// GetUserMFA retrieves the TOTP authenticator of a user, whether confirmed or not.
+func (p *DataProvider) GetUserMFA(ctx context.Context, userID int64) (*data.UserMFA, error) {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+	userMFA := &UserMFA{UserID: userID}
+
+	err := p.db.Model(userMFA).WherePK().Select(ctx)
+	if err != nil {
+		if err == pg.ErrNoRows {
+			logger.Warn(data.ErrUserMFANotFound)
+			return nil, data.ErrUserMFANotFound
+		}
+		logger.Error("error fetching user mfa: %w", err)
+		return nil, err
+	}
+
+	return userMFA.ToData(), nil
+}
+
+// ConfirmUserMFA enables the TOTP authenticator of a user.
+func (p *DataProvider) ConfirmUserMFA(ctx context.Context, userID int64) error {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+
+	res, err := p.db.Model(&UserMFA{}).
+		Set("confirmed_at = ?", time.Now()).
+		Where("user_id = ?", userID).
+		Where("confirmed_at IS NULL").
+		Update(ctx)
+	if err != nil {
+		logger.Error("error confirming user mfa: %w", err)
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		logger.Warn(data.ErrUserMFANotFound)
+		return data.ErrUserMFANotFound
+	}
+
+	logger.Info("user mfa confirmed successfully")
+	return nil
+}
+
+// UseMFAStep atomically records that a code of the given time step was accepted. It returns
+// data.ErrMFAStepUsed if a code of the same or a later step was accepted before, which means the code
+// is being replayed.
+func (p *DataProvider) UseMFAStep(ctx context.Context, userID int64, step int64) error {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+
+	res, err := p.db.Model(&UserMFA{}).
+		Set("last_used_step = ?", step).
+		Where("user_id = ?", userID).
+		Where("last_used_step < ?", step).
+		Update(ctx)
+	if err != nil {
+		logger.Error("error using mfa step: %w", err)
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		logger.Warn(data.ErrMFAStepUsed)
+		return data.ErrMFAStepUsed
+	}
+	return nil
+}
+
+// DeleteUserMFA removes the TOTP authenticator of a user, disabling MFA.
+func (p *DataProvider) DeleteUserMFA(ctx context.Context, userID int64) error {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+
+	res, err := p.db.Model(&UserMFA{}).Where("user_id = ?", userID).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting user mfa: %w", err)
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		logger.Warn(data.ErrUserMFANotFound)
+		return data.ErrUserMFANotFound
+	}
+
+	logger.Info("user mfa deleted successfully")
+	return nil
+}
+
+// CreateEmailVerification stores a new verification token for a user's email address.
+func (p *DataProvider) CreateEmailVerification(ctx context.Context, params data.CreateEmailVerificationParams) (*data.EmailVerification, error) {
+	logger := logrus.WithContext(ctx).WithField("userID", params.UserID)
+	verification := &EmailVerification{
+		TokenHash: params.TokenHash,
+		UserID:    params.UserID,
+		Email:     params.Email,
+		CreatedAt: time.Now(),
+		ExpiresAt: params.ExpiresAt,
+	}
+
+	_, err := p.db.Model(verification).Insert(ctx)
+	if err != nil {
+		logger.Error("error creating email verification: %w", err)
+		return nil, fmt.Errorf("failed to insert new email verification record: %w", err)
+	}
+
+	logger.Info("email verification created successfully")
+	return verification.ToData(), nil
+}
+
+// GetLatestEmailVerification retrieves the most recently created verification token of a user,
+// whether expired or not.
+func (p *DataProvider) GetLatestEmailVerification(ctx context.Context, userID int64) (*data.EmailVerification, error) {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+	verification := &EmailVerification{}
+
+	err := p.db.Model(verification).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(1).
+		Select(ctx)
+	if err != nil {
+		if err == pg.ErrNoRows {
+			logger.Warn(data.ErrEmailVerificationNotFound)
+			return nil, data.ErrEmailVerificationNotFound
+		}
+		logger.Error("error fetching email verification: %w", err)
+		return nil, err
+	}
+
+	return verification.ToData(), nil
+}
+
+// UseEmailVerification consumes an unexpired verification token and marks the email address it was
+// issued for as verified, along with removing the other tokens of the user. It returns
+// data.ErrEmailVerificationNotFound if the token doesn't exist, has expired, or the user's email
+// address has changed since it was issued.
+func (p *DataProvider) UseEmailVerification(ctx context.Context, tokenHash string) (*data.EmailVerification, error) {
+	logger := logrus.WithContext(ctx)
+	verification := &EmailVerification{}
+
+	err := p.db.RunInTransaction(ctx, func(ctx context.Context, tx *pg.Tx) error {
+		res, err := tx.Model(verification).
+			Where("token_hash = ?", tokenHash).
+			Where("expires_at > ?", time.Now()).
+			Returning("*").
+			Delete(ctx)
+		if err != nil && err != pg.ErrNoRows {
+			return err
+		}
+		if err == pg.ErrNoRows || res.RowsAffected() == 0 {
+			return data.ErrEmailVerificationNotFound
+		}
+
+		res, err = tx.Model(&User{}).
+			Set("email_verified = ?", true).
+			Set("updated_at = ?", time.Now()).
+			Where("id = ?", verification.UserID).
+			Where("email = ?", verification.Email).
+			Update(ctx)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return data.ErrEmailVerificationNotFound
+		}
+
+		_, err = tx.Model(&EmailVerification{}).Where("user_id = ?", verification.UserID).Delete(ctx)
+		return err
+	})
+	if err != nil {
+		if err == data.ErrEmailVerificationNotFound {
+			logger.Warn(data.ErrEmailVerificationNotFound)
+			return nil, data.ErrEmailVerificationNotFound
+		}
+		logger.Error("error using email verification: %w", err)
+		return nil, err
+	}
+
+	logger.WithField("userID", verification.UserID).Info("email verified successfully")
+	return verification.ToData(), nil
+}
+
+// DeleteExpiredEmailVerifications removes the verification tokens that can no longer be used.
+func (p *DataProvider) DeleteExpiredEmailVerifications(ctx context.Context) error {
+	logger := logrus.WithContext(ctx)
+
+	res, err := p.db.Model(&EmailVerification{}).Where("expires_at < ?", time.Now()).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting expired email verifications: %w", err)
+		return err
+	}
+
+	logger.WithField("count", res.RowsAffected()).Info("expired email verifications deleted successfully")
+	return nil
+}
+
+// CreatePasswordReset stores a new password reset token for a user.
+func (p *DataProvider) CreatePasswordReset(ctx context.Context, params data.CreatePasswordResetParams) (*data.PasswordReset, error) {
+	logger := logrus.WithContext(ctx).WithField("userID", params.UserID)
+	reset := &PasswordReset{
+		TokenHash: params.TokenHash,
+		UserID:    params.UserID,
+		CreatedAt: time.Now(),
+		ExpiresAt: params.ExpiresAt,
+	}
+
+	_, err := p.db.Model(reset).Insert(ctx)
+	if err != nil {
+		logger.Error("error creating password reset: %w", err)
+		return nil, fmt.Errorf("failed to insert new password reset record: %w", err)
+	}
+
+	logger.Info("password reset created successfully")
+	return reset.ToData(), nil
+}
+
+// GetPasswordReset retrieves an unexpired password reset token without consuming it. It returns
+// data.ErrPasswordResetNotFound if the token doesn't exist or has expired.
+func (p *DataProvider) GetPasswordReset(ctx context.Context, tokenHash string) (*data.PasswordReset, error) {
+	logger := logrus.WithContext(ctx)
+	reset := &PasswordReset{}
+
+	err := p.db.Model(reset).
+		Where("token_hash = ?", tokenHash).
+		Where("expires_at > ?", time.Now()).
+		Select(ctx)
+	if err != nil {
+		if err == pg.ErrNoRows {
+			logger.Warn(data.ErrPasswordResetNotFound)
+			return nil, data.ErrPasswordResetNotFound
+		}
+		logger.Error("error fetching password reset: %w", err)
+		return nil, err
+	}
+
+	logger.WithField("userID", reset.UserID).Info("password reset fetched successfully")
+	return reset.ToData(), nil
+}
+
+// GetLatestPasswordReset retrieves the most recently created password reset token of a user, whether
+// expired or not.
+func (p *DataProvider) GetLatestPasswordReset(ctx context.Context, userID int64) (*data.PasswordReset, error) {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+	reset := &PasswordReset{}
+
+	err := p.db.Model(reset).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(1).
+		Select(ctx)
+	if err != nil {
+		if err == pg.ErrNoRows {
+			logger.Warn(data.ErrPasswordResetNotFound)
+			return nil, data.ErrPasswordResetNotFound
+		}
+		logger.Error("error fetching password reset: %w", err)
+		return nil, err
+	}
+
+	return reset.ToData(), nil
+}
+
+// UsePasswordReset consumes an unexpired password reset token and replaces the password of its user,
+// removing the user's other reset tokens along with it. It returns data.ErrPasswordResetNotFound if
+// the token doesn't exist or has expired.
+func (p *DataProvider) UsePasswordReset(ctx context.Context, tokenHash string, hashedPassword []byte) (*data.PasswordReset, error) {
+	logger := logrus.WithContext(ctx)
+	reset := &PasswordReset{}
+
+	err := p.db.RunInTransaction(ctx, func(ctx context.Context, tx *pg.Tx) error {
+		res, err := tx.Model(reset).
+			Where("token_hash = ?", tokenHash).
+			Where("expires_at > ?", time.Now()).
+			Returning("*").
+			Delete(ctx)
+		if err != nil && err != pg.ErrNoRows {
+			return err
+		}
+		if err == pg.ErrNoRows || res.RowsAffected() == 0 {
+			return data.ErrPasswordResetNotFound
+		}
+
+		_, err = tx.Model(&User{}).
+			Set("hashed_password = ?", hashedPassword).
+			Set("updated_at = ?", time.Now()).
+			Where("id = ?", reset.UserID).
+			Update(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Model(&PasswordReset{}).Where("user_id = ?", reset.UserID).Delete(ctx)
+		return err
+	})
+	if err != nil {
+		if err == data.ErrPasswordResetNotFound {
+			logger.Warn(data.ErrPasswordResetNotFound)
+			return nil, data.ErrPasswordResetNotFound
+		}
+		logger.Error("error using password reset: %w", err)
+		return nil, err
+	}
+
+	logger.WithField("userID", reset.UserID).Info("password reset successfully")
+	return reset.ToData(), nil
+}
+
+// DeleteExpiredPasswordResets removes the password reset tokens that can no longer be used.
+func (p *DataProvider) DeleteExpiredPasswordResets(ctx context.Context) error {
+	logger := logrus.WithContext(ctx)
+
+	res, err := p.db.Model(&PasswordReset{}).Where("expires_at < ?", time.Now()).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting expired password resets: %w", err)
+		return err
+	}
+
+	logger.WithField("count", res.RowsAffected()).Info("expired password resets deleted successfully")
+	return nil
+}
+
+// AddPasswordHistory records a password a user has set, keeping only the user's keep most recent
+// ones.
+func (p *DataProvider) AddPasswordHistory(ctx context.Context, userID int64, hashedPassword []byte, keep int) error {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+
+	err := p.db.RunInTransaction(ctx, func(ctx context.Context, tx *pg.Tx) error {
+		_, err := tx.Model(&PasswordHistory{
+			UserID:         userID,
+			HashedPassword: hashedPassword,
+			CreatedAt:      time.Now(),
+		}).Insert(ctx)
+		if err != nil {
+			return err
+		}
+
+		kept := tx.Model(&PasswordHistory{}).Column("id").Where("user_id = ?", userID).Order("id DESC").Limit(keep)
+		_, err = tx.Model(&PasswordHistory{}).
+			Where("user_id = ?", userID).
+			Where("id NOT IN (?)", kept).
+			Delete(ctx)
+		return err
+	})
+	if err != nil {
+		logger.Error("error adding password history: %w", err)
+		return fmt.Errorf("failed to add password history record: %w", err)
+	}
+
+	logger.Info("password history added successfully")
+	return nil
+}
+
+// GetPasswordHistory retrieves up to limit of the most recent passwords a user has set, newest first.
+func (p *DataProvider) GetPasswordHistory(ctx context.Context, userID int64, limit int) ([]*data.PasswordHistory, error) {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+	var history []*PasswordHistory
+
+	err := p.db.Model(&history).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Select(ctx)
+	if err != nil {
+		logger.Error("error fetching password history: %w", err)
+		return nil, err
+	}
+
+	passwords := make([]*data.PasswordHistory, 0, len(history))
+	for _, h := range history {
+		passwords = append(passwords, h.ToData())
+	}
+	return passwords, nil
+}
+
+// ReplaceRecoveryCodes replaces every recovery code of a user, used or not, with the given hashes.
+func (p *DataProvider) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashedCodes []string) error {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+
+	codes := make([]*RecoveryCode, 0, len(hashedCodes))
+	for _, hashedCode := range hashedCodes {
+		codes = append(codes, &RecoveryCode{
+			UserID:     userID,
+			HashedCode: hashedCode,
+			CreatedAt:  time.Now(),
+		})
+	}
+
+	err := p.db.RunInTransaction(ctx, func(ctx context.Context, tx *pg.Tx) error {
+		if _, err := tx.Model(&RecoveryCode{}).Where("user_id = ?", userID).Delete(ctx); err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		_, err := tx.Model(&codes).Insert(ctx)
+		return err
+	})
+	if err != nil {
+		logger.Error("error replacing recovery codes: %w", err)
+		return fmt.Errorf("failed to replace recovery code records: %w", err)
+	}
+
+	logger.Info("recovery codes replaced successfully")
+	return nil
+}
+
+// GetUnusedRecoveryCodes retrieves the recovery codes of a user that haven't been used yet.
+func (p *DataProvider) GetUnusedRecoveryCodes(ctx context.Context, userID int64) ([]*data.RecoveryCode, error) {
+	logger := logrus.WithContext(ctx).WithField("userID", userID)
+	var recoveryCodes []*RecoveryCode
+
+	err := p.db.Model(&recoveryCodes).
+		Where("user_id = ?", userID).
+		Where("used_at IS NULL").
+		Order("id").
+		Select(ctx)
+	if err != nil {
+		logger.Error("error fetching recovery codes: %w", err)
+		return nil, err
+	}
+
+	codes := make([]*data.RecoveryCode, 0, len(recoveryCodes))
+	for _, c := range recoveryCodes {
+		codes = append(codes, c.ToData())
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode atomically marks a recovery code as used. It returns data.ErrRecoveryCodeUsed if
+// the code was used before, or replaced in the meantime.
+func (p *DataProvider) UseRecoveryCode(ctx context.Context, codeID int64) error {
+	logger := logrus.WithContext(ctx).WithField("codeID", codeID)
+
+	res, err := p.db.Model(&RecoveryCode{}).
+		Set("used_at = ?", time.Now()).
+		Where("id = ?", codeID).
+		Where("used_at IS NULL").
+		Update(ctx)
+	if err != nil {
+		logger.Error("error using recovery code: %w", err)
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		logger.Warn(data.ErrRecoveryCodeUsed)
+		return data.ErrRecoveryCodeUsed
+	}
+
+	logger.Info("recovery code used successfully")
+	return nil
+}
+
+// CreateMFAChallenge stores a new MFA challenge for a user who passed password authentication.
+func (p *DataProvider) CreateMFAChallenge(ctx context.Context, params data.CreateMFAChallengeParams) (*data.MFAChallenge, error) {
+	logger := logrus.WithContext(ctx).WithField("userID", params.UserID)
+	challenge := &MFAChallenge{
+		ID:        uuid.NewString(),
+		UserID:    params.UserID,
+		ExpiresAt: params.ExpiresAt,
+	}
+
+	_, err := p.db.Model(challenge).Returning("*").Insert(ctx)
+	if err != nil {
+		logger.Error("error creating mfa challenge: %w", err)
+		return nil, fmt.Errorf("failed to insert new mfa challenge record: %w", err)
+	}
+
+	logger.Info("mfa challenge created successfully")
+	return challenge.ToData(), nil
+}
+
+// GetMFAChallengeByID retrieves an MFA challenge by its ID.
+func (p *DataProvider) GetMFAChallengeByID(ctx context.Context, challengeID string) (*data.MFAChallenge, error) {
+	logger := logrus.WithContext(ctx).WithField("challengeID", challengeID)
+	challenge := &MFAChallenge{ID: challengeID}
+
+	err := p.db.Model(challenge).WherePK().Select(ctx)
+	if err != nil {
+		if err == pg.ErrNoRows {
+			logger.Warn(data.ErrMFAChallengeNotFound)
+			return nil, data.ErrMFAChallengeNotFound
+		}
+		logger.Error("error fetching mfa challenge: %w", err)
+		return nil, err
+	}
+
+	return challenge.ToData(), nil
+}
+
+// DeleteMFAChallenge removes an MFA challenge. It returns data.ErrMFAChallengeNotFound if the challenge
+// didn't exist, so that a challenge can only be completed once.
+func (p *DataProvider) DeleteMFAChallenge(ctx context.Context, challengeID string) error {
+	logger := logrus.WithContext(ctx).WithField("challengeID", challengeID)
+
+	res, err := p.db.Model(&MFAChallenge{}).Where("id = ?", challengeID).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting mfa challenge: %w", err)
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		logger.Warn(data.ErrMFAChallengeNotFound)
+		return data.ErrMFAChallengeNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredMFAChallenges removes the MFA challenges that can no longer be completed.
+func (p *DataProvider) DeleteExpiredMFAChallenges(ctx context.Context) error {
+	logger := logrus.WithContext(ctx)
+
+	res, err := p.db.Model(&MFAChallenge{}).Where("expires_at < ?", time.Now()).Delete(ctx)
+	if err != nil {
+		logger.Error("error deleting expired mfa challenges: %w", err)
+		return err
+	}
+
+	logger.WithField("count", res.RowsAffected()).Info("expired mfa challenges deleted successfully")
+	return nil
+}
+
+func newSigningKey(params data.CreateSigningKeyParams) *SigningKey {
+	signingKey := &SigningKey{
+		ID:         params.ID,
+		Algorithm:  params.Algorithm,
+		PrivateKey: params.PrivateKey,
+		PublicKey:  params.PublicKey,
+		Status:     string(params.Status),
+	}
+	if params.Status == data.SigningKeyActive {
+		now := time.Now()
+		signingKey.ActivatedAt = &now
+	}
+	return signingKey
+}
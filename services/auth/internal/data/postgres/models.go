@@ -12,8 +12,19 @@ type User struct {
 	Username       string    `pg:"username,unique,notnull"`
 	HashedPassword []byte    `pg:"hashed_password,notnull"`
 	Email          string    `pg:"email,unique,notnull"`
+	EmailVerified  bool      `pg:"email_verified,use_zero,notnull"`
 	CreatedAt      time.Time `pg:"created_at,default:now()"`
 	UpdatedAt      time.Time `pg:"updated_at"`
+
+	Roles []*UserRole `pg:"rel:has-many"`
+}
+
+type UserRole struct {
+	tableName struct{}  `pg:"user_roles"`
+	ID        int64     `pg:"id,serial,pk"`
+	UserID    int64     `pg:"user_id,notnull"`
+	Role      string    `pg:"role,notnull"`
+	CreatedAt time.Time `pg:"created_at,default:now()"`
 }
 
 type Session struct {
@@ -27,31 +38,170 @@ type Session struct {
 type Client struct {
 	tableName    struct{}  `pg:"clients"`
 	ID           int64     `pg:"id,serial,pk"`
-	HashedSecret []byte    `pg:"hashed_secret,notnull"`
+	HashedSecret []byte    `pg:"hashed_secret"`
 	Name         string    `pg:"name,unique,notnull"`
 	Website      string    `pg:"website,unique,notnull"`
 	Scope        string    `pg:"scope"`
+	IsPublic     bool      `pg:"is_public,notnull,use_zero"`
 	CreatedAt    time.Time `pg:"created_at,default:now"`
 	UpdatedAt    time.Time `pg:"updated_at"`
+
+	RedirectURIs []*ClientRedirectURI `pg:"rel:has-many"`
+}
+
+type ClientRedirectURI struct {
+	tableName   struct{}  `pg:"client_redirect_uris"`
+	ID          int64     `pg:"id,serial,pk"`
+	ClientID    int64     `pg:"client_id,notnull"`
+	RedirectURI string    `pg:"redirect_uri,notnull"`
+	CreatedAt   time.Time `pg:"created_at,default:now"`
 }
 
 type Authorization struct {
-	tableName struct{}  `pg:"authorizations"`
-	AuthCode  string    `pg:"auth_code,pk"`
+	tableName           struct{}   `pg:"authorizations"`
+	AuthCode            string     `pg:"auth_code,pk"`
+	UserID              int64      `pg:"user_id,notnull"`
+	ClientID            int64      `pg:"client_id,notnull"`
+	Scope               string     `pg:"scope"`
+	CodeChallenge       string     `pg:"code_challenge"`
+	CodeChallengeMethod string     `pg:"code_challenge_method"`
+	RedirectURI         string     `pg:"redirect_uri"`
+	Nonce               string     `pg:"nonce"`
+	AuthTime            time.Time  `pg:"auth_time"`
+	CreatedAt           time.Time  `pg:"created_at,default:now"`
+	ExpiresAt           time.Time  `pg:"expires_at,notnull"`
+	UsedAt              *time.Time `pg:"used_at"`
+	IsRevoked           bool       `pg:"is_revoked,notnull"`
+}
+
+type AuthorizationRequest struct {
+	tableName           struct{}  `pg:"authorization_requests"`
+	ID                  string    `pg:"id,pk"`
+	SessionID           string    `pg:"session_id,notnull"`
+	UserID              int64     `pg:"user_id,notnull"`
+	ClientID            int64     `pg:"client_id,notnull"`
+	RedirectURI         string    `pg:"redirect_uri,notnull"`
+	Scope               string    `pg:"scope"`
+	State               string    `pg:"state"`
+	CodeChallenge       string    `pg:"code_challenge"`
+	CodeChallengeMethod string    `pg:"code_challenge_method"`
+	Nonce               string    `pg:"nonce"`
+	AuthTime            time.Time `pg:"auth_time"`
+	Status              string    `pg:"status,notnull"`
+	CreatedAt           time.Time `pg:"created_at,default:now()"`
+	ExpiresAt           time.Time `pg:"expires_at,notnull"`
+}
+
+type SigningKey struct {
+	tableName   struct{}   `pg:"signing_keys"`
+	ID          string     `pg:"id,pk"`
+	Algorithm   string     `pg:"algorithm,notnull"`
+	PrivateKey  []byte     `pg:"private_key,notnull"` // encrypted, bound to ID
+	PublicKey   []byte     `pg:"public_key,notnull"`
+	Status      string     `pg:"status,notnull"`
+	CreatedAt   time.Time  `pg:"created_at,default:now()"`
+	ActivatedAt *time.Time `pg:"activated_at"`
+	RetiredAt   *time.Time `pg:"retired_at"`
+	ExpiresAt   *time.Time `pg:"expires_at"`
+}
+
+type UserMFA struct {
+	tableName       struct{}   `pg:"user_mfa"`
+	UserID          int64      `pg:"user_id,pk"`
+	EncryptedSecret []byte     `pg:"encrypted_secret,notnull"`
+	LastUsedStep    int64      `pg:"last_used_step,use_zero,notnull"`
+	CreatedAt       time.Time  `pg:"created_at,default:now()"`
+	ConfirmedAt     *time.Time `pg:"confirmed_at"`
+}
+
+type EmailVerification struct {
+	tableName struct{}  `pg:"email_verifications"`
+	TokenHash string    `pg:"token_hash,pk"`
+	UserID    int64     `pg:"user_id,notnull"`
+	Email     string    `pg:"email,notnull"`
+	CreatedAt time.Time `pg:"created_at,default:now()"`
+	ExpiresAt time.Time `pg:"expires_at,notnull"`
+}
+
+type PasswordReset struct {
+	tableName struct{}  `pg:"password_resets"`
+	TokenHash string    `pg:"token_hash,pk"`
 	UserID    int64     `pg:"user_id,notnull"`
-	ClientID  int64     `pg:"client_id,notnull"`
-	Scope     string    `pg:"scope"`
-	CreatedAt time.Time `pg:"created_at,default:now"`
+	CreatedAt time.Time `pg:"created_at,default:now()"`
 	ExpiresAt time.Time `pg:"expires_at,notnull"`
-	IsRevoked bool      `pg:"is_revoked,notnull"`
+}
+
+type PasswordHistory struct {
+	tableName      struct{}  `pg:"password_history"`
+	ID             int64     `pg:"id,pk"`
+	UserID         int64     `pg:"user_id,notnull"`
+	HashedPassword []byte    `pg:"hashed_password,notnull"`
+	CreatedAt      time.Time `pg:"created_at,default:now()"`
+}
+
+type RecoveryCode struct {
+	tableName  struct{}   `pg:"mfa_recovery_codes"`
+	ID         int64      `pg:"id,pk"`
+	UserID     int64      `pg:"user_id,notnull"`
+	HashedCode string     `pg:"hashed_code,notnull"`
+	CreatedAt  time.Time  `pg:"created_at,default:now()"`
+	UsedAt     *time.Time `pg:"used_at"`
+}
+
+type MFAChallenge struct {
+	tableName struct{}  `pg:"mfa_challenges"`
+	ID        string    `pg:"id,pk"`
+	UserID    int64     `pg:"user_id,notnull"`
+	CreatedAt time.Time `pg:"created_at,default:now()"`
+	ExpiresAt time.Time `pg:"expires_at,notnull"`
+}
+
+type LoginAttempts struct {
+	tableName     struct{}  `pg:"login_attempts"`
+	Key           string    `pg:"key,pk"`
+	Failures      int       `pg:"failures,use_zero,notnull"`
+	LastFailureAt time.Time `pg:"last_failure_at,notnull"`
+}
+
+type RevokedToken struct {
+	tableName struct{}  `pg:"revoked_tokens"`
+	TokenID   string    `pg:"token_id,pk"`
+	GrantID   string    `pg:"grant_id"`
+	RevokedAt time.Time `pg:"revoked_at,default:now()"`
+	ExpiresAt time.Time `pg:"expires_at,notnull"`
+}
+
+type TokenFamily struct {
+	tableName struct{}   `pg:"token_families"`
+	ID        string     `pg:"id,pk"`
+	AuthCode  string     `pg:"auth_code"`
+	UserID    int64      `pg:"user_id,notnull"`
+	ClientID  int64      `pg:"client_id,notnull"`
+	CreatedAt time.Time  `pg:"created_at,default:now()"`
+	RevokedAt *time.Time `pg:"revoked_at"`
+}
+
+type RefreshToken struct {
+	tableName struct{}   `pg:"refresh_tokens"`
+	TokenHash string     `pg:"token_hash,pk"`
+	FamilyID  string     `pg:"family_id,notnull"`
+	CreatedAt time.Time  `pg:"created_at,default:now()"`
+	UsedAt    *time.Time `pg:"used_at"`
 }
 
 func (u *User) ToData() *data.User {
+	roles := make([]string, 0, len(u.Roles))
+	for _, role := range u.Roles {
+		roles = append(roles, role.Role)
+	}
+
 	return &data.User{
 		ID:             u.ID,
 		Username:       u.Username,
 		HashedPassword: u.HashedPassword,
 		Email:          u.Email,
+		EmailVerified:  u.EmailVerified,
+		Roles:          roles,
 		CreatedAt:      u.CreatedAt,
 		UpdatedAt:      u.UpdatedAt,
 	}
@@ -67,12 +217,19 @@ func (s *Session) ToData() *data.Session {
 }
 
 func (c *Client) ToData() *data.Client {
+	redirectURIs := make([]string, 0, len(c.RedirectURIs))
+	for _, uri := range c.RedirectURIs {
+		redirectURIs = append(redirectURIs, uri.RedirectURI)
+	}
+
 	return &data.Client{
 		ID:           c.ID,
 		HashedSecret: c.HashedSecret,
 		Name:         c.Name,
 		Website:      c.Website,
 		Scope:        c.Scope,
+		IsPublic:     c.IsPublic,
+		RedirectURIs: redirectURIs,
 		CreatedAt:    c.CreatedAt,
 		UpdatedAt:    c.UpdatedAt,
 	}
@@ -80,12 +237,145 @@ func (c *Client) ToData() *data.Client {
 
 func (a *Authorization) ToData() *data.Authorization {
 	return &data.Authorization{
-		AuthCode:  a.AuthCode,
-		UserID:    a.UserID,
-		ClientID:  a.ClientID,
-		Scope:     a.Scope,
-		CreatedAt: a.CreatedAt,
-		ExpiresAt: a.ExpiresAt,
-		IsRevoked: a.IsRevoked,
+		AuthCode:            a.AuthCode,
+		UserID:              a.UserID,
+		ClientID:            a.ClientID,
+		Scope:               a.Scope,
+		CodeChallenge:       a.CodeChallenge,
+		CodeChallengeMethod: a.CodeChallengeMethod,
+		RedirectURI:         a.RedirectURI,
+		Nonce:               a.Nonce,
+		AuthTime:            a.AuthTime,
+		CreatedAt:           a.CreatedAt,
+		ExpiresAt:           a.ExpiresAt,
+		UsedAt:              a.UsedAt,
+		IsRevoked:           a.IsRevoked,
+	}
+}
+
+func (a *AuthorizationRequest) ToData() *data.AuthorizationRequest {
+	return &data.AuthorizationRequest{
+		ID:                  a.ID,
+		SessionID:           a.SessionID,
+		UserID:              a.UserID,
+		ClientID:            a.ClientID,
+		RedirectURI:         a.RedirectURI,
+		Scope:               a.Scope,
+		State:               a.State,
+		CodeChallenge:       a.CodeChallenge,
+		CodeChallengeMethod: a.CodeChallengeMethod,
+		Nonce:               a.Nonce,
+		AuthTime:            a.AuthTime,
+		Status:              data.AuthorizationRequestStatus(a.Status),
+		CreatedAt:           a.CreatedAt,
+		ExpiresAt:           a.ExpiresAt,
+	}
+}
+
+func (r *RevokedToken) ToData() *data.RevokedToken {
+	return &data.RevokedToken{
+		TokenID:   r.TokenID,
+		GrantID:   r.GrantID,
+		RevokedAt: r.RevokedAt,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+func (f *TokenFamily) ToData() *data.TokenFamily {
+	return &data.TokenFamily{
+		ID:        f.ID,
+		AuthCode:  f.AuthCode,
+		UserID:    f.UserID,
+		ClientID:  f.ClientID,
+		CreatedAt: f.CreatedAt,
+		RevokedAt: f.RevokedAt,
+	}
+}
+
+func (r *RefreshToken) ToData() *data.RefreshToken {
+	return &data.RefreshToken{
+		TokenHash: r.TokenHash,
+		FamilyID:  r.FamilyID,
+		CreatedAt: r.CreatedAt,
+		UsedAt:    r.UsedAt,
+	}
+}
+
+func (k *SigningKey) ToData() *data.SigningKey {
+	return &data.SigningKey{
+		ID:          k.ID,
+		Algorithm:   k.Algorithm,
+		PrivateKey:  k.PrivateKey,
+		PublicKey:   k.PublicKey,
+		Status:      data.SigningKeyStatus(k.Status),
+		CreatedAt:   k.CreatedAt,
+		ActivatedAt: k.ActivatedAt,
+		RetiredAt:   k.RetiredAt,
+		ExpiresAt:   k.ExpiresAt,
+	}
+}
+
+func (a *LoginAttempts) ToData() *data.LoginAttempts {
+	return &data.LoginAttempts{
+		Key:           a.Key,
+		Failures:      a.Failures,
+		LastFailureAt: a.LastFailureAt,
+	}
+}
+
+func (m *UserMFA) ToData() *data.UserMFA {
+	return &data.UserMFA{
+		UserID:          m.UserID,
+		EncryptedSecret: m.EncryptedSecret,
+		LastUsedStep:    m.LastUsedStep,
+		CreatedAt:       m.CreatedAt,
+		ConfirmedAt:     m.ConfirmedAt,
+	}
+}
+
+func (v *EmailVerification) ToData() *data.EmailVerification {
+	return &data.EmailVerification{
+		TokenHash: v.TokenHash,
+		UserID:    v.UserID,
+		Email:     v.Email,
+		CreatedAt: v.CreatedAt,
+		ExpiresAt: v.ExpiresAt,
+	}
+}
+
+func (r *PasswordReset) ToData() *data.PasswordReset {
+	return &data.PasswordReset{
+		TokenHash: r.TokenHash,
+		UserID:    r.UserID,
+		CreatedAt: r.CreatedAt,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+func (h *PasswordHistory) ToData() *data.PasswordHistory {
+	return &data.PasswordHistory{
+		ID:             h.ID,
+		UserID:         h.UserID,
+		HashedPassword: h.HashedPassword,
+		CreatedAt:      h.CreatedAt,
+	}
+}
+
+func (c *RecoveryCode) ToData() *data.RecoveryCode {
+	return &data.RecoveryCode{
+		ID:         c.ID,
+		UserID:     c.UserID,
+		HashedCode: c.HashedCode,
+		CreatedAt:  c.CreatedAt,
+		UsedAt:     c.UsedAt,
+	}
+}
+
+func (c *MFAChallenge) ToData() *data.MFAChallenge {
+	return &data.MFAChallenge{
+		ID:        c.ID,
+		UserID:    c.UserID,
+		CreatedAt: c.CreatedAt,
+		ExpiresAt: c.ExpiresAt,
 	}
 }
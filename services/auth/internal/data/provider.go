@@ -7,11 +7,24 @@ import (
 )
 
 var (
-	ErrUserNotFound          = errors.New("user not found")
-	ErrClientNotFound        = errors.New("client not found")
-	ErrSessionNotFound       = errors.New("session not found")
-	ErrAuthorizationNotFound = errors.New("auth code not found")
-	ErrInvalidCredential     = errors.New("invalid credentials")
+	ErrUserNotFound              = errors.New("user not found")
+	ErrClientNotFound            = errors.New("client not found")
+	ErrSessionNotFound           = errors.New("session not found")
+	ErrAuthorizationNotFound     = errors.New("auth code not found")
+	ErrAuthorizationCodeUsed     = errors.New("auth code already used")
+	ErrAuthRequestNotFound       = errors.New("authorization request not found")
+	ErrSigningKeyExists          = errors.New("signing key with this status already exists")
+	ErrInvalidCredential         = errors.New("invalid credentials")
+	ErrTokenFamilyNotFound       = errors.New("token family not found")
+	ErrRefreshTokenNotFound      = errors.New("refresh token not found")
+	ErrRefreshTokenUsed          = errors.New("refresh token already used")
+	ErrUserMFANotFound           = errors.New("mfa not enrolled")
+	ErrMFAAlreadyEnabled         = errors.New("mfa already enabled")
+	ErrMFAStepUsed               = errors.New("mfa code already used")
+	ErrMFAChallengeNotFound      = errors.New("mfa challenge not found")
+	ErrRecoveryCodeUsed          = errors.New("recovery code already used")
+	ErrEmailVerificationNotFound = errors.New("email verification not found")
+	ErrPasswordResetNotFound     = errors.New("password reset not found")
 )
 
 type CreateClientParams struct {
@@ -19,12 +32,33 @@ type CreateClientParams struct {
 	Website      string
 	Scope        string
 	HashedSecret string
+	IsPublic     bool
+	RedirectURIs []string
 }
 
 type CreateAuthorizationParams struct {
-	UserID   int64
-	ClientID int64
-	Scope    string
+	UserID              int64
+	ClientID            int64
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	RedirectURI         string
+	Nonce               string
+	AuthTime            time.Time
+}
+
+type CreateAuthorizationRequestParams struct {
+	SessionID           string
+	UserID              int64
+	ClientID            int64
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	AuthTime            time.Time
+	ExpiresAt           time.Time
 }
 
 type CreateUserParams struct {
@@ -43,13 +77,74 @@ type UpdateSessionParams struct {
 	ExpiresAt time.Time
 }
 
+type RevokeTokenParams struct {
+	TokenID   string
+	GrantID   string // set to revoke every token issued from the same grant
+	ExpiresAt time.Time
+}
+
+type CreateTokenFamilyParams struct {
+	AuthCode string
+	UserID   int64
+	ClientID int64
+}
+
+type CreateRefreshTokenParams struct {
+	TokenHash string
+	FamilyID  string
+}
+
+type CreateSigningKeyParams struct {
+	ID         string
+	Algorithm  string
+	PrivateKey []byte // encrypted PKCS #8 DER
+	PublicKey  []byte
+	Status     SigningKeyStatus
+}
+
+// RotateSigningKeysParams describes a rotation: the active key is retired, the next key becomes active
+// and Next is stored as the new next key. The rotation only happens if the active key was activated
+// before ActivatedBefore, so concurrent rotations don't rotate twice.
+type RotateSigningKeysParams struct {
+	ActivatedBefore  time.Time
+	RetiredExpiresAt time.Time
+	Next             CreateSigningKeyParams
+}
+
+type CreateUserMFAParams struct {
+	UserID          int64
+	EncryptedSecret []byte
+}
+
+type CreateEmailVerificationParams struct {
+	TokenHash string
+	UserID    int64
+	Email     string // address the token verifies, so that it can't verify a changed one
+	ExpiresAt time.Time
+}
+
+type CreatePasswordResetParams struct {
+	TokenHash string
+	UserID    int64
+	ExpiresAt time.Time
+}
+
+type CreateMFAChallengeParams struct {
+	UserID    int64
+	ExpiresAt time.Time
+}
+
 type DataProvider interface {
 	CreateUser(ctx context.Context, params CreateUserParams) (*User, error)
 	GetUserByID(ctx context.Context, userID int64) (*User, error)
 	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	UpdateUserPassword(ctx context.Context, userID int64, hashedPassword []byte) error
 
 	CreateSession(ctx context.Context, params CreateSessionParams) (*Session, error)
 	DeleteSessionByID(ctx context.Context, sessionID string) error
+	DeleteSessionsByUserID(ctx context.Context, userID int64) error
+	DeleteOtherSessionsByUserID(ctx context.Context, userID int64, sessionID string) error
 	UpdateSession(ctx context.Context, params UpdateSessionParams) (*Session, error)
 	GetSessionByID(ctx context.Context, sessionID string) (*Session, error)
 
@@ -58,6 +153,61 @@ type DataProvider interface {
 
 	CreateAuthorization(ctx context.Context, params CreateAuthorizationParams) (*Authorization, error)
 	GetAuthorizationCodeByAuthCode(ctx context.Context, authCode string) (*Authorization, error)
-	GetAuthorizationCodeByUserIDAndClientID(ctx context.Context, clientID, userID int64) (*Authorization, error)
 	RevokeAuthorizationByUserID(ctx context.Context, userID int64) error
+	ConsumeAuthorizationCode(ctx context.Context, authCode string, check func(*Authorization) error) (*Authorization, error)
+	RevokeAuthorizationCode(ctx context.Context, authCode string) error
+
+	CreateAuthorizationRequest(ctx context.Context, params CreateAuthorizationRequestParams) (*AuthorizationRequest, error)
+	GetAuthorizationRequestByID(ctx context.Context, requestID string) (*AuthorizationRequest, error)
+	ResolveAuthorizationRequest(ctx context.Context, requestID string, status AuthorizationRequestStatus) (*AuthorizationRequest, error)
+	DeleteExpiredAuthorizationRequests(ctx context.Context) error
+
+	RevokeToken(ctx context.Context, params RevokeTokenParams) error
+	IsTokenRevoked(ctx context.Context, tokenID, grantID string) (bool, error)
+	DeleteExpiredRevokedTokens(ctx context.Context) error
+
+	CreateTokenFamily(ctx context.Context, params CreateTokenFamilyParams) (*TokenFamily, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	RevokeTokenFamiliesByUserID(ctx context.Context, userID int64) error
+	CreateRefreshToken(ctx context.Context, params CreateRefreshTokenParams) (*RefreshToken, error)
+	UseRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error)
+
+	CreateSigningKey(ctx context.Context, params CreateSigningKeyParams) (*SigningKey, error)
+	GetSigningKeys(ctx context.Context) ([]*SigningKey, error)
+	RotateSigningKeys(ctx context.Context, params RotateSigningKeysParams) (bool, error)
+	DeleteExpiredSigningKeys(ctx context.Context) error
+
+	RecordLoginAttempt(ctx context.Context, key string, resetBefore time.Time, allow func(*LoginAttempts) bool) (*LoginAttempts, error)
+	ReleaseLoginAttempts(ctx context.Context, keys []string) error
+	ResetLoginAttempts(ctx context.Context, keys []string) error
+	DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error
+
+	CreateUserMFA(ctx context.Context, params CreateUserMFAParams) (*UserMFA, error)
+	GetUserMFA(ctx context.Context, userID int64) (*UserMFA, error)
+	ConfirmUserMFA(ctx context.Context, userID int64) error
+	UseMFAStep(ctx context.Context, userID int64, step int64) error
+	DeleteUserMFA(ctx context.Context, userID int64) error
+
+	CreateEmailVerification(ctx context.Context, params CreateEmailVerificationParams) (*EmailVerification, error)
+	GetLatestEmailVerification(ctx context.Context, userID int64) (*EmailVerification, error)
+	UseEmailVerification(ctx context.Context, tokenHash string) (*EmailVerification, error)
+	DeleteExpiredEmailVerifications(ctx context.Context) error
+
+	CreatePasswordReset(ctx context.Context, params CreatePasswordResetParams) (*PasswordReset, error)
+	GetPasswordReset(ctx context.Context, tokenHash string) (*PasswordReset, error)
+	GetLatestPasswordReset(ctx context.Context, userID int64) (*PasswordReset, error)
+	UsePasswordReset(ctx context.Context, tokenHash string, hashedPassword []byte) (*PasswordReset, error)
+	DeleteExpiredPasswordResets(ctx context.Context) error
+
+	AddPasswordHistory(ctx context.Context, userID int64, hashedPassword []byte, keep int) error
+	GetPasswordHistory(ctx context.Context, userID int64, limit int) ([]*PasswordHistory, error)
+
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, hashedCodes []string) error
+	GetUnusedRecoveryCodes(ctx context.Context, userID int64) ([]*RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, codeID int64) error
+
+	CreateMFAChallenge(ctx context.Context, params CreateMFAChallengeParams) (*MFAChallenge, error)
+	GetMFAChallengeByID(ctx context.Context, challengeID string) (*MFAChallenge, error)
+	DeleteMFAChallenge(ctx context.Context, challengeID string) error
+	DeleteExpiredMFAChallenges(ctx context.Context) error
 }
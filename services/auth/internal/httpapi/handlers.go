@@ -0,0 +1,581 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ramyadmz/goauth/internal/auth"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/pkg/pb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tokenResponse is the successful token endpoint response of RFC 6749 section 5.1.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// authorizeResponse describes a pending authorization request the user has to consent to.
+type authorizeResponse struct {
+	RequestID  string `json:"request_id"`
+	ClientName string `json:"client_name"`
+	Scope      string `json:"scope"`
+}
+
+// introspectResponse is the introspection response of RFC 7662 section 2.2.
+type introspectResponse struct {
+	Active    bool   `json:"active"`
+	Sub       string `json:"sub,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+}
+
+// metadataResponse is the authorization server metadata of RFC 8414 section 2, which is also a valid
+// OpenID Provider metadata document (OIDC Discovery section 3).
+type metadataResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// userInfoResponse is the UserInfo response of OIDC Core section 5.3.2.
+type userInfoResponse struct {
+	Sub               string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+}
+
+// handleAuthorize serves the authorization endpoint (RFC 6749 section 3.1). A GET starts an
+// authorization request for the logged in user and describes it for the consent screen, sending
+// users without a session to the login page; a POST answers it and redirects the user agent back
+// to the client.
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.startAuthorization(w, r)
+	case http.MethodPost:
+		s.answerAuthorization(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, ErrorInvalidRequest, "method not allowed")
+	}
+}
+
+func (s *Server) startAuthorization(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	clientID, err := strconv.ParseInt(query.Get("client_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "client_id is not valid")
+		return
+	}
+
+	// Until the client and redirect uri are known to be valid, errors are shown to the user instead of
+	// being redirected, so the endpoint can't be used as an open redirector (RFC 6749 section 4.1.2.1).
+	redirectURI := query.Get("redirect_uri")
+	if err := s.users.CheckRedirectURI(r.Context(), clientID, redirectURI); err != nil {
+		writeClientError(w, err, false)
+		return
+	}
+
+	state := query.Get("state")
+	if responseType := query.Get("response_type"); responseType != auth.ResponseTypeCode {
+		redirectError(w, r, redirectURI, state, ErrorUnsupportedResponseType, "only the code response type is supported")
+		return
+	}
+
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil || len(cookie.Value) == 0 {
+		s.redirectToLogin(w, r, redirectURI, state)
+		return
+	}
+
+	req := &pb.AuthorizeRequest{
+		SessionId:           cookie.Value,
+		ClientId:            clientID,
+		RedirectUri:         redirectURI,
+		Scope:               query.Get("scope"),
+		State:               state,
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+		Nonce:               query.Get("nonce"),
+	}
+
+	rsp, err := s.call(r.Context(), "Authorize", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.users.Authorize(ctx, req.(*pb.AuthorizeRequest))
+	})
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			s.redirectToLogin(w, r, redirectURI, state)
+			return
+		}
+		oauthError, description := authorizationError(err)
+		redirectError(w, r, redirectURI, state, oauthError, description)
+		return
+	}
+
+	authorized := rsp.(*pb.AuthorizeResponse)
+	writeJSON(w, http.StatusOK, authorizeResponse{
+		RequestID:  authorized.RequestId,
+		ClientName: authorized.ClientName,
+		Scope:      authorized.Scope,
+	})
+}
+
+func (s *Server) answerAuthorization(w http.ResponseWriter, r *http.Request) {
+	if !parseForm(w, r) {
+		return
+	}
+
+	approved, err := strconv.ParseBool(r.PostForm.Get("approved"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "approved is not valid")
+		return
+	}
+
+	sessionID, ok := sessionFromCookie(w, r)
+	if !ok {
+		return
+	}
+
+	req := &pb.UserConsentRequest{
+		SessionId: sessionID,
+		RequestId: r.PostForm.Get("request_id"),
+		Approved:  approved,
+		Scope:     r.PostForm.Get("scope"),
+	}
+
+	rsp, err := s.call(r.Context(), "UserConsent", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.users.ConsentUser(ctx, req.(*pb.UserConsentRequest))
+	})
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	// The redirect uri was validated against the client's registration when the request was created.
+	consent := rsp.(*pb.UserConsentResponse)
+	params := url.Values{}
+	if len(consent.Error) > 0 {
+		params.Set("error", consent.Error)
+	} else {
+		params.Set("code", consent.AuthorizationCode)
+	}
+	redirectToClient(w, r, consent.RedirectUri, consent.State, params)
+}
+
+// redirectToLogin sends a user without a valid session to the login page, which returns them to the
+// authorization request once they are logged in. Without a login page the client is told that the
+// user has to log in.
+func (s *Server) redirectToLogin(w http.ResponseWriter, r *http.Request, redirectURI, state string) {
+	if len(s.loginURL) == 0 {
+		redirectError(w, r, redirectURI, state, ErrorLoginRequired, "user is not logged in")
+		return
+	}
+
+	login, err := url.Parse(s.loginURL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrorServerError, "")
+		return
+	}
+
+	params := login.Query()
+	params.Set("return_to", r.URL.RequestURI())
+	login.RawQuery = params.Encode()
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, login.String(), http.StatusFound)
+}
+
+// redirectError redirects an error of an authorization request to the client (RFC 6749 section 4.1.2.1).
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI, state, oauthError, description string) {
+	params := url.Values{}
+	params.Set("error", oauthError)
+	if len(description) > 0 {
+		params.Set("error_description", description)
+	}
+	redirectToClient(w, r, redirectURI, state, params)
+}
+
+// redirectToClient redirects the user agent to a validated redirect uri of the client, adding params
+// and the state of the authorization request to its query.
+func redirectToClient(w http.ResponseWriter, r *http.Request, redirectURI, state string, params url.Values) {
+	redirect, err := url.Parse(redirectURI)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrorServerError, "")
+		return
+	}
+
+	query := redirect.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if len(state) > 0 {
+		query.Set("state", state)
+	}
+	redirect.RawQuery = query.Encode()
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// authorizationError translates an error of the Authorize call into the error code and description
+// redirected to the client.
+func authorizationError(err error) (string, string) {
+	st := status.Convert(err)
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidScope):
+		return ErrorInvalidScope, st.Message()
+	case st.Code() == codes.InvalidArgument:
+		return ErrorInvalidRequest, st.Message()
+	case st.Code() == codes.PermissionDenied:
+		return ErrorAccessDenied, st.Message()
+	case st.Code() == codes.ResourceExhausted:
+		return ErrorTemporarilyUnavailable, st.Message()
+	default:
+		logrus.Error("unexpected error serving authorization request: %w", err)
+		return ErrorServerError, ""
+	}
+}
+
+// handleToken serves the token endpoint (RFC 6749 section 3.2), dispatching on the grant type.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if !parseForm(w, r) {
+		return
+	}
+
+	clientID, clientSecret, basic, err := clientCredentials(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	var rsp tokenResponse
+	switch r.PostForm.Get("grant_type") {
+	case auth.GrantTypeAuthorizationCode:
+		req := &pb.ExchangeTokenRequest{
+			ClientId:          clientID,
+			ClientSecret:      clientSecret,
+			AuthorizationCode: r.PostForm.Get("code"),
+			CodeVerifier:      r.PostForm.Get("code_verifier"),
+			RedirectUri:       r.PostForm.Get("redirect_uri"),
+		}
+		res, err := s.call(r.Context(), "ExchangeToken", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.clients.ExchangeToken(ctx, req.(*pb.ExchangeTokenRequest))
+		})
+		if err != nil {
+			writeClientError(w, err, basic)
+			return
+		}
+		exchanged := res.(*pb.ExchangeTokenResponse)
+		rsp = tokenResponse{
+			AccessToken:  exchanged.AccessToken,
+			RefreshToken: exchanged.RefreshToken,
+			Scope:        exchanged.Scope,
+			IDToken:      exchanged.IdToken,
+		}
+
+	case auth.GrantTypeRefreshToken:
+		req := &pb.RefreshTokenRequest{
+			ClientId:     clientID,
+			ClientSecret: clientSecret,
+			RefreshToken: r.PostForm.Get("refresh_token"),
+			Scope:        r.PostForm.Get("scope"),
+		}
+		res, err := s.call(r.Context(), "RefreshToken", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.clients.RefreshToken(ctx, req.(*pb.RefreshTokenRequest))
+		})
+		if err != nil {
+			writeClientError(w, err, basic)
+			return
+		}
+		refreshed := res.(*pb.RefreshTokenResponse)
+		rsp = tokenResponse{
+			AccessToken:  refreshed.AccessToken,
+			RefreshToken: refreshed.RefreshToken,
+			Scope:        refreshed.Scope,
+		}
+
+	case auth.GrantTypeClientCredentials:
+		req := &pb.ClientCredentialsTokenRequest{
+			ClientId:     clientID,
+			ClientSecret: clientSecret,
+			Scope:        r.PostForm.Get("scope"),
+		}
+		res, err := s.call(r.Context(), "ClientCredentialsToken", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.clients.ClientCredentialsToken(ctx, req.(*pb.ClientCredentialsTokenRequest))
+		})
+		if err != nil {
+			writeClientError(w, err, basic)
+			return
+		}
+		issued := res.(*pb.ClientCredentialsTokenResponse)
+		rsp = tokenResponse{
+			AccessToken: issued.AccessToken,
+			Scope:       issued.Scope,
+		}
+
+	case "":
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "grant_type is required")
+		return
+
+	default:
+		writeError(w, http.StatusBadRequest, ErrorUnsupportedGrantType, "")
+		return
+	}
+
+	rsp.TokenType = "Bearer"
+	rsp.ExpiresIn = int64(s.tokenTTL.Seconds())
+	writeJSON(w, http.StatusOK, rsp)
+}
+
+// handleRevoke serves the revocation endpoint (RFC 7009 section 2).
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if !parseForm(w, r) {
+		return
+	}
+
+	clientID, clientSecret, basic, err := clientCredentials(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	req := &pb.RevokeTokenRequest{
+		ClientId:     clientID,
+		ClientSecret: clientSecret,
+		Token:        r.PostForm.Get("token"),
+	}
+
+	_, err = s.call(r.Context(), "RevokeToken", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.clients.RevokeToken(ctx, req.(*pb.RevokeTokenRequest))
+	})
+	if err != nil {
+		writeClientError(w, err, basic)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleIntrospect serves the introspection endpoint (RFC 7662 section 2).
+func (s *Server) handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	if !parseForm(w, r) {
+		return
+	}
+
+	clientID, clientSecret, basic, err := clientCredentials(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	req := &pb.IntrospectTokenRequest{
+		ClientId:     clientID,
+		ClientSecret: clientSecret,
+		Token:        r.PostForm.Get("token"),
+	}
+
+	res, err := s.call(r.Context(), "IntrospectToken", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.clients.IntrospectToken(ctx, req.(*pb.IntrospectTokenRequest))
+	})
+	if err != nil {
+		writeClientError(w, err, basic)
+		return
+	}
+
+	introspected := res.(*pb.IntrospectTokenResponse)
+	if !introspected.Active {
+		writeJSON(w, http.StatusOK, introspectResponse{Active: false})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, introspectResponse{
+		Active:    true,
+		Sub:       introspected.Sub,
+		ClientID:  strconv.FormatInt(introspected.ClientId, 10),
+		Scope:     introspected.Scope,
+		Exp:       introspected.Exp,
+		Iat:       introspected.Iat,
+		Iss:       introspected.Iss,
+		Aud:       introspected.Aud,
+		TokenType: introspected.TokenType,
+	})
+}
+
+// handleUserInfo serves the UserInfo endpoint (OIDC Core section 5.3). The access token is presented
+// as a bearer token (RFC 6750 section 2.1), and errors are reported in the WWW-Authenticate header.
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, ErrorInvalidRequest, "method not allowed")
+		return
+	}
+
+	token, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oauth"`)
+		writeError(w, http.StatusUnauthorized, ErrorInvalidRequest, "bearer token is required")
+		return
+	}
+
+	req := &pb.UserInfoRequest{AccessToken: token}
+	res, err := s.call(r.Context(), "UserInfo", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.clients.UserInfo(ctx, req.(*pb.UserInfoRequest))
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unauthenticated, codes.InvalidArgument:
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, ErrorInvalidToken, status.Convert(err).Message())
+		case codes.PermissionDenied:
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			writeError(w, http.StatusForbidden, ErrorInsufficientScope, status.Convert(err).Message())
+		default:
+			writeClientError(w, err, false)
+		}
+		return
+	}
+
+	info := res.(*pb.UserInfoResponse)
+	writeJSON(w, http.StatusOK, userInfoResponse{
+		Sub:               info.Sub,
+		PreferredUsername: info.PreferredUsername,
+		Email:             info.Email,
+		EmailVerified:     info.EmailVerified,
+	})
+}
+
+// handleMetadata serves the authorization server metadata (RFC 8414 section 3) and the OpenID
+// Provider configuration (OIDC Discovery section 4), which share the same document.
+func (s *Server) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, ErrorInvalidRequest, "method not allowed")
+		return
+	}
+
+	res, err := s.call(r.Context(), "GetServerMetadata", &pb.GetServerMetadataRequest{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.metadata.GetServerMetadata(ctx, req.(*pb.GetServerMetadataRequest))
+	})
+	if err != nil {
+		writeClientError(w, err, false)
+		return
+	}
+
+	metadata := res.(*pb.GetServerMetadataResponse)
+	writeJSON(w, http.StatusOK, metadataResponse{
+		Issuer:                            metadata.Issuer,
+		AuthorizationEndpoint:             metadata.AuthorizationEndpoint,
+		TokenEndpoint:                     metadata.TokenEndpoint,
+		RevocationEndpoint:                metadata.RevocationEndpoint,
+		IntrospectionEndpoint:             metadata.IntrospectionEndpoint,
+		UserinfoEndpoint:                  metadata.UserinfoEndpoint,
+		JwksURI:                           metadata.JwksUri,
+		ScopesSupported:                   metadata.ScopesSupported,
+		ResponseTypesSupported:            metadata.ResponseTypesSupported,
+		GrantTypesSupported:               metadata.GrantTypesSupported,
+		TokenEndpointAuthMethodsSupported: metadata.TokenEndpointAuthMethodsSupported,
+		CodeChallengeMethodsSupported:     metadata.CodeChallengeMethodsSupported,
+		IDTokenSigningAlgValuesSupported:  metadata.IdTokenSigningAlgValuesSupported,
+		SubjectTypesSupported:             metadata.SubjectTypesSupported,
+		ClaimsSupported:                   metadata.ClaimsSupported,
+	})
+}
+
+// handleJWKS publishes the keys tokens can be verified with as a JSON Web Key Set (RFC 7517 section 5).
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, ErrorInvalidRequest, "method not allowed")
+		return
+	}
+
+	res, err := s.call(r.Context(), "GetJWKS", &pb.GetJWKSRequest{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.clients.GetJWKS(ctx, req.(*pb.GetJWKSRequest))
+	})
+	if err != nil {
+		writeClientError(w, err, false)
+		return
+	}
+
+	keySet := credentials.JWKSet{Keys: []credentials.JWK{}}
+	for _, key := range res.(*pb.GetJWKSResponse).Keys {
+		keySet.Keys = append(keySet.Keys, credentials.JWK{
+			KeyType:   key.Kty,
+			KeyID:     key.Kid,
+			Use:       key.Use,
+			Algorithm: key.Alg,
+			N:         key.N,
+			E:         key.E,
+			Curve:     key.Crv,
+			X:         key.X,
+			Y:         key.Y,
+		})
+	}
+	writeJSON(w, http.StatusOK, keySet)
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || len(token) == 0 {
+		return "", false
+	}
+	return token, true
+}
+
+// sessionFromCookie returns the login session of the user agent, or writes a login_required error.
+func sessionFromCookie(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil || len(cookie.Value) == 0 {
+		writeError(w, http.StatusUnauthorized, ErrorLoginRequired, "user is not logged in")
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// writeSessionError translates an error of the user facing authorization calls. An invalid session
+// means the user has to log in again rather than that a grant was rejected.
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		writeError(w, http.StatusUnauthorized, ErrorLoginRequired, status.Convert(err).Message())
+	case codes.PermissionDenied:
+		writeError(w, http.StatusForbidden, ErrorInvalidRequest, status.Convert(err).Message())
+	case codes.NotFound, codes.FailedPrecondition:
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, status.Convert(err).Message())
+	default:
+		writeClientError(w, err, false)
+	}
+}
@@ -0,0 +1,224 @@
+// Package httpapi exposes the OAuth 2.0 endpoints of the auth service over plain HTTP.
+//
+// Handlers only translate between HTTP and the gRPC messages of OAuthService; all protocol logic
+// lives in the auth package. Requests go through the same interceptors as gRPC calls.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ramyadmz/goauth/internal/auth"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// SessionCookieName is the cookie that carries the id of the user's login session.
+const SessionCookieName = "session_id"
+
+// OAuth 2.0 error codes (RFC 6749 section 5.2, RFC 6750 section 3.1).
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorInvalidToken            = "invalid_token"
+	ErrorInsufficientScope       = "insufficient_scope"
+	ErrorLoginRequired           = "login_required"
+	ErrorAccessDenied            = "access_denied"
+	ErrorServerError             = "server_error"
+	ErrorTemporarilyUnavailable  = "temporarily_unavailable"
+)
+
+// Server serves the OAuth 2.0 HTTP endpoints.
+type Server struct {
+	users        *auth.UserAuthService
+	clients      *auth.ClientAuthService
+	metadata     *auth.MetadataService
+	tokenTTL     time.Duration // lifetime of access tokens, reported as expires_in
+	loginURL     string        // page users without a session are sent to, empty to report login_required
+	interceptors []grpc.UnaryServerInterceptor
+}
+
+// NewServer creates a new Server delegating to the given services. The interceptors run around every
+// call in the given order, just like on the gRPC server.
+func NewServer(users *auth.UserAuthService, clients *auth.ClientAuthService, metadata *auth.MetadataService, tokenTTL time.Duration, loginURL string, interceptors ...grpc.UnaryServerInterceptor) *Server {
+	return &Server{
+		users:        users,
+		clients:      clients,
+		metadata:     metadata,
+		tokenTTL:     tokenTTL,
+		loginURL:     loginURL,
+		interceptors: interceptors,
+	}
+}
+
+// Handler returns the HTTP handler serving all endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(auth.AuthorizationPath, s.handleAuthorize)
+	mux.HandleFunc(auth.TokenPath, s.handleToken)
+	mux.HandleFunc(auth.RevocationPath, s.handleRevoke)
+	mux.HandleFunc(auth.IntrospectionPath, s.handleIntrospect)
+	mux.HandleFunc(auth.UserInfoPath, s.handleUserInfo)
+	mux.HandleFunc(auth.JWKSPath, s.handleJWKS)
+	mux.HandleFunc(auth.AuthorizationServerMetadataPath, s.handleMetadata)
+	mux.HandleFunc(auth.OpenIDConfigurationPath, s.handleMetadata)
+	return withPeer(mux)
+}
+
+// withPeer puts the client address into the request context the way gRPC does, so that the auth
+// services can throttle HTTP requests by source address too.
+func withPeer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, port, err := net.SplitHostPort(r.RemoteAddr)
+		if err == nil {
+			if ip := net.ParseIP(host); ip != nil {
+				portNumber, _ := strconv.Atoi(port)
+				r = r.WithContext(peer.NewContext(r.Context(), &peer.Peer{Addr: &net.TCPAddr{IP: ip, Port: portNumber}}))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// errorResponse is the JSON error body of RFC 6749 section 5.2.
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// call invokes handler for the OAuthService method through the interceptor chain.
+func (s *Server) call(ctx context.Context, method string, req interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.OAuthService/" + method}
+
+	chained := handler
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := s.interceptors[i], chained
+		chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+	return chained(ctx, req)
+}
+
+// clientCredentials extracts the client id and secret from HTTP Basic authentication or, for clients
+// that can't use it, from the request body (RFC 6749 section 2.3.1). It reports whether Basic was used.
+func clientCredentials(r *http.Request) (int64, string, bool, error) {
+	username, password, basic := r.BasicAuth()
+	if basic {
+		if len(r.PostForm.Get("client_id")) > 0 || len(r.PostForm.Get("client_secret")) > 0 {
+			return 0, "", true, errors.New("client authenticated with more than one method")
+		}
+
+		var err error
+		if username, err = url.QueryUnescape(username); err != nil {
+			return 0, "", true, err
+		}
+		if password, err = url.QueryUnescape(password); err != nil {
+			return 0, "", true, err
+		}
+	} else {
+		username, password = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	clientID, err := strconv.ParseInt(username, 10, 64)
+	if err != nil {
+		return 0, "", basic, errors.New("client_id is not valid")
+	}
+	return clientID, password, basic, nil
+}
+
+// parseForm parses a form encoded POST body.
+func parseForm(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, ErrorInvalidRequest, "method not allowed")
+		return false
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "content type must be application/x-www-form-urlencoded")
+		return false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "malformed request body")
+		return false
+	}
+	return true
+}
+
+// writeClientError translates an error returned by the auth services into an OAuth error response.
+// Unauthenticated errors other than client authentication failures mean the grant was rejected.
+func writeClientError(w http.ResponseWriter, err error, basic bool) {
+	st := status.Convert(err)
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidClient):
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeError(w, http.StatusUnauthorized, ErrorInvalidClient, st.Message())
+	case errors.Is(err, auth.ErrInvalidScope):
+		writeError(w, http.StatusBadRequest, ErrorInvalidScope, st.Message())
+	default:
+		switch st.Code() {
+		case codes.InvalidArgument:
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, st.Message())
+		case codes.Unauthenticated, codes.FailedPrecondition, codes.NotFound:
+			writeError(w, http.StatusBadRequest, ErrorInvalidGrant, st.Message())
+		case codes.PermissionDenied:
+			writeError(w, http.StatusBadRequest, ErrorUnauthorizedClient, st.Message())
+		case codes.ResourceExhausted:
+			writeThrottled(w, st)
+		default:
+			logrus.Error("unexpected error serving oauth request: %w", err)
+			writeError(w, http.StatusInternalServerError, ErrorServerError, "")
+		}
+	}
+}
+
+// writeThrottled writes a 429 response, telling the client how long to wait when the error says so.
+func writeThrottled(w http.ResponseWriter, st *status.Status) {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+			seconds := int64(math.Ceil(info.RetryDelay.AsDuration().Seconds()))
+			w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+		}
+	}
+	writeError(w, http.StatusTooManyRequests, ErrorTemporarilyUnavailable, st.Message())
+}
+
+func writeError(w http.ResponseWriter, code int, oauthError, description string) {
+	writeJSON(w, code, errorResponse{
+		Error:            oauthError,
+		ErrorDescription: description,
+	})
+}
+
+// writeJSON writes body as JSON. Responses may carry credentials, so they must never be cached.
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logrus.Error("error writing response: %w", err)
+	}
+}
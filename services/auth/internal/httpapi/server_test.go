@@ -0,0 +1,443 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	"github.com/ramyadmz/goauth/integration"
+	"github.com/ramyadmz/goauth/internal/auth"
+	"github.com/ramyadmz/goauth/internal/config"
+	"github.com/ramyadmz/goauth/internal/credentials"
+	"github.com/ramyadmz/goauth/internal/credentials/hasher"
+	credMock "github.com/ramyadmz/goauth/internal/credentials/mock"
+	"github.com/ramyadmz/goauth/internal/credentials/policy"
+	"github.com/ramyadmz/goauth/internal/credentials/throttle"
+	"github.com/ramyadmz/goauth/internal/data"
+	dalMock "github.com/ramyadmz/goauth/internal/data/mock"
+	"github.com/ramyadmz/goauth/internal/mail"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestServer(mockDAL *dalMock.DataProvider, mockSessionManager *credMock.SessionManager, mockTokenHandler *credMock.TokenHandler) http.Handler {
+	return newTestServerWithLogin(mockDAL, mockSessionManager, mockTokenHandler, "")
+}
+
+func newTestServerWithLogin(mockDAL *dalMock.DataProvider, mockSessionManager *credMock.SessionManager, mockTokenHandler *credMock.TokenHandler, loginURL string) http.Handler {
+	integration.SetUpLocalTestEnvs()
+	defer integration.UnSetLocalTestEnvs()
+	os.Setenv("OAUTH_PUBLIC_URL", "https://auth.test")
+	defer os.Unsetenv("OAUTH_PUBLIC_URL")
+
+	jwtConfig, _ := config.NewJWTConfig()
+	serverConfig, _ := config.NewServerConfig()
+
+	throttleConfig, _ := config.NewThrottleConfig()
+	throttler := throttle.NewThrottler(throttleConfig, throttle.NewMemoryStore())
+
+	emailConfig, _ := config.NewEmailVerificationConfig()
+	resetConfig, _ := config.NewPasswordResetConfig()
+	secureHasher := hasher.NewBcryptHasher(bcrypt.MinCost)
+	policyConfig, _ := config.NewPasswordPolicyConfig()
+
+	users := auth.NewUserAuthService(mockDAL, mockSessionManager, &credMock.MFAManager{}, secureHasher, policy.NewPasswordPolicy(policyConfig, mockDAL, secureHasher), throttler, mail.NewMemoryMailer(), emailConfig, resetConfig)
+	clients := auth.NewClientAuthService(mockDAL, mockTokenHandler, secureHasher, throttler)
+	metadata := auth.NewMetadataService(jwtConfig, serverConfig)
+	return NewServer(users, clients, metadata, 15*time.Minute, loginURL, auth.ValidationInterceptor).Handler()
+}
+
+func newConfidentialClient(t *testing.T) (*data.Client, string) {
+	secret := uuid.NewString()
+	hashedSecret, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.MinCost)
+	assert.Equal(t, err, nil)
+
+	return &data.Client{
+		ID:           rand.Int63(),
+		HashedSecret: hashedSecret,
+		Name:         uuid.NewString(),
+		Scope:        "read write",
+	}, secret
+}
+
+func postForm(target string, form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) errorResponse {
+	var body errorResponse
+	assert.Equal(t, json.NewDecoder(rec.Body).Decode(&body), nil)
+	return body
+}
+
+func TestToken_ClientCredentialsWithBasicAuth(t *testing.T) {
+	client, secret := newConfidentialClient(t)
+	accessToken := uuid.NewString()
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, client.ID).Return(client, nil)
+
+	mockTokenHandler := &credMock.TokenHandler{}
+	mockTokenHandler.On("Generate", mock.Anything, mock.Anything, credentials.AccessToken).Return(accessToken, nil)
+
+	req := postForm("/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"read"}})
+	req.SetBasicAuth(strconv.FormatInt(client.ID, 10), secret)
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, &credMock.SessionManager{}, mockTokenHandler).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusOK)
+	assert.Equal(t, rec.Header().Get("Cache-Control"), "no-store")
+	assert.Equal(t, rec.Header().Get("Pragma"), "no-cache")
+
+	var body tokenResponse
+	assert.Equal(t, json.NewDecoder(rec.Body).Decode(&body), nil)
+	assert.Equal(t, body.AccessToken, accessToken)
+	assert.Equal(t, body.TokenType, "Bearer")
+	assert.Equal(t, body.ExpiresIn, int64(900))
+	assert.Equal(t, body.Scope, "read")
+}
+
+func TestToken_InvalidClientSecret(t *testing.T) {
+	client, _ := newConfidentialClient(t)
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, client.ID).Return(client, nil)
+
+	req := postForm("/token", url.Values{"grant_type": {"client_credentials"}})
+	req.SetBasicAuth(strconv.FormatInt(client.ID, 10), uuid.NewString())
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusUnauthorized)
+	assert.NotEqual(t, rec.Header().Get("WWW-Authenticate"), "")
+	assert.Equal(t, decodeError(t, rec).Error, ErrorInvalidClient)
+}
+
+func TestToken_ThrottledClient(t *testing.T) {
+	client, _ := newConfidentialClient(t)
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, client.ID).Return(client, nil)
+	handler := newTestServer(mockDAL, &credMock.SessionManager{}, &credMock.TokenHandler{})
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i <= config.DefaultThrottleFreeAttempts+1; i++ {
+		req := postForm("/token", url.Values{"grant_type": {"client_credentials"}})
+		req.SetBasicAuth(strconv.FormatInt(client.ID, 10), uuid.NewString())
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+	}
+
+	assert.Equal(t, rec.Code, http.StatusTooManyRequests)
+	assert.Equal(t, rec.Header().Get("Retry-After"), "1")
+	assert.Equal(t, decodeError(t, rec).Error, ErrorTemporarilyUnavailable)
+}
+
+func TestToken_MultipleClientAuthMethods(t *testing.T) {
+	client, secret := newConfidentialClient(t)
+
+	req := postForm("/token", url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {strconv.FormatInt(client.ID, 10)},
+		"client_secret": {secret},
+	})
+	req.SetBasicAuth(strconv.FormatInt(client.ID, 10), secret)
+	rec := httptest.NewRecorder()
+	newTestServer(&dalMock.DataProvider{}, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusBadRequest)
+	assert.Equal(t, decodeError(t, rec).Error, ErrorInvalidRequest)
+}
+
+func TestToken_UnsupportedGrantType(t *testing.T) {
+	client, secret := newConfidentialClient(t)
+
+	req := postForm("/token", url.Values{
+		"grant_type":    {"password"},
+		"client_id":     {strconv.FormatInt(client.ID, 10)},
+		"client_secret": {secret},
+	})
+	rec := httptest.NewRecorder()
+	newTestServer(&dalMock.DataProvider{}, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusBadRequest)
+	assert.Equal(t, decodeError(t, rec).Error, ErrorUnsupportedGrantType)
+}
+
+func TestToken_InvalidGrant(t *testing.T) {
+	client, secret := newConfidentialClient(t)
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, client.ID).Return(client, nil)
+	mockDAL.On("ConsumeAuthorizationCode", mock.Anything, mock.Anything).Return((*data.Authorization)(nil), data.ErrAuthorizationNotFound)
+
+	req := postForm("/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {uuid.NewString()},
+		"redirect_uri":  {"https://client.test/callback"},
+		"client_id":     {strconv.FormatInt(client.ID, 10)},
+		"client_secret": {secret},
+	})
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusBadRequest)
+	assert.Equal(t, decodeError(t, rec).Error, ErrorInvalidGrant)
+}
+
+func TestToken_RequiresPost(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/token", nil)
+	rec := httptest.NewRecorder()
+	newTestServer(&dalMock.DataProvider{}, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusMethodNotAllowed)
+	assert.Equal(t, rec.Header().Get("Allow"), http.MethodPost)
+}
+
+// newRedirectClient returns a client with a registered redirect uri, served by a data provider mock.
+func newRedirectClient() (*data.Client, *dalMock.DataProvider) {
+	client := &data.Client{
+		ID:           rand.Int63(),
+		Name:         uuid.NewString(),
+		Scope:        "read",
+		RedirectURIs: []string{"https://client.test/callback"},
+	}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, client.ID).Return(client, nil)
+	return client, mockDAL
+}
+
+func authorizeURL(clientID int64, params url.Values) string {
+	params.Set("client_id", strconv.FormatInt(clientID, 10))
+	params.Set("redirect_uri", "https://client.test/callback")
+	return "/authorize?" + params.Encode()
+}
+
+func TestAuthorize_LoginRequired(t *testing.T) {
+	client, mockDAL := newRedirectClient()
+
+	req := httptest.NewRequest(http.MethodGet, authorizeURL(client.ID, url.Values{"response_type": {"code"}, "state": {"xyz"}}), nil)
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusFound)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, location.Host, "client.test")
+	assert.Equal(t, location.Query().Get("error"), ErrorLoginRequired)
+	assert.Equal(t, location.Query().Get("state"), "xyz")
+}
+
+func TestAuthorize_RedirectsToLogin(t *testing.T) {
+	client, mockDAL := newRedirectClient()
+
+	target := authorizeURL(client.ID, url.Values{"response_type": {"code"}})
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	newTestServerWithLogin(mockDAL, &credMock.SessionManager{}, &credMock.TokenHandler{}, "https://login.test/login").ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusFound)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, location.Host, "login.test")
+	assert.Equal(t, location.Query().Get("return_to"), target)
+}
+
+func TestAuthorize_UnsupportedResponseType(t *testing.T) {
+	client, mockDAL := newRedirectClient()
+
+	req := httptest.NewRequest(http.MethodGet, authorizeURL(client.ID, url.Values{"response_type": {"token"}}), nil)
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusFound)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, location.Query().Get("error"), ErrorUnsupportedResponseType)
+}
+
+func TestAuthorize_InvalidRedirectURI(t *testing.T) {
+	client, mockDAL := newRedirectClient()
+
+	params := url.Values{"response_type": {"code"}, "client_id": {strconv.FormatInt(client.ID, 10)}, "redirect_uri": {"https://attacker.test/callback"}}
+	req := httptest.NewRequest(http.MethodGet, "/authorize?"+params.Encode(), nil)
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusBadRequest)
+	assert.Equal(t, decodeError(t, rec).Error, ErrorInvalidRequest)
+}
+
+func TestAuthorize_InvalidScopeRedirectsToClient(t *testing.T) {
+	client, mockDAL := newRedirectClient()
+	sessionID := uuid.NewString()
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   rand.Int63(),
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, authorizeURL(client.ID, url.Values{"response_type": {"code"}, "scope": {"admin"}, "state": {"xyz"}}), nil)
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: sessionID})
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, mockSessionManager, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusFound)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, location.Host, "client.test")
+	assert.Equal(t, location.Query().Get("error"), ErrorInvalidScope)
+	assert.Equal(t, location.Query().Get("state"), "xyz")
+}
+
+func TestAuthorize_ConsentRedirectsWithCode(t *testing.T) {
+	userID := rand.Int63()
+	sessionID := uuid.NewString()
+	authRequest := &data.AuthorizationRequest{
+		ID:          uuid.NewString(),
+		SessionID:   sessionID,
+		UserID:      userID,
+		ClientID:    rand.Int63(),
+		RedirectURI: "https://client.test/callback",
+		Scope:       "read",
+		State:       "xyz",
+		Status:      data.AuthorizationRequestPending,
+		ExpiresAt:   time.Now().Add(auth.AuthorizationRequestExpTime),
+	}
+	authorization := &data.Authorization{AuthCode: uuid.NewString()}
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetAuthorizationRequestByID", mock.Anything, authRequest.ID).Return(authRequest, nil)
+	mockDAL.On("ResolveAuthorizationRequest", mock.Anything, authRequest.ID, data.AuthorizationRequestApproved).Return(authRequest, nil)
+	mockDAL.On("CreateAuthorization", mock.Anything, mock.Anything).Return(authorization, nil)
+
+	mockSessionManager := &credMock.SessionManager{}
+	mockSessionManager.On("Get", mock.Anything, sessionID).Return(&credentials.Session{
+		SessionID: sessionID,
+		Subject:   userID,
+	}, nil)
+
+	req := postForm("/authorize", url.Values{"request_id": {authRequest.ID}, "approved": {"true"}})
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: sessionID})
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, mockSessionManager, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusFound)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, location.Host, "client.test")
+	assert.Equal(t, location.Query().Get("code"), authorization.AuthCode)
+	assert.Equal(t, location.Query().Get("state"), authRequest.State)
+}
+
+func TestIntrospect_InactiveToken(t *testing.T) {
+	client, secret := newConfidentialClient(t)
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, client.ID).Return(client, nil)
+
+	mockTokenHandler := &credMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, mock.Anything).Return((*credentials.Claims)(nil), credentials.ErrInvalidToken)
+
+	req := postForm("/introspect", url.Values{"token": {uuid.NewString()}})
+	req.SetBasicAuth(strconv.FormatInt(client.ID, 10), secret)
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, &credMock.SessionManager{}, mockTokenHandler).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusOK)
+	assert.Equal(t, strings.TrimSpace(rec.Body.String()), `{"active":false}`)
+}
+
+func TestRevoke_HappyPath(t *testing.T) {
+	client, secret := newConfidentialClient(t)
+	token := uuid.NewString()
+
+	mockDAL := &dalMock.DataProvider{}
+	mockDAL.On("GetClientByID", mock.Anything, client.ID).Return(client, nil)
+
+	mockTokenHandler := &credMock.TokenHandler{}
+	mockTokenHandler.On("Validate", mock.Anything, token).Return(&credentials.Claims{ClientID: client.ID}, nil)
+	mockTokenHandler.On("Invalidate", mock.Anything, token).Return(nil)
+
+	req := postForm("/revoke", url.Values{"token": {token}})
+	req.SetBasicAuth(strconv.FormatInt(client.ID, 10), secret)
+	rec := httptest.NewRecorder()
+	newTestServer(mockDAL, &credMock.SessionManager{}, mockTokenHandler).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusOK)
+	mockTokenHandler.AssertCalled(t, "Invalidate", mock.Anything, token)
+}
+
+func TestUserInfo_MissingBearerToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/userinfo", nil)
+	rec := httptest.NewRecorder()
+	newTestServer(&dalMock.DataProvider{}, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusUnauthorized)
+	assert.Equal(t, rec.Header().Get("WWW-Authenticate"), `Bearer realm="oauth"`)
+}
+
+func TestUserInfo_InvalidToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	rec := httptest.NewRecorder()
+	newTestServer(&dalMock.DataProvider{}, &credMock.SessionManager{}, &credMock.TokenHandler{}).ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusUnauthorized)
+	assert.Equal(t, rec.Header().Get("WWW-Authenticate"), `Bearer error="invalid_token"`)
+	assert.Equal(t, decodeError(t, rec).Error, ErrorInvalidToken)
+}
+
+func TestMetadata_DiscoveryDocuments(t *testing.T) {
+	handler := newTestServer(&dalMock.DataProvider{}, &credMock.SessionManager{}, &credMock.TokenHandler{})
+
+	for _, path := range []string{auth.AuthorizationServerMetadataPath, auth.OpenIDConfigurationPath} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, rec.Code, http.StatusOK)
+
+		var body metadataResponse
+		assert.Equal(t, json.NewDecoder(rec.Body).Decode(&body), nil)
+		assert.Equal(t, body.Issuer, "oauth")
+		assert.Equal(t, body.TokenEndpoint, "https://auth.test/token")
+		assert.Equal(t, body.JwksURI, "https://auth.test/.well-known/jwks.json")
+		// ID tokens are never signed with the HS256 secret the test server uses.
+		assert.Equal(t, len(body.IDTokenSigningAlgValuesSupported), 0)
+		assert.Equal(t, body.GrantTypesSupported, []string{"authorization_code", "refresh_token", "client_credentials"})
+	}
+}
+
+func TestJWKS_HappyPath(t *testing.T) {
+	mockTokenHandler := &credMock.TokenHandler{}
+	mockTokenHandler.On("KeySet", mock.Anything).Return(&credentials.JWKSet{Keys: []credentials.JWK{{
+		KeyType:   "OKP",
+		KeyID:     "key-1",
+		Use:       "sig",
+		Algorithm: "EdDSA",
+		Curve:     "Ed25519",
+		X:         "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}}}, nil)
+
+	rec := httptest.NewRecorder()
+	newTestServer(&dalMock.DataProvider{}, &credMock.SessionManager{}, mockTokenHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, auth.JWKSPath, nil))
+
+	assert.Equal(t, rec.Code, http.StatusOK)
+	var body credentials.JWKSet
+	assert.Equal(t, json.NewDecoder(rec.Body).Decode(&body), nil)
+	assert.Equal(t, len(body.Keys), 1)
+	assert.Equal(t, body.Keys[0].KeyID, "key-1")
+	assert.Equal(t, body.Keys[0].Curve, "Ed25519")
+}
@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Ensure FileMailer implements the Mailer interface.
+var _ Mailer = new(FileMailer)
+
+// FileMailer writes emails to a file instead of sending them, for local development.
+type FileMailer struct {
+	from string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+// NewFileMailer creates a new FileMailer writing to w.
+func NewFileMailer(from string, w io.Writer) *FileMailer {
+	return &FileMailer{
+		from: from,
+		w:    w,
+	}
+}
+
+// Send appends msg to the file, followed by a separator line.
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.w.Write(format(m.from, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if _, err := io.WriteString(m.w, "\r\n.\r\n"); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
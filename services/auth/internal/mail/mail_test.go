@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/ramyadmz/goauth/internal/config"
+)
+
+func TestFileMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewFileMailer("no-reply@test.com", &buf)
+
+	err := mailer.Send(context.Background(), Message{
+		To:      "user@test.com",
+		Subject: "Hello",
+		Body:    "Hi there",
+	})
+	assert.Equal(t, err, nil)
+
+	written := buf.String()
+	assert.Equal(t, strings.HasPrefix(written, "From: no-reply@test.com\r\nTo: user@test.com\r\nSubject: Hello\r\n"), true)
+	assert.Equal(t, strings.HasSuffix(written, "\r\n\r\nHi there\r\n.\r\n"), true)
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	msg := Message{To: "user@test.com", Subject: "Hello", Body: "Hi there"}
+
+	assert.Equal(t, mailer.Send(context.Background(), msg), nil)
+	assert.Equal(t, mailer.Messages(), []Message{msg})
+}
+
+// newUnresponsiveSMTPMailer returns an SMTPMailer for a server that accepts connections but never
+// answers.
+func newUnresponsiveSMTPMailer(t *testing.T, timeout string) *SMTPMailer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening failed: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	t.Setenv("OAUTH_MAIL_TRANSPORT", config.MailTransportSMTP)
+	t.Setenv("OAUTH_SMTP_HOST", host)
+	t.Setenv("OAUTH_SMTP_PORT", port)
+	t.Setenv("OAUTH_SMTP_TIMEOUT", timeout)
+
+	cnfg, err := config.NewMailConfig()
+	if err != nil {
+		t.Fatalf("invalid mail config: %s", err)
+	}
+	return NewSMTPMailer(cnfg)
+}
+
+func TestSMTPMailer_Timeout(t *testing.T) {
+	mailer := newUnresponsiveSMTPMailer(t, "1")
+
+	start := time.Now()
+	err := mailer.Send(context.Background(), Message{To: "user@test.com", Subject: "Hello", Body: "Hi there"})
+
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, time.Since(start) < 5*time.Second, true)
+}
+
+func TestSMTPMailer_Canceled(t *testing.T) {
+	mailer := newUnresponsiveSMTPMailer(t, "60")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := mailer.Send(ctx, Message{To: "user@test.com", Subject: "Hello", Body: "Hi there"})
+
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, time.Since(start) < 5*time.Second, true)
+}
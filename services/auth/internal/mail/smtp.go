@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/ramyadmz/goauth/internal/config"
+)
+
+// Ensure SMTPMailer implements the Mailer interface.
+var _ Mailer = new(SMTPMailer)
+
+// SMTPMailer sends emails through an SMTP server, using STARTTLS when the server supports it.
+type SMTPMailer struct {
+	config *config.MailConfig
+}
+
+// NewSMTPMailer creates a new SMTPMailer.
+func NewSMTPMailer(cnfg *config.MailConfig) *SMTPMailer {
+	return &SMTPMailer{
+		config: cnfg,
+	}
+}
+
+// Send delivers msg to the configured SMTP server. Connecting and the whole SMTP conversation are
+// bounded by the configured timeout and by ctx, so that an unresponsive server can't block the caller.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, m.config.GetSMTPTimeout())
+	defer cancel()
+
+	addr := net.JoinHostPort(m.config.GetSMTPHost(), strconv.Itoa(m.config.GetSMTPPort()))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set smtp deadline: %w", err)
+	}
+	// Cancelling ctx interrupts the conversation as well, not only reaching its deadline
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := m.send(conn, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// send runs the SMTP conversation delivering msg over conn, like smtp.SendMail does over a
+// connection of its own.
+func (m *SMTPMailer) send(conn net.Conn, msg Message) error {
+	client, err := smtp.NewClient(conn, m.config.GetSMTPHost())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.GetSMTPHost()}); err != nil {
+			return err
+		}
+	}
+
+	if len(m.config.GetSMTPUsername()) > 0 {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server doesn't support authentication")
+		}
+		auth := smtp.PlainAuth("", m.config.GetSMTPUsername(), m.config.GetSMTPPassword(), m.config.GetSMTPHost())
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.config.GetFrom()); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(format(m.config.GetFrom(), msg, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}